@@ -0,0 +1,1059 @@
+package bashgen
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// Options customizes the behavior of RenderDeclarations.
+//
+// The zero value of Options selects the default behavior.
+type Options struct {
+	// Global causes all of the declarations to use the -g flag, so that
+	// the variables will be global even if the generated fragment is
+	// evaluated inside a function body.
+	Global bool
+
+	// GlobalNames is a set of variable names to declare using the -g flag
+	// even when Global is false, for fragments that are function bodies
+	// where most variables should be local but some must be global. The
+	// "_keys" companion array of such a variable, if any, is also global.
+	GlobalNames map[string]bool
+
+	// Local causes the declarations to use the local command instead of
+	// declare, for fragments that are function bodies. Variables that are
+	// declared as global, because of either Global or GlobalNames, still
+	// use declare, because local can't declare global variables.
+	Local bool
+
+	// Mutable causes the variables to be declared without the read-only
+	// attribute, so that the script can assign new values to them, such as
+	// when the given values are just initial defaults. This also applies to
+	// the companion variables generated by AssocKeyOrder and ArrayCounts,
+	// but not to the functions generated for NullDelimited.
+	Mutable bool
+
+	// Export causes the variables to be declared with the export attribute,
+	// using the -x flag, so that they will be inherited by child processes.
+	// Bash can't export arrays, so this doesn't apply to them.
+	Export bool
+
+	// ReadOnlyNames and ExportNames override Mutable and Export for
+	// individual variables: a variable whose name is in one of these maps
+	// is read-only, or exported, exactly when its value is true. The
+	// companion variables of AssocKeyOrder and ArrayCounts still follow
+	// the overall settings.
+	ReadOnlyNames map[string]bool
+	ExportNames   map[string]bool
+
+	// SortLists causes the elements of indexed arrays to be sorted
+	// lexically before rendering, for situations where the order of
+	// elements is not significant.
+	SortLists bool
+
+	// Unexport is a list of variable names which should have their export
+	// attribute explicitly removed using "export -n" before any of the
+	// declarations, so that they won't be inherited by child processes even
+	// if they were exported into the environment of the script.
+	Unexport []string
+
+	// SingleArray, if set, is the name of a single associative array to
+	// declare instead of declaring each variable separately. The variable
+	// names become the keys of that array, and numbers are rendered as
+	// their decimal string representation.
+	//
+	// Lists and maps cannot be nested inside an associative array, so by
+	// default they are an error when SingleArray is set. Set
+	// SingleArrayJSON to instead encode them as JSON strings.
+	SingleArray     string
+	SingleArrayJSON bool
+
+	// AssocKeyOrder causes each associative array to be accompanied by an
+	// indexed array whose name has the suffix "_keys", listing the keys of
+	// the associative array in a predictable order. Bash itself doesn't
+	// preserve any particular order for associative array elements, so
+	// scripts can iterate over the companion array to visit the elements
+	// in order.
+	//
+	// The keys are in lexical order by default. Set SortAssocByValue to
+	// instead order them by their associated values, with ties broken by
+	// key.
+	AssocKeyOrder    bool
+	SortAssocByValue bool
+
+	// ArrayCounts causes each indexed or associative array to be
+	// accompanied by a read-only integer variable whose name has the suffix
+	// "_count", giving the number of elements in the array. As with the
+	// "_keys" companion arrays, the count is global if the array is.
+	ArrayCounts bool
+
+	// EnvOverridePrefix, if set, causes string variables to be declared
+	// such that an environment variable whose name is this prefix followed
+	// by the variable name will override the given value if it's set to a
+	// non-empty string. The given value is then just the default.
+	//
+	// Number variables are never overridable, because Bash would subject
+	// the override to arithmetic evaluation, which can run arbitrary
+	// commands.
+	EnvOverridePrefix string
+
+	// Trace is a set of variable names to declare with the trace
+	// attribute, using the -t flag.
+	Trace map[string]bool
+
+	// EmptyStrings selects how string variables whose value is the empty
+	// string are declared. This doesn't affect the elements of arrays,
+	// including the single array selected by SingleArray.
+	EmptyStrings EmptyStringMode
+
+	// MaxLineLength, if greater than zero, is a limit on the length of the
+	// lines in the result. Arrays whose declarations would exceed it are
+	// instead written with one element per line, and long strings are
+	// split across multiple lines using line continuations.
+	//
+	// This is a best-effort limit: lines can still exceed it if, for
+	// example, a single array element is longer than the limit.
+	MaxLineLength int
+
+	// Indent is a prefix to add to the start of each line of the result,
+	// as described for the Indent function. MaxLineLength, if set, includes
+	// the indentation.
+	Indent string
+
+	// AssocFallback causes each associative array declaration to be wrapped
+	// in a runtime check of the Bash version, with a fallback for Bash 3,
+	// which doesn't support associative arrays. The fallback declares an
+	// indexed array of alternating keys and values instead.
+	AssocFallback bool
+
+	// ParallelArrays causes each map to be declared as two indexed arrays
+	// instead of an associative array, for Bash 3 compatibility. The array
+	// whose name has the suffix "_keys" lists the keys in lexical order,
+	// and the array whose name has the suffix "_values" lists the
+	// corresponding values in the same order. No variable is declared with
+	// the map's own name.
+	ParallelArrays bool
+
+	// SplitArrays causes each indexed array to be declared in three steps,
+	// with separate commands to declare the variable, assign its elements,
+	// and then mark it as read-only, as some style guides require.
+	SplitArrays bool
+
+	// ShellCheckDirectives causes each declaration to be preceded by a
+	// ShellCheck directive comment disabling the warnings that the
+	// declaration would otherwise trigger: SC2034, because ShellCheck
+	// can't see uses of the variables outside of the generated code, and
+	// SC2016 for values containing "$" or "`" characters, which are
+	// intentionally not expanded.
+	ShellCheckDirectives bool
+
+	// NullDelimited is a set of names of list variables to render as
+	// read-only shell functions that write the list elements to stdout,
+	// each terminated by a NUL character, instead of as arrays. That output
+	// is suitable for commands like "xargs -0" and "sort -z".
+	//
+	// This uses functions because Bash variables cannot contain NUL
+	// characters.
+	NullDelimited map[string]bool
+
+	// Comments maps variable names to text to write as a comment on the
+	// line before each one's declaration. This doesn't apply to variables
+	// that aren't declared at all, or to the elements of the single array
+	// selected by SingleArray.
+	Comments map[string]string
+
+	// NamePrefix is a prefix to add to the name of each declared variable,
+	// to avoid collisions with variables that the rest of the script
+	// declares. The keys of vars and of the other name-keyed options are
+	// the names without the prefix, but the names of any companion
+	// variables and functions are derived from the prefixed names.
+	NamePrefix string
+}
+
+// EmptyStringMode is the type of Options.EmptyStrings.
+type EmptyStringMode int
+
+const (
+	// EmptyStringAssign declares empty string variables with an explicit
+	// empty string assignment, like any other string. This is the default.
+	EmptyStringAssign EmptyStringMode = iota
+
+	// EmptyStringSkip omits empty string variables entirely, so that they
+	// are not declared at all.
+	EmptyStringSkip
+
+	// EmptyStringUnset declares empty string variables without assigning
+	// any value, so that they are declared (and read-only) but unset.
+	EmptyStringUnset
+)
+
+// Raw is a string value that RenderDeclarations writes verbatim as the
+// value of a scalar variable, without any quoting. The caller is
+// responsible for making sure it's valid Bash syntax, such as a command
+// substitution like "$(date +%s)".
+type Raw string
+
+// RenderDeclarations produces a Bash script fragment containing declarations
+// for each of the variables described in vars, in lexical order by name.
+//
+// Each value in vars must be of one of the following Go types:
+//
+//   - string, which becomes a string variable
+//   - *big.Float, which becomes an integer variable and so must be a whole number
+//   - []string, which becomes an indexed array of strings
+//   - []*big.Float, which becomes an indexed array of integers and so must
+//     contain only whole numbers
+//   - map[string]string, which becomes an associative array of strings
+//   - map[string]*big.Float, which becomes an associative array of integers
+//     and so must contain only whole numbers
+//   - Raw, which becomes a string variable whose value is written verbatim
+//
+// Associative array keys are quoted in the same way as values, so they may
+// contain characters that are special in Bash's array syntax, like "]" and
+// "=". However, Bash doesn't allow empty keys.
+//
+// RenderDeclarations returns an error if any of the variable names are
+// invalid or if any of the values are not of a supported type.
+func RenderDeclarations(vars map[string]interface{}, opts Options) (string, error) {
+	if opts.NamePrefix != "" {
+		vars, opts = withNamePrefix(vars, opts)
+	}
+	if opts.Indent == "" {
+		return renderDeclarations(vars, opts)
+	}
+	if opts.MaxLineLength > 0 {
+		opts.MaxLineLength -= len(opts.Indent)
+		if opts.MaxLineLength < 1 {
+			opts.MaxLineLength = 1
+		}
+	}
+	src, err := renderDeclarations(vars, opts)
+	if err != nil {
+		return "", err
+	}
+	return Indent(src, opts.Indent), nil
+}
+
+// withNamePrefix returns copies of the given variables and options with
+// opts.NamePrefix added to the start of each variable name, so that the
+// rest of the rendering process can ignore NamePrefix.
+func withNamePrefix(vars map[string]interface{}, opts Options) (map[string]interface{}, Options) {
+	prefix := opts.NamePrefix
+	prefixed := make(map[string]interface{}, len(vars))
+	for name, val := range vars {
+		prefixed[prefix+name] = val
+	}
+	prefixNames := func(names map[string]bool) map[string]bool {
+		if names == nil {
+			return nil
+		}
+		ret := make(map[string]bool, len(names))
+		for name, v := range names {
+			ret[prefix+name] = v
+		}
+		return ret
+	}
+	opts.GlobalNames = prefixNames(opts.GlobalNames)
+	opts.ReadOnlyNames = prefixNames(opts.ReadOnlyNames)
+	opts.ExportNames = prefixNames(opts.ExportNames)
+	opts.Trace = prefixNames(opts.Trace)
+	opts.NullDelimited = prefixNames(opts.NullDelimited)
+	if opts.Comments != nil {
+		comments := make(map[string]string, len(opts.Comments))
+		for name, comment := range opts.Comments {
+			comments[prefix+name] = comment
+		}
+		opts.Comments = comments
+	}
+	opts.NamePrefix = ""
+	return prefixed, opts
+}
+
+// renderDeclarations is the main implementation of RenderDeclarations,
+// which produces the declarations without any indentation.
+func renderDeclarations(vars map[string]interface{}, opts Options) (string, error) {
+	if len(vars) == 0 && len(opts.Unexport) == 0 {
+		return "", nil
+	}
+
+	var buf strings.Builder
+	for _, name := range opts.Unexport {
+		if !ValidName(name) {
+			return "", fmt.Errorf("cannot use %q as a Bash variable name", name)
+		}
+		buf.WriteString("export -n ")
+		buf.WriteString(name)
+		buf.WriteString("\n")
+	}
+
+	names := make([]string, 0, len(vars))
+	for name := range vars {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	if opts.SingleArray != "" {
+		err := renderSingleArray(&buf, names, vars, opts)
+		if err != nil {
+			return "", err
+		}
+		return buf.String(), nil
+	}
+
+	for _, name := range names {
+		if !ValidName(name) {
+			return "", fmt.Errorf("cannot use %q as a Bash variable name", name)
+		}
+		if s, ok := vars[name].(string); ok && s == "" && opts.EmptyStrings != EmptyStringAssign {
+			if opts.EmptyStrings == EmptyStringUnset {
+				buf.WriteString(commentLines(opts, name))
+				buf.WriteString(shellCheckDirective(opts, s))
+				buf.WriteString(declareCommand(opts, name, ""))
+				buf.WriteString("\n")
+			}
+			continue
+		}
+		buf.WriteString(commentLines(opts, name))
+		if l, ok := vars[name].([]string); ok && opts.NullDelimited[name] {
+			buf.WriteString(nullDelimitedFunction(name, l, opts))
+			continue
+		}
+		if _, ok := assocStrings(vars[name]); ok && opts.ParallelArrays {
+			decls, err := parallelArrayDeclarations(name, vars[name], opts)
+			if err != nil {
+				return "", err
+			}
+			buf.WriteString(decls)
+			buf.WriteString(arrayCountDeclaration(name, vars[name], opts))
+			continue
+		}
+		typeFlags, lit, err := literal(name, vars[name], opts)
+		if err != nil {
+			return "", err
+		}
+		if (typeFlags == "a" || typeFlags == "ia") && opts.SplitArrays {
+			assign := name + "="
+			if opts.MaxLineLength > 0 && len(assign)+len(lit) > opts.MaxLineLength {
+				lit = wrappedLiteral(name, vars[name], opts, len(assign))
+			}
+			directive := shellCheckDirective(opts, vars[name])
+			buf.WriteString(directive)
+			buf.WriteString(declareCommandWith(opts, name, typeFlags, false))
+			buf.WriteString("\n")
+			buf.WriteString(directive)
+			buf.WriteString(assign)
+			buf.WriteString(lit)
+			buf.WriteString("\n")
+			if readOnlyFor(opts, name) {
+				buf.WriteString("readonly -a ")
+				buf.WriteString(name)
+				buf.WriteString("\n")
+			}
+			buf.WriteString(arrayCountDeclaration(name, vars[name], opts))
+			continue
+		}
+		decl := declareCommand(opts, name, typeFlags) + "="
+		if opts.MaxLineLength > 0 && len(decl)+len(lit) > opts.MaxLineLength {
+			lit = wrappedLiteral(name, vars[name], opts, len(decl))
+		}
+		buf.WriteString(shellCheckDirective(opts, vars[name]))
+		if m, ok := assocStrings(vars[name]); ok && opts.AssocFallback {
+			buf.WriteString("if ((BASH_VERSINFO[0] >= 4)); then\n")
+			buf.WriteString(decl)
+			buf.WriteString(lit)
+			buf.WriteString("\nelse\n")
+			buf.WriteString(declareCommand(opts, name, "a"))
+			buf.WriteString("=")
+			buf.WriteString(pairsLiteral(m, opts, len(decl)))
+			buf.WriteString("\nfi\n")
+		} else {
+			buf.WriteString(decl)
+			buf.WriteString(lit)
+			buf.WriteString("\n")
+		}
+		if _, ok := assocStrings(vars[name]); ok && opts.AssocKeyOrder {
+			keys := assocKeyOrder(vars[name], opts)
+			items := make([]string, len(keys))
+			for i, ek := range keys {
+				items[i] = Quote(ek)
+			}
+			keysOpts := opts
+			keysOpts.Global = opts.Global || opts.GlobalNames[name]
+			decl := declareCommand(keysOpts, name+"_keys", "a") + "="
+			lit := arrayLiteral(items)
+			if opts.MaxLineLength > 0 && len(decl)+len(lit) > opts.MaxLineLength {
+				lit = multilineArrayLiteral(items)
+			}
+			buf.WriteString(shellCheckDirective(opts, keys))
+			buf.WriteString(decl)
+			buf.WriteString(lit)
+			buf.WriteString("\n")
+		}
+		buf.WriteString(arrayCountDeclaration(name, vars[name], opts))
+	}
+	return buf.String(), nil
+}
+
+// commentLines returns the comment to write before the declaration of the
+// variable of the given name, as selected by opts.Comments, or the empty
+// string if there is no comment for it.
+func commentLines(opts Options, name string) string {
+	comment, ok := opts.Comments[name]
+	if !ok {
+		return ""
+	}
+	return "# " + strings.ReplaceAll(comment, "\n", "\n# ") + "\n"
+}
+
+// arrayCountDeclaration returns the declaration of the "_count" companion
+// variable for the given variable if it's an array and opts.ArrayCounts is
+// set, or the empty string otherwise.
+func arrayCountDeclaration(name string, val interface{}, opts Options) string {
+	if !opts.ArrayCounts {
+		return ""
+	}
+	var count int
+	switch val := val.(type) {
+	case []string:
+		count = len(val)
+	case []*big.Float:
+		count = len(val)
+	case map[string]string:
+		count = len(val)
+	case map[string]*big.Float:
+		count = len(val)
+	default:
+		return ""
+	}
+	countOpts := opts
+	countOpts.Global = opts.Global || opts.GlobalNames[name]
+	return shellCheckDirective(opts, "") + declareCommand(countOpts, name+"_count", "i") + "=" + strconv.Itoa(count) + "\n"
+}
+
+// Literal returns the literal that RenderDeclarations would assign to the
+// variable of the given name, which is the part of its declaration after
+// the equals sign.
+//
+// The value must be one of the types that RenderDeclarations accepts. The
+// SingleArray, Unexport, and AssocKeyOrder options don't affect the result,
+// because they don't change how an individual variable's value is written.
+// MaxLineLength is also ignored, so the result is always the single-line
+// form of the literal.
+//
+// If opts.EmptyStrings causes the variable to be declared without a value,
+// or not declared at all, the result is the empty string.
+func Literal(name string, val interface{}, opts Options) (string, error) {
+	if s, ok := val.(string); ok && s == "" && opts.EmptyStrings != EmptyStringAssign {
+		return "", nil
+	}
+	_, lit, err := literal(opts.NamePrefix+name, val, opts)
+	return lit, err
+}
+
+// Kind returns a short name for the kind of variable that RenderDeclarations
+// would declare for the variable of the given name, which is one of
+// "scalar", "integer", "indexed_array", "associative_array", "function"
+// for lists selected by opts.NullDelimited, or "parallel_arrays" for maps
+// when opts.ParallelArrays is set.
+//
+// Kind doesn't take into account opts.SingleArray, which causes all of the
+// variables to be elements of a single associative array instead.
+func Kind(name string, val interface{}, opts Options) (string, error) {
+	if _, ok := val.([]string); ok && opts.NullDelimited[name] {
+		return "function", nil
+	}
+	if _, ok := assocStrings(val); ok && opts.ParallelArrays {
+		return "parallel_arrays", nil
+	}
+	typeFlags, _, err := literal(name, val, opts)
+	if err != nil {
+		return "", err
+	}
+	return kindNames[typeFlags], nil
+}
+
+// kindNames maps from the type flags returned by literal to the names
+// returned by Kind.
+var kindNames = map[string]string{
+	"":   "scalar",
+	"i":  "integer",
+	"a":  "indexed_array",
+	"ia": "indexed_array",
+	"A":  "associative_array",
+	"Ai": "associative_array",
+}
+
+// literal is the main implementation of Literal, which additionally returns
+// the declare flags that select the type of variable the literal is for.
+func literal(name string, val interface{}, opts Options) (typeFlags string, lit string, err error) {
+	switch val := val.(type) {
+	case string:
+		return "", envOverride(name, Quote(val), opts), nil
+	case Raw:
+		return "", string(val), nil
+	case *big.Float:
+		// Bash only actually supports integers.
+		if !val.IsInt() {
+			return "", "", fmt.Errorf("can't use %s as value of %q: Bash doesn't support floating-point numbers", val.Text('f', -1), name)
+		}
+		return "i", integerLiteral(val), nil
+	case []string:
+		return "a", arrayLiteral(listItems(val, opts)), nil
+	case []*big.Float:
+		items, err := integerListItems(name, val, opts)
+		if err != nil {
+			return "", "", err
+		}
+		return "ia", arrayLiteral(items), nil
+	case map[string]string:
+		items, err := mapItems(name, val)
+		if err != nil {
+			return "", "", err
+		}
+		return "A", arrayLiteral(items), nil
+	case map[string]*big.Float:
+		items, err := integerMapItems(name, val)
+		if err != nil {
+			return "", "", err
+		}
+		return "Ai", arrayLiteral(items), nil
+	default:
+		return "", "", fmt.Errorf("don't know how to serialize %q for bash", name)
+	}
+}
+
+func renderSingleArray(buf *strings.Builder, names []string, vars map[string]interface{}, opts Options) error {
+	if !ValidName(opts.SingleArray) {
+		return fmt.Errorf("cannot use %q as a Bash variable name", opts.SingleArray)
+	}
+	items := make([]string, len(names))
+	for i, name := range names {
+		var s string
+		switch val := vars[name].(type) {
+		case Raw:
+			items[i] = "[" + name + "]=" + string(val)
+			continue
+		case string:
+			s = val
+		case *big.Float:
+			if !val.IsInt() {
+				return fmt.Errorf("can't use %s as value of %q: Bash doesn't support floating-point numbers", val.Text('f', -1), name)
+			}
+			s = integerLiteral(val)
+		case []string, []*big.Float, map[string]string, map[string]*big.Float:
+			if !opts.SingleArrayJSON {
+				return fmt.Errorf("can't include %q in associative array %q: Bash doesn't support nested arrays", name, opts.SingleArray)
+			}
+			var jsonVal interface{} = val
+			if l, ok := val.([]*big.Float); ok {
+				// big.Float would otherwise marshal as a JSON string.
+				items, err := integerListItems(name, l, Options{})
+				if err != nil {
+					return err
+				}
+				nums := make([]json.Number, len(items))
+				for i, item := range items {
+					nums[i] = json.Number(item)
+				}
+				jsonVal = nums
+			}
+			if m, ok := val.(map[string]*big.Float); ok {
+				nums := make(map[string]json.Number, len(m))
+				for k, f := range m {
+					if !f.IsInt() {
+						return fmt.Errorf("can't use %s as an element of %q: Bash doesn't support floating-point numbers", f.Text('f', -1), name)
+					}
+					nums[k] = json.Number(integerLiteral(f))
+				}
+				jsonVal = nums
+			}
+			src, err := json.Marshal(jsonVal)
+			if err != nil {
+				return fmt.Errorf("can't encode %q as JSON: %w", name, err)
+			}
+			s = string(src)
+		default:
+			return fmt.Errorf("don't know how to serialize %q for bash", name)
+		}
+		items[i] = "[" + name + "]=" + Quote(s)
+	}
+	decl := declareCommand(opts, opts.SingleArray, "A") + "="
+	lit := arrayLiteral(items)
+	if opts.MaxLineLength > 0 && len(decl)+len(lit) > opts.MaxLineLength {
+		lit = multilineArrayLiteral(items)
+	}
+	buf.WriteString(shellCheckDirective(opts, items))
+	buf.WriteString(decl)
+	buf.WriteString(lit)
+	buf.WriteString("\n")
+	return nil
+}
+
+// wrappedLiteral is a variant of literal that returns a literal split over
+// multiple lines, for use when the single-line form would exceed
+// opts.MaxLineLength. col is the length of the text that precedes the
+// literal on its first line.
+//
+// Values that can't be split are returned in their single-line form.
+func wrappedLiteral(name string, val interface{}, opts Options, col int) string {
+	switch val := val.(type) {
+	case string:
+		if opts.EnvOverridePrefix != "" {
+			col += len("${" + opts.EnvOverridePrefix + name + ":-")
+		}
+		return envOverride(name, wrapQuote(val, col, opts.MaxLineLength), opts)
+	case []string:
+		return multilineArrayLiteral(listItems(val, opts))
+	case []*big.Float:
+		items, _ := integerListItems(name, val, opts) // already validated by literal
+		return multilineArrayLiteral(items)
+	case map[string]string:
+		items, _ := mapItems(name, val) // already validated by literal
+		return multilineArrayLiteral(items)
+	case map[string]*big.Float:
+		items, _ := integerMapItems(name, val) // already validated by literal
+		return multilineArrayLiteral(items)
+	default:
+		_, lit, _ := literal(name, val, opts)
+		return lit
+	}
+}
+
+// listItems returns the quoted elements of the given list, sorted if
+// requested in opts.
+func listItems(l []string, opts Options) []string {
+	if opts.SortLists {
+		l = append([]string(nil), l...)
+		sort.Strings(l)
+	}
+	items := make([]string, len(l))
+	for i, es := range l {
+		items[i] = Quote(es)
+	}
+	return items
+}
+
+// integerListItems returns the elements of the given list of numbers as
+// integer literals, sorted numerically if requested in opts.
+func integerListItems(name string, l []*big.Float, opts Options) ([]string, error) {
+	if opts.SortLists {
+		l = append([]*big.Float(nil), l...)
+		sort.Slice(l, func(i, j int) bool {
+			return l[i].Cmp(l[j]) < 0
+		})
+	}
+	items := make([]string, len(l))
+	for i, f := range l {
+		if !f.IsInt() {
+			return nil, fmt.Errorf("can't use %s as an element of %q: Bash doesn't support floating-point numbers", f.Text('f', -1), name)
+		}
+		items[i] = integerLiteral(f)
+	}
+	return items, nil
+}
+
+// mapItems returns the elements of the given map as ['key']='value' items
+// of an associative array literal.
+//
+// Bash doesn't preserve the order of the elements, but we write them in
+// lexical order by key anyway so that the result is consistent for the same
+// input.
+func mapItems(name string, m map[string]string) ([]string, error) {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		if k == "" {
+			return nil, fmt.Errorf("can't use the empty string as a key in %q: Bash doesn't allow empty associative array keys", name)
+		}
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	items := make([]string, len(keys))
+	for i, k := range keys {
+		items[i] = "[" + Quote(k) + "]=" + Quote(m[k])
+	}
+	return items, nil
+}
+
+// integerMapItems is like mapItems, but for maps of numbers, which must all
+// be whole numbers.
+func integerMapItems(name string, m map[string]*big.Float) ([]string, error) {
+	keys := make([]string, 0, len(m))
+	for k, f := range m {
+		if k == "" {
+			return nil, fmt.Errorf("can't use the empty string as a key in %q: Bash doesn't allow empty associative array keys", name)
+		}
+		if !f.IsInt() {
+			return nil, fmt.Errorf("can't use %s as an element of %q: Bash doesn't support floating-point numbers", f.Text('f', -1), name)
+		}
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	items := make([]string, len(keys))
+	for i, k := range keys {
+		items[i] = "[" + Quote(k) + "]=" + integerLiteral(m[k])
+	}
+	return items, nil
+}
+
+// parallelArrayDeclarations returns the declarations of the "_keys" and
+// "_values" arrays that represent the given map for Options.ParallelArrays.
+//
+// Unlike associative arrays, these arrays can represent an empty key.
+func parallelArrayDeclarations(name string, val interface{}, opts Options) (string, error) {
+	var keys, values []string
+	valueFlags := "a"
+	if _, ok := val.(map[string]*big.Float); ok {
+		valueFlags = "ia"
+	}
+	for _, k := range assocKeyOrder(val, Options{}) {
+		keys = append(keys, Quote(k))
+		switch m := val.(type) {
+		case map[string]string:
+			values = append(values, Quote(m[k]))
+		case map[string]*big.Float:
+			if !m[k].IsInt() {
+				return "", fmt.Errorf("can't use %s as an element of %q: Bash doesn't support floating-point numbers", m[k].Text('f', -1), name)
+			}
+			values = append(values, integerLiteral(m[k]))
+		}
+	}
+
+	companionOpts := opts
+	companionOpts.Global = opts.Global || opts.GlobalNames[name]
+	var buf strings.Builder
+	for _, arr := range []struct {
+		suffix, typeFlags string
+		items             []string
+	}{
+		{"_keys", "a", keys},
+		{"_values", valueFlags, values},
+	} {
+		decl := declareCommand(companionOpts, name+arr.suffix, arr.typeFlags) + "="
+		lit := arrayLiteral(arr.items)
+		if opts.MaxLineLength > 0 && len(decl)+len(lit) > opts.MaxLineLength {
+			lit = multilineArrayLiteral(arr.items)
+		}
+		buf.WriteString(shellCheckDirective(opts, val))
+		buf.WriteString(decl)
+		buf.WriteString(lit)
+		buf.WriteString("\n")
+	}
+	return buf.String(), nil
+}
+
+// assocStrings returns the given value as a map of strings if it's one of
+// the types that becomes an associative array, with any integers in their
+// decimal string representation. The second return value is false for
+// values of other types.
+func assocStrings(val interface{}) (map[string]string, bool) {
+	switch val := val.(type) {
+	case map[string]string:
+		return val, true
+	case map[string]*big.Float:
+		m := make(map[string]string, len(val))
+		for k, f := range val {
+			m[k] = integerLiteral(f)
+		}
+		return m, true
+	default:
+		return nil, false
+	}
+}
+
+// pairsLiteral returns an indexed array literal containing the keys and
+// values of the given map as alternating elements, in lexical order by key,
+// for use as a fallback where associative arrays are not available.
+//
+// col is the length of the text that precedes the literal on its line,
+// used to decide whether to split the literal over multiple lines.
+func pairsLiteral(m map[string]string, opts Options, col int) string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	items := make([]string, 0, len(keys)*2)
+	for _, k := range keys {
+		items = append(items, Quote(k), Quote(m[k]))
+	}
+	lit := arrayLiteral(items)
+	if opts.MaxLineLength > 0 && col+len(lit) > opts.MaxLineLength {
+		lit = multilineArrayLiteral(items)
+	}
+	return lit
+}
+
+// arrayLiteral returns an array literal containing the given items on a
+// single line.
+func arrayLiteral(items []string) string {
+	return "(" + strings.Join(items, " ") + ")"
+}
+
+// multilineArrayLiteral returns an array literal with each of the given
+// items on a separate, indented line.
+func multilineArrayLiteral(items []string) string {
+	var buf strings.Builder
+	buf.WriteString("(\n")
+	for _, item := range items {
+		buf.WriteString("  ")
+		buf.WriteString(item)
+		buf.WriteString("\n")
+	}
+	buf.WriteString(")")
+	return buf.String()
+}
+
+// wrapQuote is like Quote, but splits the result into multiple separately
+// quoted parts joined by line continuations so that each line fits within
+// max columns, assuming that the first line starts at column col.
+//
+// Each part always includes at least one character, so lines may exceed
+// max if col is too large.
+func wrapQuote(s string, col, max int) string {
+	var parts []string
+	var cur strings.Builder
+	curLen := 2            // the quotes around the current part
+	avail := max - col - 1 // reserve space for the line continuation
+	for _, r := range s {
+		w := utf8.RuneLen(r)
+		switch {
+		case r == '\'':
+			w = len(`'\''`)
+		case isControl(r):
+			w = len(`\x00`)
+		}
+		if cur.Len() != 0 && curLen+w > avail {
+			parts = append(parts, Quote(cur.String()))
+			cur.Reset()
+			curLen = 2
+			avail = max - 1
+		}
+		cur.WriteRune(r)
+		curLen += w
+	}
+	parts = append(parts, Quote(cur.String()))
+	return strings.Join(parts, "\\\n")
+}
+
+// nullDelimitedFunction returns the definition of a read-only function with
+// the given name which writes each of the given elements to stdout,
+// terminated by NUL characters, for Options.NullDelimited.
+func nullDelimitedFunction(name string, l []string, opts Options) string {
+	items := listItems(l, opts)
+	var buf strings.Builder
+	buf.WriteString(name)
+	if len(items) == 0 {
+		// printf would write a single empty record if given no elements.
+		buf.WriteString("() { :; }\n")
+	} else {
+		buf.WriteString("() { printf '%s\\0' ")
+		buf.WriteString(strings.Join(items, " "))
+		buf.WriteString("; }\n")
+	}
+	buf.WriteString("readonly -f ")
+	buf.WriteString(name)
+	buf.WriteString("\n")
+	return buf.String()
+}
+
+// shellCheckDirective returns a ShellCheck directive comment line to place
+// before the declaration of the given value, if opts.ShellCheckDirectives
+// is set. Otherwise, returns the empty string.
+func shellCheckDirective(opts Options, val interface{}) string {
+	if !opts.ShellCheckDirectives {
+		return ""
+	}
+	codes := "SC2034"
+	if containsExpansionChars(val) {
+		codes += ",SC2016"
+	}
+	return "# shellcheck disable=" + codes + "\n"
+}
+
+// containsExpansionChars returns true if the given value, or any of its
+// elements or keys, contains characters that would begin an expansion if
+// they were not in single quotes.
+func containsExpansionChars(val interface{}) bool {
+	switch val := val.(type) {
+	case string:
+		return strings.ContainsAny(val, "$`")
+	case []string:
+		for _, s := range val {
+			if strings.ContainsAny(s, "$`") {
+				return true
+			}
+		}
+	case map[string]string:
+		for k, v := range val {
+			if strings.ContainsAny(k, "$`") || strings.ContainsAny(v, "$`") {
+				return true
+			}
+		}
+	case map[string]*big.Float:
+		for k := range val {
+			if strings.ContainsAny(k, "$`") {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// assocKeyOrder returns the keys of the given map, which must be one of the
+// types that becomes an associative array, in the order selected by the
+// given options for the companion arrays generated by
+// Options.AssocKeyOrder.
+//
+// When ordering by value, maps of numbers are ordered numerically.
+func assocKeyOrder(val interface{}, opts Options) []string {
+	var keys []string
+	var compareValues func(a, b string) int
+	switch m := val.(type) {
+	case map[string]string:
+		for k := range m {
+			keys = append(keys, k)
+		}
+		compareValues = func(a, b string) int {
+			return strings.Compare(m[a], m[b])
+		}
+	case map[string]*big.Float:
+		for k := range m {
+			keys = append(keys, k)
+		}
+		compareValues = func(a, b string) int {
+			return m[a].Cmp(m[b])
+		}
+	}
+	if opts.SortAssocByValue {
+		sort.Slice(keys, func(i, j int) bool {
+			if c := compareValues(keys[i], keys[j]); c != 0 {
+				return c < 0
+			}
+			return keys[i] < keys[j]
+		})
+	} else {
+		sort.Strings(keys)
+	}
+	return keys
+}
+
+// envOverride wraps the given already-quoted value in a parameter expansion
+// that allows it to be overridden by an environment variable, if
+// opts.EnvOverridePrefix is set. Otherwise, returns the value unchanged.
+//
+// The result is intended for use as the right-hand side of an assignment,
+// where Bash doesn't perform word splitting or pathname expansion and so the
+// expansion doesn't need to be in double quotes. Double quotes would be
+// harmful here, because Bash would then not perform quote removal on the
+// default value.
+func envOverride(name, quoted string, opts Options) string {
+	if opts.EnvOverridePrefix == "" {
+		return quoted
+	}
+	return "${" + opts.EnvOverridePrefix + name + ":-" + quoted + "}"
+}
+
+// declareCommand returns a declare command for the variable with the given
+// name, without any assignment, using the flags implied by opts along with
+// the given additional flags that are specific to the type of value being
+// declared.
+func declareCommand(opts Options, name string, typeFlags string) string {
+	return declareCommandWith(opts, name, typeFlags, true)
+}
+
+// declareCommandWith is like declareCommand, but allows omitting the
+// read-only flag for situations where the variable will be marked as
+// read-only separately.
+func declareCommandWith(opts Options, name string, typeFlags string, readOnly bool) string {
+	cmd := "declare"
+	if opts.Local {
+		cmd = "local"
+	}
+	flags := ""
+	if readOnly && readOnlyFor(opts, name) {
+		flags = "r"
+	}
+	if opts.Global || opts.GlobalNames[name] {
+		cmd = "declare"
+		flags = "g" + flags
+	}
+	if opts.Trace[name] {
+		flags += "t"
+	}
+	if exportFor(opts, name) && !strings.ContainsAny(typeFlags, "aA") {
+		flags += "x"
+	}
+	flags += typeFlags
+	if flags == "" {
+		return cmd + " " + name
+	}
+	return cmd + " -" + flags + " " + name
+}
+
+// readOnlyFor returns true if the variable of the given name should be
+// declared as read-only, taking into account both opts.Mutable and
+// opts.ReadOnlyNames.
+func readOnlyFor(opts Options, name string) bool {
+	if readOnly, ok := opts.ReadOnlyNames[name]; ok {
+		return readOnly
+	}
+	return !opts.Mutable
+}
+
+// exportFor returns true if the variable of the given name should be
+// exported, taking into account both opts.Export and opts.ExportNames.
+func exportFor(opts Options, name string) bool {
+	if export, ok := opts.ExportNames[name]; ok {
+		return export
+	}
+	return opts.Export
+}
+
+// integerLiteral returns a decimal integer literal for the given number,
+// which must be a whole number.
+//
+// We format via big.Int rather than directly formatting the big.Float so
+// that the result can never include a fractional part or an exponent, even
+// if the float's internal representation is unusual.
+func integerLiteral(f *big.Float) string {
+	i, _ := f.Int(nil)
+	return i.String()
+}
+
+// FormatNumber returns a decimal representation of the given number for
+// use in languages other than Bash, which may support fractional numbers.
+//
+// Whole numbers are formatted exactly as Bash integer literals are, so that
+// all of the dialects agree on them. Other numbers use the shortest decimal
+// representation that identifies the value, without an exponent.
+func FormatNumber(f *big.Float) string {
+	if f.IsInt() {
+		return integerLiteral(f)
+	}
+	return f.Text('f', -1)
+}
+
+// Indent returns the given script fragment, as returned by
+// RenderDeclarations, with the given prefix added to the start of each
+// non-empty line.
+//
+// Lines that follow a line continuation are not indented, because the
+// prefix would then become part of the word being continued.
+func Indent(s, prefix string) string {
+	lines := strings.SplitAfter(s, "\n")
+	var buf strings.Builder
+	continued := false
+	for _, line := range lines {
+		if !continued && strings.TrimSpace(line) != "" {
+			buf.WriteString(prefix)
+		}
+		buf.WriteString(line)
+		continued = strings.HasSuffix(line, "\\\n")
+	}
+	return buf.String()
+}
@@ -0,0 +1,841 @@
+package bashgen
+
+import (
+	"math/big"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRenderDeclarations(t *testing.T) {
+	tests := map[string]struct {
+		vars    map[string]interface{}
+		opts    Options
+		want    string
+		wantErr string
+	}{
+		"no variables": {
+			vars: nil,
+			want: ``,
+		},
+		"string": {
+			vars: map[string]interface{}{
+				"greeting": "it's me",
+			},
+			want: `declare -r greeting='it'\''s me'
+`,
+		},
+		"number": {
+			vars: map[string]interface{}{
+				"port": number("8080"),
+			},
+			want: `declare -ri port=8080
+`,
+		},
+		"negative number": {
+			vars: map[string]interface{}{
+				"offset": number("-12"),
+			},
+			want: `declare -ri offset=-12
+`,
+		},
+		"list of strings": {
+			vars: map[string]interface{}{
+				"names": []string{"a", "b c"},
+			},
+			want: `declare -ra names=('a' 'b c')
+`,
+		},
+		"empty list": {
+			vars: map[string]interface{}{
+				"names": []string{},
+			},
+			want: `declare -ra names=()
+`,
+		},
+		"map of strings": {
+			vars: map[string]interface{}{
+				"tags": map[string]string{"b": "2", "a": "1"},
+			},
+			want: `declare -rA tags=(['a']='1' ['b']='2')
+`,
+		},
+		"several variables in lexical order": {
+			vars: map[string]interface{}{
+				"b": "2",
+				"a": "1",
+				"c": number("3"),
+			},
+			want: `declare -r a='1'
+declare -r b='2'
+declare -ri c=3
+`,
+		},
+		"global": {
+			vars: map[string]interface{}{
+				"s":  "x",
+				"n":  number("1"),
+				"l":  []string{"a"},
+				"ln": []*big.Float{number("1")},
+				"m":  map[string]string{"k": "v"},
+				"mn": map[string]*big.Float{"k": number("1")},
+			},
+			opts: Options{
+				Global: true,
+			},
+			want: `declare -gra l=('a')
+declare -gria ln=(1)
+declare -grA m=(['k']='v')
+declare -grAi mn=(['k']=1)
+declare -gri n=1
+declare -gr s='x'
+`,
+		},
+		"sorted lists": {
+			vars: map[string]interface{}{
+				"s": []string{"b", "a", "B", "10", "9"},
+				"n": []*big.Float{number("10"), number("9"), number("-1")},
+			},
+			opts: Options{
+				SortLists: true,
+			},
+			want: `declare -ria n=(-1 9 10)
+declare -ra s=('10' '9' 'B' 'a' 'b')
+`,
+		},
+		"large number": {
+			vars: map[string]interface{}{
+				"big": number("9007199254740993"),
+			},
+			want: `declare -ri big=9007199254740993
+`,
+		},
+		"whole number with fractional notation": {
+			vars: map[string]interface{}{
+				"x": number("3.0"),
+			},
+			want: `declare -ri x=3
+`,
+		},
+		"unexport": {
+			vars: map[string]interface{}{
+				"a": "x",
+			},
+			opts: Options{
+				Unexport: []string{"AWS_SECRET_ACCESS_KEY", "TOKEN"},
+			},
+			want: `export -n AWS_SECRET_ACCESS_KEY
+export -n TOKEN
+declare -r a='x'
+`,
+		},
+		"single array": {
+			vars: map[string]interface{}{
+				"region": "us-east-1",
+				"port":   number("8080"),
+			},
+			opts: Options{
+				SingleArray: "VARS",
+			},
+			want: `declare -rA VARS=([port]='8080' [region]='us-east-1')
+`,
+		},
+		"single array with JSON collections": {
+			vars: map[string]interface{}{
+				"zones": []string{"a", "b"},
+				"ports": map[string]*big.Float{"http": number("80")},
+			},
+			opts: Options{
+				SingleArray:     "VARS",
+				SingleArrayJSON: true,
+			},
+			want: `declare -rA VARS=([ports]='{"http":80}' [zones]='["a","b"]')
+`,
+		},
+		"single array with a list": {
+			vars: map[string]interface{}{
+				"zones": []string{"a", "b"},
+			},
+			opts: Options{
+				SingleArray: "VARS",
+			},
+			wantErr: `can't include "zones" in associative array "VARS": Bash doesn't support nested arrays`,
+		},
+		"assoc key order": {
+			vars: map[string]interface{}{
+				"m": map[string]string{"b": "1", "a": "2"},
+			},
+			opts: Options{
+				AssocKeyOrder: true,
+			},
+			want: `declare -rA m=(['a']='2' ['b']='1')
+declare -ra m_keys=('a' 'b')
+`,
+		},
+		"assoc key order by value": {
+			vars: map[string]interface{}{
+				"m": map[string]string{"c": "1", "b": "2", "a": "1"},
+				"n": map[string]*big.Float{"x": number("10"), "y": number("9")},
+			},
+			opts: Options{
+				AssocKeyOrder:    true,
+				SortAssocByValue: true,
+			},
+			want: `declare -rA m=(['a']='1' ['b']='2' ['c']='1')
+declare -ra m_keys=('a' 'c' 'b')
+declare -rAi n=(['x']=10 ['y']=9)
+declare -ra n_keys=('y' 'x')
+`,
+		},
+		"env override prefix": {
+			vars: map[string]interface{}{
+				"region": "us-east-1",
+				"port":   number("8080"),
+				"zones":  []string{"a"},
+			},
+			opts: Options{
+				EnvOverridePrefix: "TF_",
+			},
+			want: `declare -ri port=8080
+declare -r region=${TF_region:-'us-east-1'}
+declare -ra zones=('a')
+`,
+		},
+		"trace": {
+			vars: map[string]interface{}{
+				"a": "x",
+				"b": number("1"),
+				"c": []string{"y"},
+			},
+			opts: Options{
+				Trace: map[string]bool{"a": true, "b": true, "c": true},
+			},
+			want: `declare -rt a='x'
+declare -rti b=1
+declare -rta c=('y')
+`,
+		},
+		"map keys with array syntax characters": {
+			vars: map[string]interface{}{
+				"m": map[string]string{"a]b": "1", "a[0]": "2", "a b": "3", "a=b": "4", "$x": "5"},
+			},
+			want: `declare -rA m=(['$x']='5' ['a b']='3' ['a=b']='4' ['a[0]']='2' ['a]b']='1')
+`,
+		},
+		"map with an empty key": {
+			vars: map[string]interface{}{
+				"m": map[string]string{"": "1"},
+			},
+			wantErr: `can't use the empty string as a key in "m": Bash doesn't allow empty associative array keys`,
+		},
+		"max line length": {
+			vars: map[string]interface{}{
+				"short": []string{"a", "b"},
+				"long":  []string{"alpha", "bravo", "charlie", "delta"},
+				"str":   "the quick brown fox jumps over the lazy dog",
+			},
+			opts: Options{
+				MaxLineLength: 30,
+			},
+			want: `declare -ra long=(
+  'alpha'
+  'bravo'
+  'charlie'
+  'delta'
+)
+declare -ra short=('a' 'b')
+declare -r str='the quick br'\
+'own fox jumps over the lazy'\
+' dog'
+`,
+		},
+		"max line length with indent": {
+			vars: map[string]interface{}{
+				"long": []string{"alpha", "bravo", "charlie"},
+			},
+			opts: Options{
+				MaxLineLength: 30,
+				Indent:        "    ",
+			},
+			want: `    declare -ra long=(
+      'alpha'
+      'bravo'
+      'charlie'
+    )
+`,
+		},
+		"indent": {
+			vars: map[string]interface{}{
+				"m": map[string]string{"a": "1", "b": "2"},
+				"s": "x",
+			},
+			opts: Options{Indent: "  "},
+			want: `  declare -rA m=(['a']='1' ['b']='2')
+  declare -r s='x'
+`,
+		},
+		"indent with multi-line map": {
+			vars: map[string]interface{}{
+				"m": map[string]string{"alpha": "1", "bravo": "2", "charlie": "3"},
+			},
+			opts: Options{Indent: "  ", MaxLineLength: 30},
+			want: `  declare -rA m=(
+    ['alpha']='1'
+    ['bravo']='2'
+    ['charlie']='3'
+  )
+`,
+		},
+		"assoc fallback": {
+			vars: map[string]interface{}{
+				"m": map[string]string{"b": "2", "a": "1"},
+			},
+			opts: Options{
+				AssocFallback: true,
+			},
+			want: `if ((BASH_VERSINFO[0] >= 4)); then
+declare -rA m=(['a']='1' ['b']='2')
+else
+declare -ra m=('a' '1' 'b' '2')
+fi
+`,
+		},
+		"split arrays": {
+			vars: map[string]interface{}{
+				"l": []string{"a", "b"},
+				"n": []*big.Float{number("1")},
+				"s": "x",
+			},
+			opts: Options{
+				SplitArrays: true,
+			},
+			want: `declare -a l
+l=('a' 'b')
+readonly -a l
+declare -ia n
+n=(1)
+readonly -a n
+declare -r s='x'
+`,
+		},
+		"split arrays mutable": {
+			vars: map[string]interface{}{
+				"l": []string{"a", "b"},
+			},
+			opts: Options{
+				SplitArrays: true,
+				Mutable:     true,
+			},
+			want: `declare -a l
+l=('a' 'b')
+`,
+		},
+		"shellcheck directives": {
+			vars: map[string]interface{}{
+				"s": "x",
+				"d": "$HOME",
+				"n": number("1"),
+				"l": []string{"a", "`b`"},
+				"m": map[string]string{"k": "v"},
+			},
+			opts: Options{
+				ShellCheckDirectives: true,
+			},
+			want: `# shellcheck disable=SC2034,SC2016
+declare -r d='$HOME'
+# shellcheck disable=SC2034,SC2016
+declare -ra l=('a' '` + "`b`" + `')
+# shellcheck disable=SC2034
+declare -rA m=(['k']='v')
+# shellcheck disable=SC2034
+declare -ri n=1
+# shellcheck disable=SC2034
+declare -r s='x'
+`,
+		},
+		"shellcheck directives split arrays": {
+			vars: map[string]interface{}{
+				"l": []string{"a"},
+			},
+			opts: Options{
+				ShellCheckDirectives: true,
+				SplitArrays:          true,
+			},
+			want: `# shellcheck disable=SC2034
+declare -a l
+# shellcheck disable=SC2034
+l=('a')
+readonly -a l
+`,
+		},
+		"null delimited": {
+			vars: map[string]interface{}{
+				"l": []string{"a b", "c\nd", ""},
+				"e": []string{},
+			},
+			opts: Options{
+				NullDelimited: map[string]bool{"l": true, "e": true},
+			},
+			want: `e() { :; }
+readonly -f e
+l() { printf '%s\0' 'a b' $'c\nd' ''; }
+readonly -f l
+`,
+		},
+		"array counts": {
+			vars: map[string]interface{}{
+				"l": []string{"a", "b", "c"},
+				"n": []*big.Float{number("1")},
+				"m": map[string]string{"k": "v", "l": "w"},
+				"e": []string{},
+				"s": "x",
+			},
+			opts: Options{
+				ArrayCounts: true,
+			},
+			want: `declare -ra e=()
+declare -ri e_count=0
+declare -ra l=('a' 'b' 'c')
+declare -ri l_count=3
+declare -rA m=(['k']='v' ['l']='w')
+declare -ri m_count=2
+declare -ria n=(1)
+declare -ri n_count=1
+declare -r s='x'
+`,
+		},
+		"mutable string": {
+			vars: map[string]interface{}{
+				"s": "x",
+			},
+			opts: Options{Mutable: true},
+			want: `declare s='x'
+`,
+		},
+		"mutable number": {
+			vars: map[string]interface{}{
+				"n": number("1"),
+			},
+			opts: Options{Mutable: true},
+			want: `declare -i n=1
+`,
+		},
+		"mutable list": {
+			vars: map[string]interface{}{
+				"l": []string{"a", "b"},
+			},
+			opts: Options{Mutable: true},
+			want: `declare -a l=('a' 'b')
+`,
+		},
+		"mutable map": {
+			vars: map[string]interface{}{
+				"m": map[string]string{"k": "v"},
+			},
+			opts: Options{Mutable: true},
+			want: `declare -A m=(['k']='v')
+`,
+		},
+		"mutable with read-only override": {
+			vars: map[string]interface{}{
+				"a": "x",
+				"b": []string{"y"},
+			},
+			opts: Options{Mutable: true, ReadOnlyNames: map[string]bool{"b": true}},
+			want: `declare a='x'
+declare -ra b=('y')
+`,
+		},
+		"export": {
+			vars: map[string]interface{}{
+				"s": "x",
+				"n": number("1"),
+			},
+			opts: Options{Export: true},
+			want: `declare -rxi n=1
+declare -rx s='x'
+`,
+		},
+		"export mutable": {
+			vars: map[string]interface{}{
+				"s": "x",
+			},
+			opts: Options{Export: true, Mutable: true},
+			want: `declare -x s='x'
+`,
+		},
+		"export arrays": {
+			vars: map[string]interface{}{
+				"l": []string{"a"},
+				"m": map[string]string{"k": "v"},
+			},
+			opts: Options{Export: true},
+			want: `declare -ra l=('a')
+declare -rA m=(['k']='v')
+`,
+		},
+		"mixed types": {
+			vars: map[string]interface{}{
+				"host":  "example.com",
+				"port":  number("443"),
+				"paths": []string{"/a", "/b"},
+				"tags":  map[string]string{"env": "prod"},
+			},
+			want: `declare -r host='example.com'
+declare -ra paths=('/a' '/b')
+declare -ri port=443
+declare -rA tags=(['env']='prod')
+`,
+		},
+		"parallel arrays": {
+			vars: map[string]interface{}{
+				"m": map[string]string{"b": "2", "a": "1"},
+			},
+			opts: Options{ParallelArrays: true},
+			want: `declare -ra m_keys=('a' 'b')
+declare -ra m_values=('1' '2')
+`,
+		},
+		"parallel arrays of numbers": {
+			vars: map[string]interface{}{
+				"m": map[string]*big.Float{"a": number("1")},
+			},
+			opts: Options{ParallelArrays: true},
+			want: `declare -ra m_keys=('a')
+declare -ria m_values=(1)
+`,
+		},
+		"parallel arrays with an empty key": {
+			vars: map[string]interface{}{
+				"m": map[string]string{"": "x"},
+			},
+			opts: Options{ParallelArrays: true},
+			want: `declare -ra m_keys=('')
+declare -ra m_values=('x')
+`,
+		},
+		"comments": {
+			vars: map[string]interface{}{
+				"b": "2",
+				"a": "1",
+				"c": "3",
+			},
+			opts: Options{Comments: map[string]string{"a": "string", "b": "string"}},
+			want: `# string
+declare -r a='1'
+# string
+declare -r b='2'
+declare -r c='3'
+`,
+		},
+		"invalid name": {
+			vars: map[string]interface{}{
+				"not-valid": "x",
+			},
+			wantErr: `cannot use "not-valid" as a Bash variable name`,
+		},
+		"unsupported type": {
+			vars: map[string]interface{}{
+				"x": 1.5,
+			},
+			wantErr: `don't know how to serialize "x" for bash`,
+		},
+		"fractional number": {
+			vars: map[string]interface{}{
+				"x": number("1.5"),
+			},
+			wantErr: `can't use 1.5 as value of "x": Bash doesn't support floating-point numbers`,
+		},
+		"nearly whole number": {
+			vars: map[string]interface{}{
+				"x": number("3.0000000001"),
+			},
+			wantErr: `can't use 3.0000000001 as value of "x"`,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			got, err := RenderDeclarations(test.vars, test.opts)
+			if test.wantErr != "" {
+				if err == nil {
+					t.Fatalf("unexpected success\ngot:\n%s\nwant error: %s", got, test.wantErr)
+				}
+				if !strings.Contains(err.Error(), test.wantErr) {
+					t.Fatalf("wrong error\ngot:  %s\nwant: %s", err, test.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+			if got != test.want {
+				t.Errorf("wrong result\ngot:\n%s\nwant:\n%s", got, test.want)
+			}
+		})
+	}
+}
+
+func TestFormatNumber(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{"0", "0"},
+		{"8080", "8080"},
+		{"-12", "-12"},
+		{"3.0", "3"},
+		{"9007199254740993", "9007199254740993"},
+		{"100000000000000000000000000000", "100000000000000000000000000000"},
+		{"1.5", "1.5"},
+		{"3.0000000001", "3.0000000001"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.input, func(t *testing.T) {
+			got := FormatNumber(number(test.input))
+			if got != test.want {
+				t.Errorf("wrong result for %s: got %s, want %s", test.input, got, test.want)
+			}
+		})
+	}
+}
+
+// number parses the given decimal string as a *big.Float with enough
+// precision to represent it exactly, for use in test cases.
+func number(s string) *big.Float {
+	f, _, err := big.ParseFloat(s, 10, 512, big.ToNearestEven)
+	if err != nil {
+		panic(err)
+	}
+	return f
+}
+
+// TestRenderDeclarationsExtglob checks that values which look like extended
+// glob patterns stay literal even when a script enables the shell options
+// that affect pathname expansion, by running the declarations with Bash.
+func TestRenderDeclarationsExtglob(t *testing.T) {
+	bash, err := exec.LookPath("bash")
+	if err != nil {
+		t.Skip("bash is not available")
+	}
+
+	// The directory contains files that the patterns would match if they
+	// were expanded.
+	dir := t.TempDir()
+	for _, name := range []string{"a", "b", "ab", "x", "y"} {
+		if err := os.WriteFile(filepath.Join(dir, name), nil, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	patterns := []string{"@(a|b)", "*", "+(ab|ac)", "!(x)", "**"}
+	decls, err := RenderDeclarations(map[string]interface{}{
+		"list":   patterns,
+		"map":    map[string]string{"@(a|b)": "!(x)"},
+		"scalar": "@(a|b)",
+	}, Options{})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	script := "shopt -s extglob nullglob globstar dotglob\n" + decls + `
+printf '%s\n' "${list[@]}"
+for k in "${!map[@]}"; do printf '%s=%s\n' "$k" "${map[$k]}"; done
+printf '%s\n' "$scalar"
+`
+	cmd := exec.Command(bash, "-c", script)
+	cmd.Dir = dir
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		t.Fatalf("bash failed: %s\n%s", err, out)
+	}
+	got := string(out)
+	want := strings.Join(patterns, "\n") + "\n@(a|b)=!(x)\n@(a|b)\n"
+	if got != want {
+		t.Errorf("wrong output\ngot:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+// TestRenderDeclarationsAssocKeyOrderRun checks that iterating over the
+// "_keys" companion array visits the keys in lexical order, regardless of
+// the order Bash uses for the associative array itself.
+func TestRenderDeclarationsAssocKeyOrderRun(t *testing.T) {
+	bash, err := exec.LookPath("bash")
+	if err != nil {
+		t.Skip("bash is not available")
+	}
+
+	m := make(map[string]string)
+	var want strings.Builder
+	for _, k := range []string{"a", "b", "c", "d", "e", "f", "g", "h", "i", "j"} {
+		m[k] = "value-" + k
+		want.WriteString(k + "=value-" + k + "\n")
+	}
+	decls, err := RenderDeclarations(map[string]interface{}{"m": m}, Options{
+		AssocKeyOrder: true,
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	script := decls + `for k in "${m_keys[@]}"; do printf '%s=%s\n' "$k" "${m[$k]}"; done` + "\n"
+	out, err := exec.Command(bash, "-c", script).CombinedOutput()
+	if err != nil {
+		t.Fatalf("bash failed: %s\n%s", err, out)
+	}
+	if got := string(out); got != want.String() {
+		t.Errorf("wrong output\ngot:\n%s\nwant:\n%s", got, want.String())
+	}
+}
+
+// TestRenderDeclarationsEnvOverrideRun checks that environment variables
+// override only string variables, and that an override for a number
+// variable isn't evaluated as an arithmetic expression.
+func TestRenderDeclarationsEnvOverrideRun(t *testing.T) {
+	bash, err := exec.LookPath("bash")
+	if err != nil {
+		t.Skip("bash is not available")
+	}
+
+	decls, err := RenderDeclarations(map[string]interface{}{
+		"region": "us-east-1",
+		"port":   number("8080"),
+	}, Options{
+		EnvOverridePrefix: "TF_",
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	script := decls + `printf '%s %s\n' "$region" "$port"` + "\n"
+	cmd := exec.Command(bash, "-c", script)
+	cmd.Env = append(os.Environ(), "TF_region=eu-west-1", "TF_port=a[$(echo pwned >&2)]")
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		t.Fatalf("bash failed: %s\n%s", err, out)
+	}
+	if got, want := string(out), "eu-west-1 8080\n"; got != want {
+		t.Errorf("wrong output\ngot:  %q\nwant: %q", got, want)
+	}
+}
+
+// TestRenderDeclarationsEvalSnippets checks that map values containing Bash
+// code are stored exactly, so that a script can use the map as an
+// eval-based dispatch table.
+func TestRenderDeclarationsEvalSnippets(t *testing.T) {
+	bash, err := exec.LookPath("bash")
+	if err != nil {
+		t.Skip("bash is not available")
+	}
+
+	decls, err := RenderDeclarations(map[string]interface{}{
+		"table": map[string]string{
+			"greet": `printf '%s, %s!\n' "Hello" "$1"`,
+			"count": `printf '%s\n' "$@" | wc -l | tr -d ' '`,
+			"quote": `echo "it's \"$1\"" 2>&1`,
+		},
+	}, Options{})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	script := decls + `set -- world two three
+for k in greet count quote; do eval "${table[$k]}"; done
+`
+	out, err := exec.Command(bash, "-c", script).CombinedOutput()
+	if err != nil {
+		t.Fatalf("bash failed: %s\n%s", err, out)
+	}
+	want := "Hello, world!\n3\nit's \"world\"\n"
+	if got := string(out); got != want {
+		t.Errorf("wrong output\ngot:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestRenderDeclarationsNullDelimitedRun(t *testing.T) {
+	bash, err := exec.LookPath("bash")
+	if err != nil {
+		t.Skip("bash is not available")
+	}
+
+	tests := map[string][]string{
+		"none":           {},
+		"one":            {"a"},
+		"one empty":      {""},
+		"several":        {"a b", "c\nd", "", "$e"},
+		"printf escapes": {`\0`, "%s"},
+	}
+	for name, l := range tests {
+		t.Run(name, func(t *testing.T) {
+			decls, err := RenderDeclarations(map[string]interface{}{
+				"l": l,
+			}, Options{
+				NullDelimited: map[string]bool{"l": true},
+			})
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+			out, err := exec.Command(bash, "-c", decls+"l\n").Output()
+			if err != nil {
+				t.Fatalf("bash failed: %s", err)
+			}
+			var got []string
+			if len(out) != 0 {
+				if out[len(out)-1] != 0 {
+					t.Fatalf("output doesn't end with a NUL: %q", out)
+				}
+				got = strings.Split(string(out[:len(out)-1]), "\x00")
+			}
+			if len(got) != len(l) {
+				t.Fatalf("wrong number of records %d; want %d\n%q", len(got), len(l), out)
+			}
+			for i := range l {
+				if got[i] != l[i] {
+					t.Errorf("wrong record %d %q; want %q", i, got[i], l[i])
+				}
+			}
+		})
+	}
+}
+
+func TestRenderDeclarationsDeterministic(t *testing.T) {
+	// Go randomizes map iteration order, so we build the same maps several
+	// times, with the keys inserted in different orders, and render each
+	// one several times to give any nondeterminism a chance to show.
+	keys := []string{"zeta", "alpha", "mu", "beta", "omega", "delta", "pi", "epsilon"}
+	build := func(offset int) map[string]interface{} {
+		strs := make(map[string]string)
+		nums := make(map[string]*big.Float)
+		for i := range keys {
+			k := keys[(i+offset)%len(keys)]
+			strs[k] = "v-" + k
+			nums[k] = big.NewFloat(float64(len(k)))
+		}
+		return map[string]interface{}{
+			"strs": strs,
+			"nums": nums,
+		}
+	}
+
+	want, err := RenderDeclarations(build(0), Options{})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	for offset := 0; offset < len(keys); offset++ {
+		for i := 0; i < 10; i++ {
+			got, err := RenderDeclarations(build(offset), Options{})
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+			if got != want {
+				t.Fatalf("result differs\ngot:\n%s\nwant:\n%s", got, want)
+			}
+		}
+	}
+	if !strings.Contains(want, "(['alpha']='v-alpha' ['beta']='v-beta' ") {
+		t.Errorf("keys are not sorted\n%s", want)
+	}
+}
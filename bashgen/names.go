@@ -0,0 +1,45 @@
+package bashgen
+
+// ValidName returns true if the given string is valid to use as the name of
+// a Bash variable.
+func ValidName(s string) bool {
+	if len(s) == 0 {
+		return false
+	}
+	for i, c := range s {
+		if i == 0 {
+			if !validNameInitialCharacter(c) {
+				return false
+			}
+		} else {
+			if !validNameSubsequentCharacter(c) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+func validNameInitialCharacter(c rune) bool {
+	switch {
+	case c == '_':
+		return true
+	case c >= 'A' && c <= 'Z':
+		return true
+	case c >= 'a' && c <= 'z':
+		return true
+	default:
+		return false
+	}
+}
+
+func validNameSubsequentCharacter(c rune) bool {
+	switch {
+	case validNameInitialCharacter(c):
+		return true
+	case c >= '0' && c <= '9':
+		return true
+	default:
+		return false
+	}
+}
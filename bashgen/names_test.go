@@ -0,0 +1,33 @@
+package bashgen
+
+import (
+	"testing"
+)
+
+func TestValidName(t *testing.T) {
+	tests := []struct {
+		input string
+		want  bool
+	}{
+		{"", false},
+		{"a", true},
+		{"_", true},
+		{"foo_bar", true},
+		{"FOO", true},
+		{"foo1", true},
+		{"1foo", false},
+		{"foo-bar", false},
+		{"foo.bar", false},
+		{"foo bar", false},
+		{"héllo", false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.input, func(t *testing.T) {
+			got := ValidName(test.input)
+			if got != test.want {
+				t.Errorf("wrong result for %q: got %t, want %t", test.input, got, test.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,70 @@
+// Package bashgen contains the Bash code generation logic used by the
+// bash_script data source in terraform-provider-bash, exported so that other
+// Go programs can reuse the same escaping and declaration rendering without
+// going through Terraform.
+//
+// The functions in this package deal only in plain Go values. Translating
+// from some other type system, such as Terraform's, into the forms accepted
+// here is the caller's responsibility.
+package bashgen
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Quote returns a Bash string literal which, when interpreted by Bash, will
+// produce exactly the given string.
+//
+// The result is an ordinary single-quoted string unless s contains control
+// characters, such as newlines or tabs, in which case it uses ANSI-C
+// quoting, like $'line1\nline2', so that the literal stays on one line and
+// the control characters are visible in the generated code.
+func Quote(s string) string {
+	if strings.IndexFunc(s, isControl) != -1 {
+		return ansiCQuote(s)
+	}
+	if strings.IndexByte(s, '\'') == -1 {
+		// Most strings contain no single quotes at all, in which case we
+		// can skip the escaping step.
+		return "'" + s + "'"
+	}
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// ansiCQuote returns a Bash ANSI-C quoted string literal, like $'a\tb',
+// which will produce exactly the given string.
+func ansiCQuote(s string) string {
+	var b strings.Builder
+	b.WriteString("$'")
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case c == '\\' || c == '\'':
+			b.WriteByte('\\')
+			b.WriteByte(c)
+		case c == '\n':
+			b.WriteString(`\n`)
+		case c == '\t':
+			b.WriteString(`\t`)
+		case c == '\r':
+			b.WriteString(`\r`)
+		case isControl(rune(c)):
+			// Bash reads up to two hex digits after \x, so we always
+			// write two to avoid consuming a following literal digit.
+			fmt.Fprintf(&b, `\x%02x`, c)
+		default:
+			// Other bytes, including those of multi-byte UTF-8
+			// sequences, are taken literally.
+			b.WriteByte(c)
+		}
+	}
+	b.WriteString("'")
+	return b.String()
+}
+
+// isControl returns true if r is an ASCII control character, which Quote
+// escapes using ANSI-C quoting.
+func isControl(r rune) bool {
+	return r < 0x20 || r == 0x7f
+}
@@ -0,0 +1,96 @@
+package bashgen
+
+import (
+	"os/exec"
+	"testing"
+)
+
+func TestQuote(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{"", `''`},
+		{"hello", `'hello'`},
+		{"hello world", `'hello world'`},
+		{"it's", `'it'\''s'`},
+		{"''", `''\'''\'''`},
+		{"'leading", `''\''leading'`},
+		{"trailing'", `'trailing'\'''`},
+		{"a'b'c", `'a'\''b'\''c'`},
+		{`don't "quote" me`, `'don'\''t "quote" me'`},
+		{"$HOME `id` \"x\" \\", `'$HOME ` + "`id`" + ` "x" \'`},
+		{"*.txt", `'*.txt'`},
+		{"héllo", `'héllo'`},
+		{"@(a|b)", `'@(a|b)'`},
+		{"!(x)", `'!(x)'`},
+		{"+(ab|ac)", `'+(ab|ac)'`},
+		{"line1\nline2\t\x01", `$'line1\nline2\t\x01'`},
+		{"a\r\n", `$'a\r\n'`},
+		{"\x1b[0m", `$'\x1b[0m'`},
+		{"it's\n", `$'it\'s\n'`},
+		{"back\\slash\n", `$'back\\slash\n'`},
+		{"\x7f", `$'\x7f'`},
+	}
+
+	for _, test := range tests {
+		t.Run(test.input, func(t *testing.T) {
+			got := Quote(test.input)
+			if got != test.want {
+				t.Errorf("wrong result\ninput: %q\ngot:   %s\nwant:  %s", test.input, got, test.want)
+			}
+		})
+	}
+}
+
+func TestQuoteRun(t *testing.T) {
+	bash, err := exec.LookPath("bash")
+	if err != nil {
+		t.Skip("bash is not available")
+	}
+
+	inputs := []string{
+		"'leading",
+		"trailing'",
+		"a'b'c",
+		"''",
+		"line1\nline2\t\x01",
+		"a\r\n",
+		"\x1b[0m",
+		"it's\n",
+		"back\\slash\n",
+		"\x01f\x7f\x1fz",
+		"héllo\n世界",
+	}
+	for _, input := range inputs {
+		t.Run(input, func(t *testing.T) {
+			out, err := exec.Command(bash, "-c", "printf '%s' "+Quote(input)).Output()
+			if err != nil {
+				t.Fatalf("bash failed: %s", err)
+			}
+			if got := string(out); got != input {
+				t.Errorf("wrong result\ngot:  %q\nwant: %q", got, input)
+			}
+		})
+	}
+}
+
+func BenchmarkBashQuoteString(b *testing.B) {
+	inputs := map[string]string{
+		// The fast path, with no escaping required.
+		"plain": "us-east-1.compute.internal",
+		// Single quotes must be escaped.
+		"quotes": "it's Terraform's job",
+		// Control characters require ANSI-C quoting.
+		"control": "line one\nline two\ttabbed",
+	}
+
+	for name, input := range inputs {
+		b.Run(name, func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				Quote(input)
+			}
+		})
+	}
+}
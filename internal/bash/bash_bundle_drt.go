@@ -0,0 +1,308 @@
+package bash
+
+import (
+	"encoding/base64"
+	"fmt"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/hashicorp/terraform-plugin-go/tfprotov5"
+	"github.com/hashicorp/terraform-plugin-go/tfprotov5/tftypes"
+
+	"github.com/apparentlymart/terraform-provider-bash/bashgen"
+)
+
+type bashBundleConfig struct {
+	// Files maps each file path to its content, with any content given in
+	// files_base64 already decoded.
+	Files map[string][]byte
+
+	// Base64Files is the set of paths whose content was given in
+	// files_base64, which we always embed as base64 to avoid making any
+	// assumptions about their encoding.
+	Base64Files map[string]bool
+
+	// config retains the full configuration object, so that we can echo
+	// back the arguments exactly as given when producing a result.
+	config map[string]tftypes.Value
+}
+
+var bashBundleType = tftypes.Object{
+	AttributeTypes: map[string]tftypes.Type{
+		"files":        mapOfString,
+		"files_base64": mapOfString,
+		"result":       tftypes.String,
+	},
+}
+
+func newBashBundleConfig(raw *tfprotov5.DynamicValue) (*bashBundleConfig, []*tfprotov5.Diagnostic) {
+	ret := &bashBundleConfig{}
+	var diags []*tfprotov5.Diagnostic
+
+	val, err := raw.Unmarshal(bashBundleType)
+	if err != nil {
+		// Terraform should have verified that the configuration matches
+		// our schema, so we'll get here only if Terraform and the provider
+		// disagree about what the schema is.
+		detail := fmt.Sprintf("The given configuration doesn't match the expected schema: %s.", err)
+		if mismatch := schemaMismatchDetail(raw, bashBundleType); mismatch != "" {
+			detail = fmt.Sprintf("The given configuration doesn't match the expected schema: %s. This suggests that Terraform and the provider disagree about the bash_bundle schema, such as if Terraform is running a different version of the provider than the one it got the schema from.", mismatch)
+		}
+		diags = append(diags, &tfprotov5.Diagnostic{
+			Severity: tfprotov5.DiagnosticSeverityError,
+			Summary:  "Invalid configuration",
+			Detail:   detail,
+		})
+		return ret, diags
+	}
+
+	var obj map[string]tftypes.Value
+	if err := val.As(&obj); err != nil {
+		// Similarly, this indicates a bug in Terraform's validation.
+		diags = append(diags, &tfprotov5.Diagnostic{
+			Severity: tfprotov5.DiagnosticSeverityError,
+			Summary:  "Invalid configuration",
+			Detail:   fmt.Sprintf("The given configuration doesn't match the expected schema: %s.", err),
+		})
+		return ret, diags
+	}
+	ret.config = obj
+
+	ret.Files = make(map[string][]byte)
+	ret.Base64Files = make(map[string]bool)
+	for filePath, content := range stringMapAttr(obj, "files") {
+		ret.Files[filePath] = []byte(content)
+	}
+	for filePath, encoded := range stringMapAttr(obj, "files_base64") {
+		content, err := base64.StdEncoding.DecodeString(encoded)
+		if err != nil {
+			diags = append(diags, &tfprotov5.Diagnostic{
+				Severity: tfprotov5.DiagnosticSeverityError,
+				Summary:  "Invalid file content",
+				Detail:   fmt.Sprintf("The content for %q is not valid base64: %s.", filePath, err),
+				Attribute: &tftypes.AttributePath{
+					Steps: []tftypes.AttributePathStep{
+						tftypes.AttributeName("files_base64"),
+						tftypes.ElementKeyString(filePath),
+					},
+				},
+			})
+			continue
+		}
+		if _, exists := ret.Files[filePath]; exists {
+			diags = append(diags, &tfprotov5.Diagnostic{
+				Severity: tfprotov5.DiagnosticSeverityError,
+				Summary:  "Duplicate file path",
+				Detail:   fmt.Sprintf("The path %q is given in both \"files\" and \"files_base64\".", filePath),
+				Attribute: &tftypes.AttributePath{
+					Steps: []tftypes.AttributePathStep{
+						tftypes.AttributeName("files_base64"),
+						tftypes.ElementKeyString(filePath),
+					},
+				},
+			})
+			continue
+		}
+		ret.Files[filePath] = content
+		ret.Base64Files[filePath] = true
+	}
+
+	for _, attrName := range []string{"files", "files_base64"} {
+		for filePath := range stringMapAttr(obj, attrName) {
+			problem := filePathProblem(filePath)
+			if problem == "" {
+				continue
+			}
+			diags = append(diags, &tfprotov5.Diagnostic{
+				Severity: tfprotov5.DiagnosticSeverityError,
+				Summary:  "Invalid file path",
+				Detail:   fmt.Sprintf("Cannot use %q as a file path: %s.", filePath, problem),
+				Attribute: &tftypes.AttributePath{
+					Steps: []tftypes.AttributePathStep{
+						tftypes.AttributeName(attrName),
+						tftypes.ElementKeyString(filePath),
+					},
+				},
+			})
+		}
+	}
+	diags = append(diags, filePathConflictDiags(ret.Files, func(filePath string) string {
+		if ret.Base64Files[filePath] {
+			return "files_base64"
+		}
+		return "files"
+	})...)
+
+	return ret, diags
+}
+
+// filePathProblem returns a description of why the given path can't be
+// used as a bash_bundle file path, or the empty string if it's valid.
+//
+// The paths must be relative and stay within the directory where the script
+// runs, so that running the script can't write files anywhere else.
+func filePathProblem(filePath string) string {
+	switch {
+	case filePath == "":
+		return "the path is empty"
+	case strings.ContainsRune(filePath, 0):
+		return "the path contains a NUL character"
+	case strings.HasPrefix(filePath, "/"):
+		return "the path must be relative to the directory where the script runs"
+	case strings.HasSuffix(filePath, "/"):
+		return "the path must be a file, not a directory"
+	}
+	for _, part := range strings.Split(filePath, "/") {
+		switch part {
+		case "..":
+			return "the path must not contain \"..\" segments"
+		case ".":
+			return "the path must not contain \".\" segments"
+		}
+	}
+	if clean := path.Clean(filePath); clean != filePath {
+		// Only repeated slashes can get us here, given the checks above.
+		return fmt.Sprintf("the path must be written in its simplest form, %q", clean)
+	}
+	return ""
+}
+
+// filePathConflictDiags returns an error diagnostic for each file path that
+// has another of the given file paths as a parent directory, because the
+// script can't then create both the file and the directory.
+//
+// The attrName function returns the name of the argument where the given
+// path was set, for the diagnostic's attribute path. Paths that
+// filePathProblem rejects are ignored, because they are already reported.
+func filePathConflictDiags(paths map[string][]byte, attrName func(string) string) []*tfprotov5.Diagnostic {
+	var diags []*tfprotov5.Diagnostic
+	sorted := make([]string, 0, len(paths))
+	for filePath := range paths {
+		if filePathProblem(filePath) == "" {
+			sorted = append(sorted, filePath)
+		}
+	}
+	sort.Strings(sorted)
+	for _, filePath := range sorted {
+		for dir := path.Dir(filePath); dir != "."; dir = path.Dir(dir) {
+			if _, exists := paths[dir]; !exists {
+				continue
+			}
+			diags = append(diags, &tfprotov5.Diagnostic{
+				Severity: tfprotov5.DiagnosticSeverityError,
+				Summary:  "Conflicting file paths",
+				Detail:   fmt.Sprintf("Cannot use %q as a file path because it is inside %q, which is also given as a file.", filePath, dir),
+				Attribute: &tftypes.AttributePath{
+					Steps: []tftypes.AttributePathStep{
+						tftypes.AttributeName(attrName(filePath)),
+						tftypes.ElementKeyString(filePath),
+					},
+				},
+			})
+			break
+		}
+	}
+	return diags
+}
+
+// Script returns a Bash script which writes each of the files in the
+// configuration, relative to the current working directory, creating any
+// parent directories as needed.
+//
+// Text content is written using a here-document where possible, so that
+// the script remains readable. Content that a here-document can't
+// represent exactly, such as text containing NUL characters or content
+// given in files_base64, is instead embedded as base64 and decoded using
+// the base64 command at runtime.
+func (c *bashBundleConfig) Script() string {
+	paths := make([]string, 0, len(c.Files))
+	for filePath := range c.Files {
+		paths = append(paths, filePath)
+	}
+	sort.Strings(paths)
+
+	var b strings.Builder
+	b.WriteString(dialectShebangs["bash"] + "\n")
+	b.WriteString("set -e\n")
+	for _, filePath := range paths {
+		content := c.Files[filePath]
+		quotedPath := bashgen.Quote(filePath)
+		if dir := path.Dir(filePath); dir != "." {
+			b.WriteString("mkdir -p -- " + bashgen.Quote(dir) + "\n")
+		}
+		switch {
+		case len(content) == 0:
+			b.WriteString(": >" + quotedPath + "\n")
+		case c.Base64Files[filePath] || strings.ContainsRune(string(content), 0):
+			delim := heredocDelimiter(base64Lines(content))
+			b.WriteString("base64 -d >" + quotedPath + " <<'" + delim + "'\n")
+			b.WriteString(base64Lines(content))
+			b.WriteString(delim + "\n")
+		case content[len(content)-1] == '\n':
+			delim := heredocDelimiter(string(content))
+			b.WriteString("cat >" + quotedPath + " <<'" + delim + "'\n")
+			b.Write(content)
+			b.WriteString(delim + "\n")
+		default:
+			// A here-document always ends with a newline, so content
+			// without one must be written some other way.
+			b.WriteString("printf '%s' " + bashgen.Quote(string(content)) + " >" + quotedPath + "\n")
+		}
+	}
+	return b.String()
+}
+
+// base64Lines returns the base64 encoding of the given content, split into
+// lines of 76 characters as expected by the base64 command.
+func base64Lines(content []byte) string {
+	const lineLen = 76
+	encoded := base64.StdEncoding.EncodeToString(content)
+	var b strings.Builder
+	for len(encoded) > lineLen {
+		b.WriteString(encoded[:lineLen] + "\n")
+		encoded = encoded[lineLen:]
+	}
+	b.WriteString(encoded + "\n")
+	return b.String()
+}
+
+// heredocDelimiter returns a here-document delimiter word that doesn't
+// appear as a line of its own in the given content, and so can't end the
+// here-document early.
+func heredocDelimiter(content string) string {
+	lines := make(map[string]struct{})
+	for _, line := range strings.Split(content, "\n") {
+		lines[line] = struct{}{}
+	}
+	delim := "EOF"
+	for i := 1; ; i++ {
+		if _, exists := lines[delim]; !exists {
+			return delim
+		}
+		delim = "EOF_" + strconv.Itoa(i)
+	}
+}
+
+func (c *bashBundleConfig) ResultObject(result string) tftypes.Value {
+	attrs := make(map[string]tftypes.Value, len(bashBundleType.AttributeTypes))
+	for name, aty := range bashBundleType.AttributeTypes {
+		if v, ok := c.config[name]; ok {
+			attrs[name] = v
+		} else {
+			attrs[name] = tftypes.NewValue(aty, nil)
+		}
+	}
+	attrs["result"] = tftypes.NewValue(tftypes.String, result)
+	return tftypes.NewValue(bashBundleType, attrs)
+}
+
+func (c *bashBundleConfig) ResultDynamicValue(result string) *tfprotov5.DynamicValue {
+	v, err := tfprotov5.NewDynamicValue(bashBundleType, c.ResultObject(result))
+	if err != nil {
+		// We control all of the inputs here, so any error represents a bug.
+		panic(fmt.Sprintf("failed to build dynamic value: %s", err))
+	}
+	return &v
+}
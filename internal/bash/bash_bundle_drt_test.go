@@ -0,0 +1,290 @@
+package bash
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-go/tfprotov5"
+	"github.com/hashicorp/terraform-plugin-go/tfprotov5/tftypes"
+)
+
+func TestBashBundleInvalidPaths(t *testing.T) {
+	tests := map[string]string{
+		"empty":             "",
+		"NUL":               "a\x00b",
+		"absolute":          "/etc/passwd",
+		"root":              "/",
+		"parent":            "../a",
+		"parent in middle":  "a/../../b",
+		"parent at end":     "a/..",
+		"directory":         "a/",
+		"nested directory":  "a/b/",
+		"current":           ".",
+		"current at start":  "./a",
+		"current in middle": "a/./b",
+		"current at end":    "a/.",
+		"repeated slash":    "a//b",
+	}
+
+	for name, filePath := range tests {
+		t.Run(name, func(t *testing.T) {
+			for _, attrName := range []string{"files", "files_base64"} {
+				resp, err := NewProvider().ValidateDataSourceConfig(context.Background(), &tfprotov5.ValidateDataSourceConfigRequest{
+					TypeName: "bash_bundle",
+					Config: testBashBundleConfig(t, map[string]tftypes.Value{
+						attrName: stringMapVal(filePath, ""),
+					}),
+				})
+				if err != nil {
+					t.Fatalf("unexpected error: %s", err)
+				}
+				wantDiag(t, resp.Diagnostics, tfprotov5.DiagnosticSeverityError, "Invalid file path", fmt.Sprintf("%s[%q]", attrName, filePath), "")
+			}
+		})
+	}
+}
+
+func TestBashBundleValidPaths(t *testing.T) {
+	for _, filePath := range []string{"a", "a/b/c.txt", "..a", "a..", ".hidden", "a/.hidden", "a b"} {
+		t.Run(filePath, func(t *testing.T) {
+			resp, err := NewProvider().ValidateDataSourceConfig(context.Background(), &tfprotov5.ValidateDataSourceConfigRequest{
+				TypeName: "bash_bundle",
+				Config: testBashBundleConfig(t, map[string]tftypes.Value{
+					"files": stringMapVal(filePath, "x\n"),
+				}),
+			})
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+			wantNoDiags(t, resp.Diagnostics)
+		})
+	}
+}
+
+func TestBashBundleConflictingPaths(t *testing.T) {
+	tests := map[string]struct {
+		args map[string]tftypes.Value
+		path string // path of the expected error, if any
+	}{
+		"file inside file": {
+			args: map[string]tftypes.Value{
+				"files": stringMapVal("a", "x\n", "a/b", "y\n"),
+			},
+			path: `files["a/b"]`,
+		},
+		"file nested inside file": {
+			args: map[string]tftypes.Value{
+				"files": stringMapVal("a", "x\n", "a/b/c", "y\n"),
+			},
+			path: `files["a/b/c"]`,
+		},
+		"across arguments": {
+			args: map[string]tftypes.Value{
+				"files":        stringMapVal("a", "x\n"),
+				"files_base64": stringMapVal("a/b", "eQo="),
+			},
+			path: `files_base64["a/b"]`,
+		},
+		"siblings": {
+			args: map[string]tftypes.Value{
+				"files": stringMapVal("a/b", "x\n", "a/c", "y\n"),
+			},
+		},
+		"shared prefix": {
+			args: map[string]tftypes.Value{
+				"files": stringMapVal("a", "x\n", "ab/c", "y\n"),
+			},
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			resp, err := NewProvider().ValidateDataSourceConfig(context.Background(), &tfprotov5.ValidateDataSourceConfigRequest{
+				TypeName: "bash_bundle",
+				Config:   testBashBundleConfig(t, test.args),
+			})
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+			if test.path == "" {
+				wantNoDiags(t, resp.Diagnostics)
+				return
+			}
+			wantDiag(t, resp.Diagnostics, tfprotov5.DiagnosticSeverityError, "Conflicting file paths", test.path, "")
+		})
+	}
+}
+
+func TestBashBundleResult(t *testing.T) {
+	tests := map[string]struct {
+		args map[string]tftypes.Value
+		want string
+	}{
+		"text": {
+			args: map[string]tftypes.Value{
+				"files": stringMapVal("a.txt", "hello\n"),
+			},
+			want: `#!/usr/bin/env bash
+set -e
+cat >'a.txt' <<'EOF'
+hello
+EOF
+`,
+		},
+		"nested": {
+			args: map[string]tftypes.Value{
+				"files": stringMapVal("a/b/c.txt", "hello\n"),
+			},
+			want: `#!/usr/bin/env bash
+set -e
+mkdir -p -- 'a/b'
+cat >'a/b/c.txt' <<'EOF'
+hello
+EOF
+`,
+		},
+		"no trailing newline": {
+			args: map[string]tftypes.Value{
+				"files": stringMapVal("a.txt", "it's"),
+			},
+			want: `#!/usr/bin/env bash
+set -e
+printf '%s' 'it'\''s' >'a.txt'
+`,
+		},
+		"empty": {
+			args: map[string]tftypes.Value{
+				"files": stringMapVal("a.txt", ""),
+			},
+			want: `#!/usr/bin/env bash
+set -e
+: >'a.txt'
+`,
+		},
+		"delimiter in content": {
+			args: map[string]tftypes.Value{
+				"files": stringMapVal("a.txt", "EOF\nEOF_1\n"),
+			},
+			want: `#!/usr/bin/env bash
+set -e
+cat >'a.txt' <<'EOF_2'
+EOF
+EOF_1
+EOF_2
+`,
+		},
+		"base64": {
+			args: map[string]tftypes.Value{
+				"files_base64": stringMapVal("a.bin", base64.StdEncoding.EncodeToString([]byte{0, 1, 2})),
+			},
+			want: `#!/usr/bin/env bash
+set -e
+base64 -d >'a.bin' <<'EOF'
+AAEC
+EOF
+`,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			got := testBashBundleResult(t, test.args)
+			if got != test.want {
+				t.Errorf("wrong result\ngot:\n%s\nwant:\n%s", got, test.want)
+			}
+		})
+	}
+}
+
+func TestBashBundleRun(t *testing.T) {
+	bash, err := exec.LookPath("bash")
+	if err != nil {
+		t.Skip("bash is not available")
+	}
+
+	files := map[string][]byte{
+		"a.txt":        []byte("hello\n"),
+		"sub/dir/b.sh": []byte("#!/bin/sh\necho \"$HOME\" `pwd`\nEOF\n"),
+		"c":            []byte("no newline"),
+		"empty":        {},
+		"bin":          {0, 0xff, '\n', 0},
+	}
+	script := testBashBundleResult(t, map[string]tftypes.Value{
+		"files": stringMapVal(
+			"a.txt", string(files["a.txt"]),
+			"sub/dir/b.sh", string(files["sub/dir/b.sh"]),
+			"c", string(files["c"]),
+			"empty", string(files["empty"]),
+		),
+		"files_base64": stringMapVal(
+			"bin", base64.StdEncoding.EncodeToString(files["bin"]),
+		),
+	})
+
+	dir := t.TempDir()
+	cmd := exec.Command(bash, "-c", script)
+	cmd.Dir = dir
+	if out, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("bash failed: %s\n%s", err, out)
+	}
+	for name, want := range files {
+		got, err := os.ReadFile(filepath.Join(dir, filepath.FromSlash(name)))
+		if err != nil {
+			t.Errorf("can't read %s: %s", name, err)
+			continue
+		}
+		if string(got) != string(want) {
+			t.Errorf("wrong content for %s\ngot:  %q\nwant: %q", name, got, want)
+		}
+	}
+}
+
+// testBashBundleConfig returns a bash_bundle configuration with the given
+// arguments set, and all of the other arguments null.
+func testBashBundleConfig(t *testing.T, args map[string]tftypes.Value) *tfprotov5.DynamicValue {
+	t.Helper()
+	attrs := make(map[string]tftypes.Value, len(bashBundleType.AttributeTypes))
+	for name, aty := range bashBundleType.AttributeTypes {
+		attrs[name] = tftypes.NewValue(aty, nil)
+	}
+	for name, v := range args {
+		if _, ok := bashBundleType.AttributeTypes[name]; !ok {
+			t.Fatalf("bash_bundle has no argument named %q", name)
+		}
+		attrs[name] = v
+	}
+	dv, err := tfprotov5.NewDynamicValue(bashBundleType, tftypes.NewValue(bashBundleType, attrs))
+	if err != nil {
+		t.Fatalf("failed to encode configuration: %s", err)
+	}
+	return &dv
+}
+
+// testBashBundleResult calls ReadDataSource for a bash_bundle data source
+// with the given arguments, failing the test if there are any errors, and
+// returns the result attribute.
+func testBashBundleResult(t *testing.T, args map[string]tftypes.Value) string {
+	t.Helper()
+	resp, err := NewProvider().ReadDataSource(context.Background(), &tfprotov5.ReadDataSourceRequest{
+		TypeName: "bash_bundle",
+		Config:   testBashBundleConfig(t, args),
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	wantNoErrors(t, resp.Diagnostics)
+	v, err := resp.State.Unmarshal(bashBundleType)
+	if err != nil {
+		t.Fatalf("failed to decode state: %s", err)
+	}
+	var attrs map[string]tftypes.Value
+	if err := v.As(&attrs); err != nil {
+		t.Fatalf("failed to decode state: %s", err)
+	}
+	return testStringAttr(t, attrs, "result")
+}
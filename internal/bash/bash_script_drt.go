@@ -1,23 +1,213 @@
 package bash
 
 import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
 	"fmt"
+	"math"
 	"math/big"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
 
 	"github.com/hashicorp/terraform-plugin-go/tfprotov5"
 	"github.com/hashicorp/terraform-plugin-go/tfprotov5/tftypes"
+
+	"github.com/apparentlymart/terraform-provider-bash/bashgen"
 )
 
 type bashScriptConfig struct {
 	Source    string
 	Variables map[string]tftypes.Value
+
+	// VariablesUnknown is set if "variables", or one of the layers to
+	// merge into it, isn't known yet, in which case Variables might be
+	// missing some of the names that the configuration refers to.
+	VariablesUnknown bool
+
+	DeclareScope string
+	ReadOnly     bool
+	Export       bool
+	SortLists    bool
+
+	// ReadOnlyNames and ExportNames record the per-variable "readonly"
+	// and "export" settings given using the structured form of a variable,
+	// which override the arguments of the same names.
+	ReadOnlyNames map[string]bool
+	ExportNames   map[string]bool
+
+	// ForcedReadOnlyNames is the "readonly_names" argument, listing
+	// variables to declare as read-only even when ReadOnly is false.
+	ForcedReadOnlyNames []string
+
+	GlobalNames []string
+
+	Markers       bool
+	CommentPrefix string
+
+	UnexportNames []string
+
+	RawVariables map[string]string
+
+	RejectReservedWords bool
+
+	SingleArray            string
+	SingleArrayCollections string
+
+	ConvertWindowsPaths []string
+
+	NumericNames []string
+
+	BoolFormat string
+	BoolTokens map[string]string
+
+	ListAsWords []string
+
+	SplitToArray map[string]string
+
+	ListAsNullDelimited []string
+
+	FunctionName string
+	NamePrefix   string
+
+	ExitTrap []string
+
+	Shebang              string
+	ShebangDefault       bool
+	ReplaceSourceShebang bool
+
+	VariablesMarker string
+
+	HermeticPreamble bool
+
+	StrictMode bool
+
+	RequiredCommands []string
+
+	SourceIFS string
+	WrapIFS   bool
+
+	EmitAssocKeyOrder bool
+	SortAssocBy       string
+
+	EmitArrayCounts bool
+	AnnotateTypes   bool
+
+	EnvOverridePrefix string
+
+	TraceNames []string
+
+	Dialect string
+
+	Expect map[string]string
+
+	EmbedChecksum   bool
+	GeneratedHeader bool
+
+	TrailingNewline string
+	LineEndings     string
+
+	ResultSensitive bool
+
+	EmptyStringMode string
+
+	Preview bool
+
+	MaxLineLength int
+	Indent        string
+
+	VersionFallback      bool
+	MapsAsParallelArrays bool
+
+	SplitArrayDeclaration bool
+
+	ShellCheckDirectives bool
+
+	RestrictedSafe bool
+
+	PosixBashSafe bool
+
+	MinBashVersion string
+
+	NullCollectionMode string
+	OmitNull           bool
+
+	// config retains the full configuration object, so that we can echo
+	// back the arguments exactly as given when producing a result.
+	config map[string]tftypes.Value
 }
 
 var bashScriptType = tftypes.Object{
 	AttributeTypes: map[string]tftypes.Type{
-		"source":    tftypes.String,
-		"variables": tftypes.DynamicPseudoType,
-		"result":    tftypes.String,
+		"source":                   tftypes.String,
+		"variables":                tftypes.DynamicPseudoType,
+		"declare_scope":            tftypes.String,
+		"sort_lists":               tftypes.Bool,
+		"markers":                  tftypes.Bool,
+		"comment_prefix":           tftypes.String,
+		"unexport_names":           setOfString,
+		"single_array":             tftypes.String,
+		"single_array_collections": tftypes.String,
+		"convert_windows_paths":    setOfString,
+		"exit_trap":                listOfString,
+		"emit_assoc_key_order":     tftypes.Bool,
+		"sort_assoc_by":            tftypes.String,
+		"env_override_prefix":      tftypes.String,
+		"trace_names":              setOfString,
+		"dialect":                  tftypes.String,
+		"expect":                   mapOfString,
+		"embed_checksum":           tftypes.Bool,
+		"empty_string_mode":        tftypes.String,
+		"preview":                  tftypes.Bool,
+		"numeric_names":            setOfString,
+		"source_ifs":               tftypes.String,
+		"shebang":                  tftypes.String,
+		"shebang_default":          tftypes.Bool,
+		"replace_source_shebang":   tftypes.Bool,
+		"hermetic_preamble":        tftypes.Bool,
+		"max_line_length":          tftypes.Number,
+		"version_fallback":         tftypes.Bool,
+		"required_commands":        listOfString,
+		"split_array_declaration":  tftypes.Bool,
+		"list_as_words":            setOfString,
+		"shellcheck_directives":    tftypes.Bool,
+		"restricted_safe":          tftypes.Bool,
+		"list_as_null_delimited":   setOfString,
+		"min_bash_version":         tftypes.String,
+		"null_collection_mode":     tftypes.String,
+		"variables_marker":         tftypes.String,
+		"posix_bash_safe":          tftypes.Bool,
+		"strict_mode":              tftypes.Bool,
+		"bool_format":              tftypes.String,
+		"bool_tokens":              mapOfString,
+		"global_names":             setOfString,
+		"split_to_array":           mapOfString,
+		"emit_array_counts":        tftypes.Bool,
+		"omit_null":                tftypes.Bool,
+		"readonly":                 tftypes.Bool,
+		"readonly_names":           setOfString,
+		"export":                   tftypes.Bool,
+		"maps_as_parallel_arrays":  tftypes.Bool,
+		"trailing_newline":         tftypes.String,
+		"raw_variables":            mapOfString,
+		"function_name":            tftypes.String,
+		"indent":                   tftypes.String,
+		"annotate_types":           tftypes.Bool,
+		"result_sensitive":         tftypes.Bool,
+		"name_prefix":              tftypes.String,
+		"generated_header":         tftypes.Bool,
+		"line_endings":             tftypes.String,
+		"reject_reserved_words":    tftypes.Bool,
+		"result":                   tftypes.String,
+		"result_base64":            tftypes.String,
+		"result_sha256":            tftypes.String,
+		"version_constraint":       tftypes.String,
+		"env_result":               tftypes.String,
+		"variable_bash_types":      mapOfString,
+		"sensitive_result":         tftypes.String,
+		"result_lines":             listOfString,
 	},
 }
 
@@ -25,22 +215,46 @@ var mapOfString = tftypes.Map{
 	AttributeType: tftypes.String,
 }
 
+var mapOfNumber = tftypes.Map{
+	AttributeType: tftypes.Number,
+}
+
 var listOfString = tftypes.List{
 	ElementType: tftypes.String,
 }
 
-func newBashScriptConfig(raw *tfprotov5.DynamicValue) (*bashScriptConfig, []*tfprotov5.Diagnostic) {
+var listOfNumber = tftypes.List{
+	ElementType: tftypes.Number,
+}
+
+var setOfString = tftypes.Set{
+	ElementType: tftypes.String,
+}
+
+// newBashScriptConfig decodes and validates the given raw bash_script
+// configuration.
+//
+// defaultMinBashVersion is the value to use for min_bash_version if it isn't
+// set in the configuration, which comes from the provider configuration. It
+// may be empty, such as when the provider isn't configured yet.
+func newBashScriptConfig(raw *tfprotov5.DynamicValue, defaultMinBashVersion string) (*bashScriptConfig, []*tfprotov5.Diagnostic) {
 	ret := &bashScriptConfig{}
 	var diags []*tfprotov5.Diagnostic
 
 	lessRaw, err := raw.Unmarshal(bashScriptType)
 	if err != nil {
 		// This particular error shouldn't happen because Terraform ought to
-		// have verified that the configuration matches our schema.
+		// have verified that the configuration matches our schema, so if
+		// we get here it's probably because Terraform and the provider
+		// disagree about what the schema is. We'll try to say how.
+		detail := fmt.Sprintf("The given configuration doesn't match the expected schema: %s.", err)
+		if mismatch := schemaMismatchDetail(raw, bashScriptType); mismatch != "" {
+			detail = fmt.Sprintf("The given configuration doesn't match the expected schema: %s. This suggests that Terraform and the provider disagree about the bash_script schema, such as if Terraform is running a different version of the provider than the one it got the schema from.", mismatch)
+		}
 		diags = append(diags, &tfprotov5.Diagnostic{
 			Severity: tfprotov5.DiagnosticSeverityError,
 			Summary:  "Invalid configuration",
-			Detail:   fmt.Sprintf("The given configuration doesn't match the expected schema: %s.", err),
+			Detail:   detail,
 		})
 		return ret, diags
 	}
@@ -57,23 +271,38 @@ func newBashScriptConfig(raw *tfprotov5.DynamicValue) (*bashScriptConfig, []*tfp
 		return ret, diags
 	}
 
-	// If we get down here then obj should be a three-element map with
-	// elements matching the bashScriptType shape. Therefore we assume that
-	// some second-level conversions should always succeed.
-	err = obj["source"].As(&ret.Source)
-	if err != nil {
-		panic("source isn't a string")
-	}
+	ret.config = obj
+
+	// If we get down here then obj should be a map with one element per
+	// attribute in the bashScriptType shape. Therefore we assume that some
+	// second-level conversions should always succeed.
+	ret.Source = stringAttr(obj, "source", "")
 
 	// "variables" is typed as DynamicPseudoType, so Terraform will allow it
-	// to be anything in principle. We need it to be an object type though,
-	// because we'll be using the attribute names as variable names.
-	err = obj["variables"].As(&ret.Variables)
-	if err != nil {
+	// to be anything in principle. We need it to be an object or map type
+	// though, because we'll be using the attribute names or map keys as
+	// variable names, or a list or tuple of those to merge in order.
+	//
+	// If it isn't known yet then we can't check it, so we'll wait until it
+	// is, and the result will be unknown in the meantime.
+	var layers []tftypes.Value
+	if v := obj["variables"]; !v.IsKnown() {
+		// Nothing to check yet.
+		ret.VariablesUnknown = true
+	} else if (v.Is(tftypes.List{}) || v.Is(tftypes.Tuple{})) && v.As(&layers) == nil {
+		var moreDiags []*tfprotov5.Diagnostic
+		ret.Variables, moreDiags = mergeVariables(layers)
+		diags = append(diags, moreDiags...)
+		for _, layer := range layers {
+			if !layer.IsKnown() {
+				ret.VariablesUnknown = true
+			}
+		}
+	} else if err := obj["variables"].As(&ret.Variables); err != nil {
 		diags = append(diags, &tfprotov5.Diagnostic{
 			Severity: tfprotov5.DiagnosticSeverityError,
 			Summary:  "Invalid variables",
-			Detail:   "The \"variables\" argument must be an object with one attribute per variable you wish to declare for the Bash script.",
+			Detail:   "The \"variables\" argument must be an object or map with one element per variable you wish to declare for the Bash script, or a list of such objects or maps to merge.",
 			Attribute: &tftypes.AttributePath{
 				Steps: []tftypes.AttributePathStep{
 					tftypes.AttributeName("variables"),
@@ -82,6 +311,43 @@ func newBashScriptConfig(raw *tfprotov5.DynamicValue) (*bashScriptConfig, []*tfp
 		})
 	}
 
+	var moreDiags []*tfprotov5.Diagnostic
+	ret.Variables, ret.ReadOnlyNames, ret.ExportNames, moreDiags = unwrapStructuredVariables(ret.Variables)
+	diags = append(diags, moreDiags...)
+	setsAsLists(ret.Variables)
+	emptyCollectionsAsStrings(ret.Variables)
+	diags = append(diags, objectsAsMaps(ret.Variables)...)
+
+	// A script that only declares variables is occasionally what's intended,
+	// but more often it means "source" was left empty by mistake, such as
+	// by referring to a file that turned out to be empty.
+	if obj["source"].IsKnown() && strings.TrimSpace(ret.Source) == "" && len(ret.Variables) != 0 {
+		diags = append(diags, &tfprotov5.Diagnostic{
+			Severity: tfprotov5.DiagnosticSeverityWarning,
+			Summary:  "Empty script source",
+			Detail:   "The \"source\" argument is empty or contains only whitespace, so the result will declare the given variables but do nothing with them. If that's intended, you can ignore this warning.",
+			Attribute: &tftypes.AttributePath{
+				Steps: []tftypes.AttributePathStep{
+					tftypes.AttributeName("source"),
+				},
+			},
+		})
+	}
+
+	ret.NamePrefix = stringAttr(obj, "name_prefix", "")
+	if ret.NamePrefix != "" && !bashgen.ValidName(ret.NamePrefix) {
+		diags = append(diags, &tfprotov5.Diagnostic{
+			Severity: tfprotov5.DiagnosticSeverityError,
+			Summary:  "Invalid name prefix",
+			Detail:   fmt.Sprintf("Cannot use %q as a prefix for Bash variable names. The prefix must follow the same rules as a variable name.", ret.NamePrefix),
+			Attribute: &tftypes.AttributePath{
+				Steps: []tftypes.AttributePathStep{
+					tftypes.AttributeName("name_prefix"),
+				},
+			},
+		})
+	}
+
 	for name, val := range ret.Variables {
 		if len(name) == 0 {
 			diags = append(diags, &tfprotov5.Diagnostic{
@@ -97,11 +363,11 @@ func newBashScriptConfig(raw *tfprotov5.DynamicValue) (*bashScriptConfig, []*tfp
 			})
 			continue
 		}
-		if !validVariableName(name) {
+		if !bashgen.ValidName(ret.NamePrefix + name) {
 			diags = append(diags, &tfprotov5.Diagnostic{
 				Severity: tfprotov5.DiagnosticSeverityError,
 				Summary:  "Invalid variable name",
-				Detail:   fmt.Sprintf("Cannot use %q as a Bash variable name.", name),
+				Detail:   fmt.Sprintf("Cannot use %q as a Bash variable name.", ret.NamePrefix+name),
 				Attribute: &tftypes.AttributePath{
 					Steps: []tftypes.AttributePathStep{
 						tftypes.AttributeName("variables"),
@@ -111,47 +377,95 @@ func newBashScriptConfig(raw *tfprotov5.DynamicValue) (*bashScriptConfig, []*tfp
 			})
 			continue
 		}
+		if val.IsNull() && !isCollection(val) {
+			// Null values are handled below, along with null_collection_mode.
+			continue
+		}
 		switch {
-		case val.Is(tftypes.String): // okay
+		case val.Is(tftypes.String):
+			diags = append(diags, nulDiags(val, fmt.Sprintf("the value of %q", name), &tftypes.AttributePath{
+				Steps: []tftypes.AttributePathStep{
+					tftypes.AttributeName("variables"),
+					tftypes.AttributeName(name),
+				},
+			})...)
 		case val.Is(tftypes.Number):
-			var f big.Float
-			if err := val.As(&f); err != nil {
-				// Weird!
-				diags = append(diags, &tfprotov5.Diagnostic{
-					Severity: tfprotov5.DiagnosticSeverityError,
-					Summary:  "Invalid variable value",
-					Detail:   fmt.Sprintf("Failed to decode %q as a number: %s.", name, err),
-					Attribute: &tftypes.AttributePath{
-						Steps: []tftypes.AttributePathStep{
-							tftypes.AttributeName("variables"),
-							tftypes.AttributeName(name),
-						},
+			diags = append(diags, integerDiags(val, fmt.Sprintf("value of %q", name), &tftypes.AttributePath{
+				Steps: []tftypes.AttributePathStep{
+					tftypes.AttributeName("variables"),
+					tftypes.AttributeName(name),
+				},
+			})...)
+		case val.Is(tftypes.Bool): // okay
+		case val.Is(listOfString):
+			if !val.IsKnown() || val.IsNull() {
+				continue
+			}
+			var elems []tftypes.Value
+			val.As(&elems)
+			for i, ev := range elems {
+				diags = append(diags, nulDiags(ev, fmt.Sprintf("element %d of %q", i, name), &tftypes.AttributePath{
+					Steps: []tftypes.AttributePathStep{
+						tftypes.AttributeName("variables"),
+						tftypes.AttributeName(name),
+						tftypes.ElementKeyInt(int64(i)),
 					},
-				})
+				})...)
+			}
+		case val.Is(listOfNumber):
+			if !val.IsKnown() || val.IsNull() {
+				continue
+			}
+			var elems []tftypes.Value
+			val.As(&elems)
+			for i, ev := range elems {
+				diags = append(diags, integerDiags(ev, fmt.Sprintf("element %d of %q", i, name), &tftypes.AttributePath{
+					Steps: []tftypes.AttributePathStep{
+						tftypes.AttributeName("variables"),
+						tftypes.AttributeName(name),
+						tftypes.ElementKeyInt(int64(i)),
+					},
+				})...)
+			}
+		case val.Is(mapOfString) || val.Is(mapOfNumber):
+			if !val.IsKnown() || val.IsNull() {
 				continue
-			} else {
-				if !f.IsInt() {
+			}
+			var elems map[string]tftypes.Value
+			val.As(&elems)
+			keys := make([]string, 0, len(elems))
+			for k := range elems {
+				keys = append(keys, k)
+			}
+			sort.Strings(keys)
+			for _, k := range keys {
+				path := &tftypes.AttributePath{
+					Steps: []tftypes.AttributePathStep{
+						tftypes.AttributeName("variables"),
+						tftypes.AttributeName(name),
+						tftypes.ElementKeyString(k),
+					},
+				}
+				if strings.ContainsRune(k, 0) {
 					diags = append(diags, &tfprotov5.Diagnostic{
-						Severity: tfprotov5.DiagnosticSeverityError,
-						Summary:  "Invalid variable value",
-						Detail:   fmt.Sprintf("Can't use %s as value of %q: Bash doesn't support floating-point numbers.", f.Text('f', -1), name),
-						Attribute: &tftypes.AttributePath{
-							Steps: []tftypes.AttributePathStep{
-								tftypes.AttributeName("variables"),
-								tftypes.AttributeName(name),
-							},
-						},
+						Severity:  tfprotov5.DiagnosticSeverityError,
+						Summary:   "Invalid variable value",
+						Detail:    fmt.Sprintf("Can't use %q as a key in %q: Bash strings can't contain NUL characters.", k, name),
+						Attribute: path,
 					})
 				}
-				continue
+				what := fmt.Sprintf("element %q of %q", k, name)
+				if val.Is(mapOfNumber) {
+					diags = append(diags, integerDiags(elems[k], what, path)...)
+				} else {
+					diags = append(diags, nulDiags(elems[k], what, path)...)
+				}
 			}
-		case val.Is(listOfString):
-		case val.Is(mapOfString):
 		default:
 			diags = append(diags, &tfprotov5.Diagnostic{
 				Severity: tfprotov5.DiagnosticSeverityError,
 				Summary:  "Invalid variable value",
-				Detail:   fmt.Sprintf("Invalid value for Bash variable %q: Bash only supports strings, whole numbers, lists of strings, and maps of strings.", name),
+				Detail:   fmt.Sprintf("Invalid value for Bash variable %q: Bash only supports strings, whole numbers, booleans, lists or maps of strings or whole numbers, sets of strings, and objects whose attributes are all strings.", name),
 				Attribute: &tftypes.AttributePath{
 					Steps: []tftypes.AttributePathStep{
 						tftypes.AttributeName("variables"),
@@ -163,58 +477,1545 @@ func newBashScriptConfig(raw *tfprotov5.DynamicValue) (*bashScriptConfig, []*tfp
 		}
 	}
 
-	return ret, diags
-}
+	ret.DeclareScope = stringAttr(obj, "declare_scope", "default")
+	switch ret.DeclareScope {
+	case "default", "global_explicit":
+		// okay
+	default:
+		diags = append(diags, &tfprotov5.Diagnostic{
+			Severity: tfprotov5.DiagnosticSeverityError,
+			Summary:  "Invalid declaration scope",
+			Detail:   "The \"declare_scope\" argument must be either \"default\" or \"global_explicit\".",
+			Attribute: &tftypes.AttributePath{
+				Steps: []tftypes.AttributePathStep{
+					tftypes.AttributeName("declare_scope"),
+				},
+			},
+		})
+	}
 
-func (c *bashScriptConfig) ResultObject(result string) tftypes.Value {
-	vty := variablesType(c.Variables)
-	return tftypes.NewValue(bashScriptType, map[string]tftypes.Value{
-		"source":    tftypes.NewValue(tftypes.String, c.Source),
-		"variables": tftypes.NewValue(vty, c.Variables),
-		"result":    tftypes.NewValue(tftypes.String, result),
-	})
-}
+	ret.GlobalNames = stringSetAttr(obj, "global_names")
+	diags = append(diags, ret.namedVariableDiags("global_names", ret.GlobalNames, nil)...)
 
-func (c *bashScriptConfig) ResultDynamicValue(result string) *tfprotov5.DynamicValue {
-	v, err := tfprotov5.NewDynamicValue(bashScriptType, c.ResultObject(result))
-	if err != nil {
-		// We control all of the inputs here, so any error represents a bug.
-		panic(fmt.Sprintf("failed to build dynamic value: %s", err))
+	ret.ReadOnly = boolAttr(obj, "readonly", true)
+	ret.ForcedReadOnlyNames = stringSetAttr(obj, "readonly_names")
+	diags = append(diags, ret.namedVariableDiags("readonly_names", ret.ForcedReadOnlyNames, func(name string, val tftypes.Value) string {
+		if readOnly, ok := ret.ReadOnlyNames[name]; ok && !readOnly {
+			return fmt.Sprintf("Can't force %q to be read-only because its own \"readonly\" setting is false.", name)
+		}
+		return ""
+	})...)
+	ret.Export = boolAttr(obj, "export", false)
+	ret.SortLists = boolAttr(obj, "sort_lists", false)
+
+	ret.Markers = boolAttr(obj, "markers", false)
+	ret.CommentPrefix = stringAttr(obj, "comment_prefix", "terraform-provider-bash")
+	if strings.ContainsAny(ret.CommentPrefix, "\r\n") {
+		diags = append(diags, &tfprotov5.Diagnostic{
+			Severity: tfprotov5.DiagnosticSeverityError,
+			Summary:  "Invalid comment prefix",
+			Detail:   "The marker text in \"comment_prefix\" must not contain line breaks, because it must fit on a single comment line.",
+			Attribute: &tftypes.AttributePath{
+				Steps: []tftypes.AttributePathStep{
+					tftypes.AttributeName("comment_prefix"),
+				},
+			},
+		})
+	}
+
+	ret.UnexportNames = stringSetAttr(obj, "unexport_names")
+	for _, name := range ret.UnexportNames {
+		if !bashgen.ValidName(name) {
+			diags = append(diags, &tfprotov5.Diagnostic{
+				Severity: tfprotov5.DiagnosticSeverityError,
+				Summary:  "Invalid variable name",
+				Detail:   fmt.Sprintf("Cannot use %q as a Bash variable name.", name),
+				Attribute: &tftypes.AttributePath{
+					Steps: []tftypes.AttributePathStep{
+						tftypes.AttributeName("unexport_names"),
+						tftypes.ElementKeyValue(tftypes.NewValue(tftypes.String, name)),
+					},
+				},
+			})
+		}
+	}
+
+	ret.RawVariables = stringMapAttr(obj, "raw_variables")
+	for name := range ret.RawVariables {
+		path := &tftypes.AttributePath{
+			Steps: []tftypes.AttributePathStep{
+				tftypes.AttributeName("raw_variables"),
+				tftypes.ElementKeyString(name),
+			},
+		}
+		if !bashgen.ValidName(ret.NamePrefix + name) {
+			diags = append(diags, &tfprotov5.Diagnostic{
+				Severity:  tfprotov5.DiagnosticSeverityError,
+				Summary:   "Invalid variable name",
+				Detail:    fmt.Sprintf("Cannot use %q as a Bash variable name.", ret.NamePrefix+name),
+				Attribute: path,
+			})
+			continue
+		}
+		if _, exists := ret.Variables[name]; exists {
+			diags = append(diags, &tfprotov5.Diagnostic{
+				Severity:  tfprotov5.DiagnosticSeverityError,
+				Summary:   "Duplicate variable name",
+				Detail:    fmt.Sprintf("The variable %q is given in both \"variables\" and \"raw_variables\".", name),
+				Attribute: path,
+			})
+		}
+	}
+
+	ret.RejectReservedWords = boolAttr(obj, "reject_reserved_words", false)
+
+	ret.SingleArray = stringAttr(obj, "single_array", "")
+	ret.SingleArrayCollections = stringAttr(obj, "single_array_collections", "error")
+	if ret.SingleArray != "" && !bashgen.ValidName(ret.SingleArray) {
+		diags = append(diags, &tfprotov5.Diagnostic{
+			Severity: tfprotov5.DiagnosticSeverityError,
+			Summary:  "Invalid array name",
+			Detail:   fmt.Sprintf("Cannot use %q as a Bash variable name.", ret.SingleArray),
+			Attribute: &tftypes.AttributePath{
+				Steps: []tftypes.AttributePathStep{
+					tftypes.AttributeName("single_array"),
+				},
+			},
+		})
+	}
+	switch ret.SingleArrayCollections {
+	case "error":
+		if ret.SingleArray == "" {
+			break
+		}
+		for name, val := range ret.Variables {
+			if isCollection(val) {
+				diags = append(diags, &tfprotov5.Diagnostic{
+					Severity: tfprotov5.DiagnosticSeverityError,
+					Summary:  "Invalid variable value",
+					Detail:   fmt.Sprintf("Can't include %q in the single associative array %q, because Bash doesn't support nested arrays. Set single_array_collections = \"json\" to encode lists and maps as JSON strings instead.", name, ret.SingleArray),
+					Attribute: &tftypes.AttributePath{
+						Steps: []tftypes.AttributePathStep{
+							tftypes.AttributeName("variables"),
+							tftypes.AttributeName(name),
+						},
+					},
+				})
+			}
+		}
+	case "json":
+		// okay
+	default:
+		diags = append(diags, &tfprotov5.Diagnostic{
+			Severity: tfprotov5.DiagnosticSeverityError,
+			Summary:  "Invalid collection handling mode",
+			Detail:   "The \"single_array_collections\" argument must be either \"error\" or \"json\".",
+			Attribute: &tftypes.AttributePath{
+				Steps: []tftypes.AttributePathStep{
+					tftypes.AttributeName("single_array_collections"),
+				},
+			},
+		})
+	}
+
+	ret.ConvertWindowsPaths = stringSetAttr(obj, "convert_windows_paths")
+	diags = append(diags, ret.namedVariableDiags("convert_windows_paths", ret.ConvertWindowsPaths, func(name string, val tftypes.Value) string {
+		if !val.Is(tftypes.String) {
+			return fmt.Sprintf("Can't convert Windows paths in %q because only string variables can contain paths.", name)
+		}
+		return ""
+	})...)
+
+	ret.NumericNames = stringSetAttr(obj, "numeric_names")
+	diags = append(diags, ret.namedVariableDiags("numeric_names", ret.NumericNames, func(name string, val tftypes.Value) string {
+		if !val.Is(tftypes.String) {
+			return fmt.Sprintf("Can't treat %q as a number because it isn't a string variable.", name)
+		}
+		if !val.IsKnown() || val.IsNull() {
+			return ""
+		}
+		var s string
+		val.As(&s)
+		i, ok := new(big.Int).SetString(s, 10)
+		if !ok {
+			return fmt.Sprintf("Can't treat %q as a number because %q isn't a whole number in decimal notation.", name, s)
+		}
+		if !i.IsInt64() {
+			return fmt.Sprintf("Can't treat %q as a number because %s is outside the range of Bash integers, which are signed 64-bit integers.", name, s)
+		}
+		return ""
+	})...)
+
+	ret.BoolFormat = stringAttr(obj, "bool_format", "word")
+	switch ret.BoolFormat {
+	case "word", "integer", "presence":
+		// okay
+	default:
+		diags = append(diags, &tfprotov5.Diagnostic{
+			Severity: tfprotov5.DiagnosticSeverityError,
+			Summary:  "Invalid boolean format",
+			Detail:   "The \"bool_format\" argument must be \"word\", \"integer\", or \"presence\".",
+			Attribute: &tftypes.AttributePath{
+				Steps: []tftypes.AttributePathStep{
+					tftypes.AttributeName("bool_format"),
+				},
+			},
+		})
 	}
-	return &v
-}
 
-// variablesType is a helper to work around the fact that tftypes doesn't,
-// at least at the time of writing this, have a way to ask for the type
-// of a value, and so instead we assume all variables will be one of the
-// types we accept during newBashScriptConfig and thus re-calculate the
-// effective object type for the given variables, so we can ultimately
-// construct a valid DynamicValue to return in responses.
-func variablesType(vars map[string]tftypes.Value) tftypes.Type {
-	if len(vars) == 0 {
-		return tftypes.Object{
-			AttributeTypes: nil,
+	ret.BoolTokens = stringMapAttr(obj, "bool_tokens")
+	if ret.BoolTokens != nil {
+		for _, key := range []string{"true", "false"} {
+			if _, ok := ret.BoolTokens[key]; !ok {
+				diags = append(diags, &tfprotov5.Diagnostic{
+					Severity: tfprotov5.DiagnosticSeverityError,
+					Summary:  "Invalid boolean tokens",
+					Detail:   fmt.Sprintf("The \"bool_tokens\" argument must have both \"true\" and \"false\" elements, but %q is missing.", key),
+					Attribute: &tftypes.AttributePath{
+						Steps: []tftypes.AttributePathStep{
+							tftypes.AttributeName("bool_tokens"),
+						},
+					},
+				})
+			}
+		}
+		for key := range ret.BoolTokens {
+			if key == "true" || key == "false" {
+				continue
+			}
+			diags = append(diags, &tfprotov5.Diagnostic{
+				Severity: tfprotov5.DiagnosticSeverityError,
+				Summary:  "Invalid boolean tokens",
+				Detail:   fmt.Sprintf("The \"bool_tokens\" argument may only have \"true\" and \"false\" elements, so %q is not allowed.", key),
+				Attribute: &tftypes.AttributePath{
+					Steps: []tftypes.AttributePathStep{
+						tftypes.AttributeName("bool_tokens"),
+						tftypes.ElementKeyString(key),
+					},
+				},
+			})
 		}
 	}
-	atys := make(map[string]tftypes.Type, len(vars))
-	for k, v := range vars {
+
+	ret.SplitToArray = stringMapAttr(obj, "split_to_array")
+	splitNames := make([]string, 0, len(ret.SplitToArray))
+	for name := range ret.SplitToArray {
+		splitNames = append(splitNames, name)
+	}
+	sort.Strings(splitNames)
+	for _, name := range splitNames {
+		path := &tftypes.AttributePath{
+			Steps: []tftypes.AttributePathStep{
+				tftypes.AttributeName("split_to_array"),
+				tftypes.ElementKeyString(name),
+			},
+		}
+		val, ok := ret.Variables[name]
 		switch {
-		case v.Is(tftypes.String):
-			atys[k] = tftypes.String
-		case v.Is(tftypes.Number):
-			atys[k] = tftypes.Number
-		case v.Is(listOfString):
-			atys[k] = listOfString
-		case v.Is(mapOfString):
-			atys[k] = mapOfString
-		default:
-			// DynamicPseudoType isn't actually valid to use here but
-			// we don't care because we shouldn't ever get here if there's
-			// a variable with a type other than the ones handled above.
-			atys[k] = tftypes.DynamicPseudoType
+		case !ok && !ret.VariablesUnknown:
+			diags = append(diags, &tfprotov5.Diagnostic{
+				Severity:  tfprotov5.DiagnosticSeverityError,
+				Summary:   "Reference to undeclared variable",
+				Detail:    fmt.Sprintf("The \"split_to_array\" argument refers to %q, but there is no such variable declared in \"variables\".", name),
+				Attribute: path,
+			})
+		case ok && !val.Is(tftypes.String):
+			diags = append(diags, &tfprotov5.Diagnostic{
+				Severity:  tfprotov5.DiagnosticSeverityError,
+				Summary:   "Unsuitable variable",
+				Detail:    fmt.Sprintf("Can't split %q into an array because it isn't a string variable.", name),
+				Attribute: path,
+			})
+		case ret.SplitToArray[name] == "":
+			diags = append(diags, &tfprotov5.Diagnostic{
+				Severity:  tfprotov5.DiagnosticSeverityError,
+				Summary:   "Invalid delimiter",
+				Detail:    fmt.Sprintf("The delimiter for splitting %q must not be empty.", name),
+				Attribute: path,
+			})
+		}
+	}
+
+	ret.ListAsWords = stringSetAttr(obj, "list_as_words")
+	diags = append(diags, ret.namedVariableDiags("list_as_words", ret.ListAsWords, func(name string, val tftypes.Value) string {
+		if !val.Is(listOfString) {
+			return fmt.Sprintf("Can't render %q as words because it isn't a list of strings.", name)
+		}
+		return ""
+	})...)
+	for _, name := range ret.ListAsWords {
+		val, ok := ret.Variables[name]
+		if !ok || !val.Is(listOfString) || !fullyKnown(val) {
+			continue
 		}
+		var elems []tftypes.Value
+		val.As(&elems)
+		for i, ev := range elems {
+			var es string
+			ev.As(&es)
+			if es == "" || strings.IndexFunc(es, unicode.IsSpace) >= 0 {
+				diags = append(diags, &tfprotov5.Diagnostic{
+					Severity: tfprotov5.DiagnosticSeverityWarning,
+					Summary:  "List element will not survive word splitting",
+					Detail:   fmt.Sprintf("Element %d of %q is empty or contains whitespace, so splitting the joined string into words won't produce the same elements. Consider declaring %q as an array instead, by removing it from \"list_as_words\".", i, name, name),
+					Attribute: &tftypes.AttributePath{
+						Steps: []tftypes.AttributePathStep{
+							tftypes.AttributeName("variables"),
+							tftypes.AttributeName(name),
+							tftypes.ElementKeyInt(int64(i)),
+						},
+					},
+				})
+			}
+		}
+	}
+
+	ret.ListAsNullDelimited = stringSetAttr(obj, "list_as_null_delimited")
+	diags = append(diags, ret.namedVariableDiags("list_as_null_delimited", ret.ListAsNullDelimited, func(name string, val tftypes.Value) string {
+		if !val.Is(listOfString) {
+			return fmt.Sprintf("Can't render %q as null-delimited output because it isn't a list of strings.", name)
+		}
+		if nameSet(ret.ListAsWords)[name] {
+			return fmt.Sprintf("Can't render %q as null-delimited output because it's also listed in \"list_as_words\".", name)
+		}
+		return ""
+	})...)
+
+	ret.FunctionName = stringAttr(obj, "function_name", "")
+	if ret.FunctionName != "" {
+		var detail string
+		switch {
+		case !bashgen.ValidName(ret.FunctionName):
+			detail = fmt.Sprintf("Cannot use %q as a Bash function name. The name must follow the same rules as a variable name.", ret.FunctionName)
+		case strings.HasPrefix(ret.FunctionName, ret.NamePrefix) && nameSet(ret.ListAsNullDelimited)[strings.TrimPrefix(ret.FunctionName, ret.NamePrefix)]:
+			detail = fmt.Sprintf("Can't use %q as the function name because it's also listed in \"list_as_null_delimited\", which declares a function of the same name.", ret.FunctionName)
+		}
+		if detail != "" {
+			diags = append(diags, &tfprotov5.Diagnostic{
+				Severity: tfprotov5.DiagnosticSeverityError,
+				Summary:  "Invalid function name",
+				Detail:   detail,
+				Attribute: &tftypes.AttributePath{
+					Steps: []tftypes.AttributePathStep{
+						tftypes.AttributeName("function_name"),
+					},
+				},
+			})
+		}
+	}
+
+	ret.ExitTrap = stringListAttr(obj, "exit_trap")
+	for i, cmd := range ret.ExitTrap {
+		if !balancedQuotes(cmd) {
+			diags = append(diags, &tfprotov5.Diagnostic{
+				Severity: tfprotov5.DiagnosticSeverityError,
+				Summary:  "Invalid exit trap command",
+				Detail:   "This command has an unterminated quoted string or a trailing backslash, which would break the generated trap command.",
+				Attribute: &tftypes.AttributePath{
+					Steps: []tftypes.AttributePathStep{
+						tftypes.AttributeName("exit_trap"),
+						tftypes.ElementKeyInt(int64(i)),
+					},
+				},
+			})
+		}
+	}
+
+	ret.Shebang = stringAttr(obj, "shebang", "")
+	ret.ShebangDefault = boolAttr(obj, "shebang_default", false)
+	ret.ReplaceSourceShebang = boolAttr(obj, "replace_source_shebang", false)
+	if ret.Shebang != "" && (!strings.HasPrefix(ret.Shebang, "#!") || strings.ContainsAny(ret.Shebang, "\r\n")) {
+		diags = append(diags, &tfprotov5.Diagnostic{
+			Severity: tfprotov5.DiagnosticSeverityError,
+			Summary:  "Invalid interpreter line",
+			Detail:   "The \"shebang\" argument must be a single line starting with \"#!\", such as \"#!/bin/bash\". To use the default interpreter line for the selected dialect, set shebang_default = true instead.",
+			Attribute: &tftypes.AttributePath{
+				Steps: []tftypes.AttributePathStep{
+					tftypes.AttributeName("shebang"),
+				},
+			},
+		})
+	}
+	if ret.Shebang != "" || ret.ShebangDefault {
+		attrName := "shebang"
+		if ret.Shebang == "" {
+			attrName = "shebang_default"
+		}
+		if obj["source"].IsKnown() && strings.HasPrefix(ret.Source, "#!") && !ret.ReplaceSourceShebang {
+			diags = append(diags, &tfprotov5.Diagnostic{
+				Severity: tfprotov5.DiagnosticSeverityError,
+				Summary:  "Conflicting interpreter lines",
+				Detail:   fmt.Sprintf("The script in \"source\" already starts with an interpreter line, so the result would have two. Either remove the interpreter line from the source, remove the %q argument, or set replace_source_shebang = true to use the %q argument instead of the source's interpreter line.", attrName, attrName),
+				Attribute: &tftypes.AttributePath{
+					Steps: []tftypes.AttributePathStep{
+						tftypes.AttributeName(attrName),
+					},
+				},
+			})
+		}
+	}
+
+	ret.VariablesMarker = strings.TrimSpace(stringAttr(obj, "variables_marker", defaultVariablesMarker))
+	if ret.VariablesMarker == "" || strings.ContainsAny(ret.VariablesMarker, "\r\n") {
+		diags = append(diags, &tfprotov5.Diagnostic{
+			Severity: tfprotov5.DiagnosticSeverityError,
+			Summary:  "Invalid variables marker",
+			Detail:   "The \"variables_marker\" argument must be a single non-empty line.",
+			Attribute: &tftypes.AttributePath{
+				Steps: []tftypes.AttributePathStep{
+					tftypes.AttributeName("variables_marker"),
+				},
+			},
+		})
+	} else if obj["source"].IsKnown() {
+		switch n := len(markerLines(ret.Source, ret.VariablesMarker)); {
+		case n > 1:
+			diags = append(diags, &tfprotov5.Diagnostic{
+				Severity: tfprotov5.DiagnosticSeverityError,
+				Summary:  "Ambiguous variables marker",
+				Detail:   fmt.Sprintf("The script in \"source\" contains the line %q %d times, so it's ambiguous where to insert the variable declarations. The marker must appear at most once.", ret.VariablesMarker, n),
+				Attribute: &tftypes.AttributePath{
+					Steps: []tftypes.AttributePathStep{
+						tftypes.AttributeName("source"),
+					},
+				},
+			})
+		case n == 0 && obj["variables_marker"].IsKnown() && !obj["variables_marker"].IsNull():
+			// If the marker was set explicitly then we assume that its
+			// absence is a mistake, rather than silently falling back to
+			// placing the declarations at the start.
+			diags = append(diags, &tfprotov5.Diagnostic{
+				Severity: tfprotov5.DiagnosticSeverityError,
+				Summary:  "Missing variables marker",
+				Detail:   fmt.Sprintf("The script in \"source\" doesn't contain a line %q to mark where to insert the variable declarations.", ret.VariablesMarker),
+				Attribute: &tftypes.AttributePath{
+					Steps: []tftypes.AttributePathStep{
+						tftypes.AttributeName("variables_marker"),
+					},
+				},
+			})
+		}
+	}
+
+	ret.HermeticPreamble = boolAttr(obj, "hermetic_preamble", false)
+	ret.StrictMode = boolAttr(obj, "strict_mode", false)
+
+	ret.RequiredCommands = stringListAttr(obj, "required_commands")
+	for i, cmd := range ret.RequiredCommands {
+		if cmd == "" || strings.ContainsAny(cmd, "\x00\r\n") {
+			diags = append(diags, &tfprotov5.Diagnostic{
+				Severity: tfprotov5.DiagnosticSeverityError,
+				Summary:  "Invalid required command",
+				Detail:   "Each required command must be a non-empty command name or path, without line breaks.",
+				Attribute: &tftypes.AttributePath{
+					Steps: []tftypes.AttributePathStep{
+						tftypes.AttributeName("required_commands"),
+						tftypes.ElementKeyInt(int64(i)),
+					},
+				},
+			})
+		}
+	}
+
+	ret.SourceIFS = stringAttr(obj, "source_ifs", "")
+	ret.WrapIFS = !obj["source_ifs"].IsNull()
+	if strings.ContainsRune(ret.SourceIFS, 0) {
+		diags = append(diags, &tfprotov5.Diagnostic{
+			Severity: tfprotov5.DiagnosticSeverityError,
+			Summary:  "Invalid IFS value",
+			Detail:   "The \"source_ifs\" argument must not contain NUL characters, because Bash variables cannot contain them.",
+			Attribute: &tftypes.AttributePath{
+				Steps: []tftypes.AttributePathStep{
+					tftypes.AttributeName("source_ifs"),
+				},
+			},
+		})
+	}
+	if ret.WrapIFS {
+		for _, declName := range []string{"IFS", savedIFSVar, hadIFSVar} {
+			if !strings.HasPrefix(declName, ret.NamePrefix) {
+				continue
+			}
+			name := strings.TrimPrefix(declName, ret.NamePrefix)
+			if _, exists := ret.Variables[name]; exists {
+				diags = append(diags, &tfprotov5.Diagnostic{
+					Severity: tfprotov5.DiagnosticSeverityError,
+					Summary:  "Invalid variable name",
+					Detail:   fmt.Sprintf("Can't declare a variable named %q when \"source_ifs\" is set, because the IFS wrapper needs to assign it.", declName),
+					Attribute: &tftypes.AttributePath{
+						Steps: []tftypes.AttributePathStep{
+							tftypes.AttributeName("variables"),
+							tftypes.AttributeName(name),
+						},
+					},
+				})
+			}
+		}
+	}
+
+	ret.EmitAssocKeyOrder = boolAttr(obj, "emit_assoc_key_order", false)
+	ret.EmitArrayCounts = boolAttr(obj, "emit_array_counts", false)
+	ret.AnnotateTypes = boolAttr(obj, "annotate_types", false)
+	ret.SortAssocBy = stringAttr(obj, "sort_assoc_by", "key")
+	switch ret.SortAssocBy {
+	case "key", "value":
+		// okay
+	default:
+		diags = append(diags, &tfprotov5.Diagnostic{
+			Severity: tfprotov5.DiagnosticSeverityError,
+			Summary:  "Invalid associative array sort order",
+			Detail:   "The \"sort_assoc_by\" argument must be either \"key\" or \"value\".",
+			Attribute: &tftypes.AttributePath{
+				Steps: []tftypes.AttributePathStep{
+					tftypes.AttributeName("sort_assoc_by"),
+				},
+			},
+		})
+	}
+
+	ret.EnvOverridePrefix = stringAttr(obj, "env_override_prefix", "")
+	if ret.EnvOverridePrefix != "" && !bashgen.ValidName(ret.EnvOverridePrefix) {
+		diags = append(diags, &tfprotov5.Diagnostic{
+			Severity: tfprotov5.DiagnosticSeverityError,
+			Summary:  "Invalid environment variable prefix",
+			Detail:   fmt.Sprintf("Cannot use %q as a prefix for Bash variable names: it must start with a letter or underscore and contain only letters, digits, and underscores.", ret.EnvOverridePrefix),
+			Attribute: &tftypes.AttributePath{
+				Steps: []tftypes.AttributePathStep{
+					tftypes.AttributeName("env_override_prefix"),
+				},
+			},
+		})
+	}
+
+	ret.TraceNames = stringSetAttr(obj, "trace_names")
+	diags = append(diags, ret.namedVariableDiags("trace_names", ret.TraceNames, nil)...)
+
+	ret.Dialect = stringAttr(obj, "dialect", "bash")
+	switch ret.Dialect {
+	case "bash", "make", "sh", "dotenv":
+		// okay, but the other dialects support fewer kinds of value, so
+		// we'll check the variables against the dialect at the end, once
+		// we've decoded all of the arguments that transform them.
+	default:
+		diags = append(diags, &tfprotov5.Diagnostic{
+			Severity: tfprotov5.DiagnosticSeverityError,
+			Summary:  "Unsupported dialect",
+			Detail:   "The \"dialect\" argument must be \"bash\", \"make\", \"sh\", or \"dotenv\".",
+			Attribute: &tftypes.AttributePath{
+				Steps: []tftypes.AttributePathStep{
+					tftypes.AttributeName("dialect"),
+				},
+			},
+		})
+	}
+
+	if ret.ShebangDefault && ret.Shebang == "" {
+		ret.Shebang = dialectShebangs[ret.Dialect]
+	}
+
+	ret.MapsAsParallelArrays = boolAttr(obj, "maps_as_parallel_arrays", false)
+	if ret.Dialect == "bash" && ret.SingleArray == "" && !ret.MapsAsParallelArrays {
+		// Bash can accept almost any string as an associative array key
+		// as long as it's quoted, but it rejects the empty string. That
+		// doesn't matter for parallel arrays, where keys are just elements.
+		for name, val := range ret.Variables {
+			if !(val.Is(mapOfString) || val.Is(mapOfNumber)) || !val.IsKnown() {
+				continue
+			}
+			var m map[string]tftypes.Value
+			val.As(&m)
+			if _, ok := m[""]; ok {
+				diags = append(diags, &tfprotov5.Diagnostic{
+					Severity: tfprotov5.DiagnosticSeverityError,
+					Summary:  "Invalid variable value",
+					Detail:   fmt.Sprintf("Can't use the empty string as a key in %q: Bash doesn't allow empty associative array keys.", name),
+					Attribute: &tftypes.AttributePath{
+						Steps: []tftypes.AttributePathStep{
+							tftypes.AttributeName("variables"),
+							tftypes.AttributeName(name),
+						},
+					},
+				})
+			}
+		}
+	}
+
+	ret.EmbedChecksum = boolAttr(obj, "embed_checksum", false)
+	ret.GeneratedHeader = boolAttr(obj, "generated_header", false)
+
+	ret.TrailingNewline = stringAttr(obj, "trailing_newline", "preserve")
+	switch ret.TrailingNewline {
+	case "preserve", "ensure", "strip":
+		// okay
+	default:
+		diags = append(diags, &tfprotov5.Diagnostic{
+			Severity: tfprotov5.DiagnosticSeverityError,
+			Summary:  "Invalid trailing newline mode",
+			Detail:   "The \"trailing_newline\" argument must be \"preserve\", \"ensure\", or \"strip\".",
+			Attribute: &tftypes.AttributePath{
+				Steps: []tftypes.AttributePathStep{
+					tftypes.AttributeName("trailing_newline"),
+				},
+			},
+		})
+	}
+
+	ret.LineEndings = stringAttr(obj, "line_endings", "lf")
+	switch ret.LineEndings {
+	case "lf", "crlf":
+		// okay
+	default:
+		diags = append(diags, &tfprotov5.Diagnostic{
+			Severity: tfprotov5.DiagnosticSeverityError,
+			Summary:  "Invalid line endings",
+			Detail:   "The \"line_endings\" argument must be either \"lf\" or \"crlf\".",
+			Attribute: &tftypes.AttributePath{
+				Steps: []tftypes.AttributePathStep{
+					tftypes.AttributeName("line_endings"),
+				},
+			},
+		})
+	}
+
+	ret.ResultSensitive = boolAttr(obj, "result_sensitive", false)
+
+	ret.EmptyStringMode = stringAttr(obj, "empty_string_mode", "declare_empty")
+	switch ret.EmptyStringMode {
+	case "declare_empty", "skip", "declare_null":
+		// okay
+	default:
+		diags = append(diags, &tfprotov5.Diagnostic{
+			Severity: tfprotov5.DiagnosticSeverityError,
+			Summary:  "Invalid empty string mode",
+			Detail:   "The \"empty_string_mode\" argument must be \"declare_empty\", \"skip\", or \"declare_null\".",
+			Attribute: &tftypes.AttributePath{
+				Steps: []tftypes.AttributePathStep{
+					tftypes.AttributeName("empty_string_mode"),
+				},
+			},
+		})
+	}
+
+	ret.Preview = boolAttr(obj, "preview", false)
+
+	ret.VersionFallback = boolAttr(obj, "version_fallback", false)
+	ret.SplitArrayDeclaration = boolAttr(obj, "split_array_declaration", false)
+	ret.ShellCheckDirectives = boolAttr(obj, "shellcheck_directives", false)
+	ret.RestrictedSafe = boolAttr(obj, "restricted_safe", false)
+	ret.PosixBashSafe = boolAttr(obj, "posix_bash_safe", false)
+
+	ret.MaxLineLength = intAttr(obj, "max_line_length", 0)
+	if ret.MaxLineLength < 0 {
+		diags = append(diags, &tfprotov5.Diagnostic{
+			Severity: tfprotov5.DiagnosticSeverityError,
+			Summary:  "Invalid maximum line length",
+			Detail:   "The \"max_line_length\" argument must be a positive whole number, or zero to disable line wrapping.",
+			Attribute: &tftypes.AttributePath{
+				Steps: []tftypes.AttributePathStep{
+					tftypes.AttributeName("max_line_length"),
+				},
+			},
+		})
+	}
+
+	ret.Indent = stringAttr(obj, "indent", "")
+	if strings.Trim(ret.Indent, " \t") != "" {
+		diags = append(diags, &tfprotov5.Diagnostic{
+			Severity: tfprotov5.DiagnosticSeverityError,
+			Summary:  "Invalid indentation",
+			Detail:   "The \"indent\" argument must contain only spaces and tabs.",
+			Attribute: &tftypes.AttributePath{
+				Steps: []tftypes.AttributePathStep{
+					tftypes.AttributeName("indent"),
+				},
+			},
+		})
+	}
+
+	ret.OmitNull = boolAttr(obj, "omit_null", false)
+	ret.NullCollectionMode = stringAttr(obj, "null_collection_mode", "omit")
+	switch ret.NullCollectionMode {
+	case "omit", "empty", "error":
+		// The result echoes back the original configuration value rather
+		// than ret.Variables, so we can change ret.Variables freely here.
+		for name, val := range ret.Variables {
+			if !val.IsNull() {
+				continue
+			}
+			if ret.OmitNull {
+				delete(ret.Variables, name)
+				continue
+			}
+			if !isCollection(val) {
+				diags = append(diags, &tfprotov5.Diagnostic{
+					Severity: tfprotov5.DiagnosticSeverityError,
+					Summary:  "Invalid variable value",
+					Detail:   fmt.Sprintf("The value for %q is null. Set omit_null = true to leave null variables undeclared.", name),
+					Attribute: &tftypes.AttributePath{
+						Steps: []tftypes.AttributePathStep{
+							tftypes.AttributeName("variables"),
+							tftypes.AttributeName(name),
+						},
+					},
+				})
+				continue
+			}
+			switch ret.NullCollectionMode {
+			case "omit":
+				delete(ret.Variables, name)
+			case "empty":
+				switch {
+				case val.Is(listOfString):
+					ret.Variables[name] = tftypes.NewValue(listOfString, []tftypes.Value{})
+				case val.Is(listOfNumber):
+					ret.Variables[name] = tftypes.NewValue(listOfNumber, []tftypes.Value{})
+				case val.Is(mapOfNumber):
+					ret.Variables[name] = tftypes.NewValue(mapOfNumber, map[string]tftypes.Value{})
+				default:
+					ret.Variables[name] = tftypes.NewValue(mapOfString, map[string]tftypes.Value{})
+				}
+			case "error":
+				diags = append(diags, &tfprotov5.Diagnostic{
+					Severity: tfprotov5.DiagnosticSeverityError,
+					Summary:  "Invalid variable value",
+					Detail:   fmt.Sprintf("The value for %q is null. Set null_collection_mode to \"omit\" or \"empty\" to allow null lists and maps.", name),
+					Attribute: &tftypes.AttributePath{
+						Steps: []tftypes.AttributePathStep{
+							tftypes.AttributeName("variables"),
+							tftypes.AttributeName(name),
+						},
+					},
+				})
+			}
+		}
+	default:
+		diags = append(diags, &tfprotov5.Diagnostic{
+			Severity: tfprotov5.DiagnosticSeverityError,
+			Summary:  "Invalid null collection mode",
+			Detail:   "The \"null_collection_mode\" argument must be \"omit\", \"empty\", or \"error\".",
+			Attribute: &tftypes.AttributePath{
+				Steps: []tftypes.AttributePathStep{
+					tftypes.AttributeName("null_collection_mode"),
+				},
+			},
+		})
+	}
+
+	ret.Expect = stringMapAttr(obj, "expect")
+	expectNames := make([]string, 0, len(ret.Expect))
+	for name := range ret.Expect {
+		expectNames = append(expectNames, name)
+	}
+	sort.Strings(expectNames)
+	for _, name := range expectNames {
+		if _, ok := ret.Variables[name]; !ok && !ret.VariablesUnknown {
+			diags = append(diags, &tfprotov5.Diagnostic{
+				Severity: tfprotov5.DiagnosticSeverityError,
+				Summary:  "Reference to undeclared variable",
+				Detail:   fmt.Sprintf("The \"expect\" argument refers to %q, but there is no such variable declared in \"variables\".", name),
+				Attribute: &tftypes.AttributePath{
+					Steps: []tftypes.AttributePathStep{
+						tftypes.AttributeName("expect"),
+						tftypes.ElementKeyString(name),
+					},
+				},
+			})
+		}
+	}
+
+	diags = append(diags, optionConflictDiags(ret)...)
+	diags = append(diags, restrictedDiags(ret)...)
+	diags = append(diags, posixModeDiags(ret)...)
+	diags = append(diags, exportDiags(ret)...)
+	diags = append(diags, specialNameDiags(ret)...)
+	diags = append(diags, reservedWordDiags(ret)...)
+	diags = append(diags, generatedNameDiags(ret)...)
+
+	ret.MinBashVersion = stringAttr(obj, "min_bash_version", defaultMinBashVersion)
+	if ret.MinBashVersion != "" && obj["min_bash_version"].IsKnown() {
+		minVersion, err := parseBashVersion(ret.MinBashVersion)
+		if err != nil {
+			diags = append(diags, &tfprotov5.Diagnostic{
+				Severity: tfprotov5.DiagnosticSeverityError,
+				Summary:  "Invalid minimum Bash version",
+				Detail:   fmt.Sprintf("Can't use %q as a Bash version: %s. Specify a version like \"4.2\".", ret.MinBashVersion, err),
+				Attribute: &tftypes.AttributePath{
+					Steps: []tftypes.AttributePathStep{
+						tftypes.AttributeName("min_bash_version"),
+					},
+				},
+			})
+		} else if ret.Dialect == "bash" && minVersion.Less(bashVersionAssocArrays) && !ret.VersionFallback && !ret.MapsAsParallelArrays && ret.SingleArray == "" && hasMaps(ret.Variables) {
+			// Maps are the most common reason to need Bash 4, so we report
+			// them individually with some suggestions.
+			names := make([]string, 0, len(ret.Variables))
+			for name, val := range ret.Variables {
+				if val.Is(mapOfString) || val.Is(mapOfNumber) {
+					names = append(names, name)
+				}
+			}
+			sort.Strings(names)
+			for _, name := range names {
+				diags = append(diags, &tfprotov5.Diagnostic{
+					Severity: tfprotov5.DiagnosticSeverityError,
+					Summary:  "Unsupported Bash version",
+					Detail:   fmt.Sprintf("The map %q would be declared as an associative array, but associative arrays require Bash %s and the minimum Bash version to support is %s. Set maps_as_parallel_arrays = true to declare maps as pairs of indexed arrays instead, or version_fallback = true to choose at runtime.", name, bashVersionAssocArrays, minVersion),
+					Attribute: &tftypes.AttributePath{
+						Steps: []tftypes.AttributePathStep{
+							tftypes.AttributeName("variables"),
+							tftypes.AttributeName(name),
+						},
+					},
+				})
+			}
+		} else if ret.Dialect == "bash" && minVersion.Less(ret.RequiredBashVersion()) {
+			diags = append(diags, &tfprotov5.Diagnostic{
+				Severity: tfprotov5.DiagnosticSeverityError,
+				Summary:  "Unsupported Bash version",
+				Detail:   fmt.Sprintf("The generated declarations require Bash %s, but the minimum Bash version to support is %s. Use only features that are compatible with Bash %s, or raise the minimum version.", ret.RequiredBashVersion(), minVersion, minVersion),
+				Attribute: &tftypes.AttributePath{
+					Steps: []tftypes.AttributePathStep{
+						tftypes.AttributeName("min_bash_version"),
+					},
+				},
+			})
+		}
+	}
+
+	diags = append(diags, ret.dialectValueDiags()...)
+
+	// We can only check the expectations once everything else is valid,
+	// because otherwise we might not be able to render the variables.
+	if !hasErrors(diags) {
+		diags = append(diags, ret.expectDiags(expectNames)...)
+	}
+
+	return ret, diags
+}
+
+// dialectValueDiags checks that the selected dialect can represent the
+// value of each variable, returning an error diagnostic for each one that
+// it can't.
+//
+// The check uses the values as we'd actually render them, because arguments
+// like split_to_array and bool_format can change a value's type.
+func (c *bashScriptConfig) dialectValueDiags() []*tfprotov5.Diagnostic {
+	var valueFn func(tftypes.Value) (string, error)
+	var what string
+	switch c.Dialect {
+	case "make":
+		valueFn = func(val tftypes.Value) (string, error) {
+			return makeValue(val, false)
+		}
+		what = "make variable"
+	case "sh":
+		valueFn = shValue
+		what = "shell variable"
+	case "dotenv":
+		valueFn = dotenvValue
+		what = "dotenv variable"
+	default:
+		// Bash supports all of the values we accept, and we report an
+		// unsupported dialect elsewhere.
+		return nil
+	}
+
+	var diags []*tfprotov5.Diagnostic
+	for name, val := range c.RenderVariables() {
+		if orig := c.Variables[name]; !fullyKnown(orig) || orig.IsNull() {
+			continue // we'll check it again once it's known
+		}
+		if _, err := valueFn(val); err != nil {
+			path := &tftypes.AttributePath{
+				Steps: []tftypes.AttributePathStep{
+					tftypes.AttributeName("variables"),
+					tftypes.AttributeName(name),
+				},
+			}
+			detail := fmt.Sprintf("Invalid value for %s %q: %s.", what, name, err)
+			if _, split := c.SplitToArray[name]; split {
+				path = &tftypes.AttributePath{
+					Steps: []tftypes.AttributePathStep{
+						tftypes.AttributeName("split_to_array"),
+						tftypes.ElementKeyString(name),
+					},
+				}
+				detail = fmt.Sprintf("Invalid value for %s %q after splitting it into a list: %s.", what, name, err)
+			}
+			diags = append(diags, &tfprotov5.Diagnostic{
+				Severity:  tfprotov5.DiagnosticSeverityError,
+				Summary:   "Invalid variable value",
+				Detail:    detail,
+				Attribute: path,
+			})
+		}
+	}
+	return diags
+}
+
+// expectDiags compares the rendered value of each of the given variables with
+// the literal given for it in the "expect" argument, returning an error
+// diagnostic for each one that doesn't match.
+func (c *bashScriptConfig) expectDiags(names []string) []*tfprotov5.Diagnostic {
+	var diags []*tfprotov5.Diagnostic
+	for _, name := range names {
+		if !fullyKnown(c.Variables[name]) {
+			continue // we'll check it again once it's known
+		}
+		got, err := c.Literal(name)
+		if err != nil {
+			// Should never happen, because we've already validated the
+			// variables.
+			continue
+		}
+		if want := c.Expect[name]; got != want {
+			diags = append(diags, &tfprotov5.Diagnostic{
+				Severity: tfprotov5.DiagnosticSeverityError,
+				Summary:  "Unexpected variable declaration",
+				Detail:   fmt.Sprintf("The value of %q is rendered as %s, but \"expect\" requires %s.", name, got, want),
+				Attribute: &tftypes.AttributePath{
+					Steps: []tftypes.AttributePathStep{
+						tftypes.AttributeName("expect"),
+						tftypes.ElementKeyString(name),
+					},
+				},
+			})
+		}
+	}
+	return diags
+}
+
+// RenderVariables returns the variables to render, after applying any
+// transformations of their values requested in the configuration.
+func (c *bashScriptConfig) RenderVariables() map[string]tftypes.Value {
+	ret := make(map[string]tftypes.Value, len(c.Variables))
+	for name, val := range c.Variables {
+		if val.Is(tftypes.Bool) {
+			// Bash has no boolean type, so we represent booleans either as
+			// the words "true" and "false", as the integers 1 and 0, as the
+			// custom strings given in bool_tokens, or, in "presence" mode,
+			// by declaring only the variables that are true.
+			var b bool
+			val.As(&b)
+			switch {
+			case c.BoolTokens != nil:
+				val = tftypes.NewValue(tftypes.String, c.BoolTokens[strconv.FormatBool(b)])
+			case c.BoolFormat == "integer" && b:
+				val = tftypes.NewValue(tftypes.Number, big.NewFloat(1))
+			case c.BoolFormat == "integer":
+				val = tftypes.NewValue(tftypes.Number, big.NewFloat(0))
+			case c.BoolFormat == "presence" && b:
+				val = tftypes.NewValue(tftypes.Number, big.NewFloat(1))
+			case c.BoolFormat == "presence":
+				continue
+			default:
+				val = tftypes.NewValue(tftypes.String, strconv.FormatBool(b))
+			}
+		}
+		ret[name] = val
+	}
+	for _, name := range c.ConvertWindowsPaths {
+		val, ok := ret[name]
+		if !ok || !val.Is(tftypes.String) {
+			continue
+		}
+		var s string
+		val.As(&s)
+		ret[name] = tftypes.NewValue(tftypes.String, msysPath(s))
+	}
+	for _, name := range c.NumericNames {
+		val, ok := ret[name]
+		if !ok || !val.Is(tftypes.String) {
+			continue
+		}
+		var s string
+		val.As(&s)
+		i, ok := new(big.Int).SetString(s, 10)
+		if !ok {
+			continue // should've been caught during validation
+		}
+		ret[name] = tftypes.NewValue(tftypes.Number, new(big.Float).SetInt(i))
+	}
+	for name, delim := range c.SplitToArray {
+		val, ok := ret[name]
+		if !ok || !val.Is(tftypes.String) || delim == "" {
+			continue
+		}
+		var s string
+		val.As(&s)
+		elems := []tftypes.Value{}
+		if s != "" {
+			// An empty string is treated as an empty list, rather than as
+			// a list containing a single empty element.
+			parts := strings.Split(s, delim)
+			elems = make([]tftypes.Value, len(parts))
+			for i, part := range parts {
+				elems[i] = tftypes.NewValue(tftypes.String, part)
+			}
+		}
+		ret[name] = tftypes.NewValue(listOfString, elems)
+	}
+	for _, name := range c.ListAsWords {
+		val, ok := ret[name]
+		if !ok || !val.Is(listOfString) {
+			continue
+		}
+		var elems []tftypes.Value
+		val.As(&elems)
+		words := make([]string, len(elems))
+		for i, ev := range elems {
+			ev.As(&words[i])
+		}
+		if c.SortLists {
+			sort.Strings(words)
+		}
+		ret[name] = tftypes.NewValue(tftypes.String, strings.Join(words, " "))
+	}
+	return ret
+}
+
+// readOnlyNames returns the per-variable read-only settings, combining
+// those from the structured variables with the readonly_names argument.
+func (c *bashScriptConfig) readOnlyNames() map[string]bool {
+	if len(c.ForcedReadOnlyNames) == 0 {
+		return c.ReadOnlyNames
+	}
+	ret := make(map[string]bool, len(c.ReadOnlyNames)+len(c.ForcedReadOnlyNames))
+	for name, readOnly := range c.ReadOnlyNames {
+		ret[name] = readOnly
+	}
+	for _, name := range c.ForcedReadOnlyNames {
+		ret[name] = true
+	}
+	return ret
+}
+
+// RenderOptions returns the bashgen options corresponding to the rendering
+// settings in the configuration.
+func (c *bashScriptConfig) RenderOptions() bashgen.Options {
+	return bashgen.Options{
+		Global:      c.DeclareScope == "global_explicit",
+		GlobalNames: nameSet(c.GlobalNames),
+		Local:       c.FunctionName != "",
+		Mutable:     !c.ReadOnly,
+		Export:      c.Export,
+
+		ReadOnlyNames: c.readOnlyNames(),
+		ExportNames:   c.ExportNames,
+		SortLists:     c.SortLists,
+		Unexport:      c.UnexportNames,
+
+		SingleArray:     c.SingleArray,
+		SingleArrayJSON: c.SingleArrayCollections == "json",
+
+		AssocKeyOrder:    c.EmitAssocKeyOrder,
+		SortAssocByValue: c.SortAssocBy == "value",
+		ArrayCounts:      c.EmitArrayCounts,
+
+		EnvOverridePrefix:    c.EnvOverridePrefix,
+		Trace:                nameSet(c.TraceNames),
+		EmptyStrings:         emptyStringModes[c.EmptyStringMode],
+		MaxLineLength:        c.MaxLineLength,
+		Indent:               c.Indent,
+		AssocFallback:        c.VersionFallback,
+		ParallelArrays:       c.MapsAsParallelArrays,
+		SplitArrays:          c.SplitArrayDeclaration,
+		ShellCheckDirectives: c.ShellCheckDirectives,
+		NullDelimited:        nameSet(c.ListAsNullDelimited),
+		Comments:             c.typeComments(),
+		NamePrefix:           c.NamePrefix,
+	}
+}
+
+// typeComments returns the comments for bashgen.Options.Comments that
+// describe the Terraform type of each variable, if annotate_types is
+// enabled, or nil otherwise.
+func (c *bashScriptConfig) typeComments() map[string]string {
+	if !c.AnnotateTypes {
+		return nil
+	}
+	ret := make(map[string]string, len(c.Variables))
+	for name, val := range c.Variables {
+		ret[name] = typeSummary(val)
+	}
+	return ret
+}
+
+var emptyStringModes = map[string]bashgen.EmptyStringMode{
+	"declare_empty": bashgen.EmptyStringAssign,
+	"skip":          bashgen.EmptyStringSkip,
+	"declare_null":  bashgen.EmptyStringUnset,
+}
+
+func (c *bashScriptConfig) ResultObject(result string) tftypes.Value {
+	attrs := c.argumentAttrs()
+	attrs["result"] = tftypes.NewValue(tftypes.String, result)
+	attrs["result_base64"] = tftypes.NewValue(tftypes.String, base64.StdEncoding.EncodeToString([]byte(result)))
+	sum := sha256.Sum256([]byte(result))
+	attrs["result_sha256"] = tftypes.NewValue(tftypes.String, hex.EncodeToString(sum[:]))
+	attrs["result_lines"] = resultLines(result)
+	attrs["env_result"] = tftypes.NewValue(tftypes.String, variablesToDotenv(c.RenderVariables()))
+	attrs["variable_bash_types"] = c.variableBashTypes()
+	if c.Dialect == "bash" {
+		attrs["version_constraint"] = tftypes.NewValue(tftypes.String, ">= "+c.RequiredBashVersion().String())
+	} else {
+		attrs["version_constraint"] = tftypes.NewValue(tftypes.String, nil)
+	}
+	if c.ResultSensitive {
+		// The provider protocol doesn't allow marking a value as sensitive
+		// dynamically, so instead we move everything that could reveal
+		// the variable values to the attribute whose schema marks it as
+		// sensitive.
+		attrs["sensitive_result"] = attrs["result"]
+		for _, name := range []string{"result", "result_base64", "result_sha256", "result_lines", "env_result", "variable_bash_types"} {
+			attrs[name] = tftypes.NewValue(bashScriptType.AttributeTypes[name], nil)
+		}
+	}
+	return tftypes.NewValue(bashScriptType, attrs)
+}
+
+// resultLines returns the value for the computed "result_lines" attribute,
+// which is the given result split into lines. A trailing newline doesn't
+// produce an extra empty line.
+func resultLines(result string) tftypes.Value {
+	lines := strings.Split(strings.TrimSuffix(result, "\n"), "\n")
+	if result == "" {
+		lines = nil
+	}
+	vals := make([]tftypes.Value, len(lines))
+	for i, line := range lines {
+		vals[i] = tftypes.NewValue(tftypes.String, line)
+	}
+	return tftypes.NewValue(listOfString, vals)
+}
+
+// UnknownResultObject returns the object to use as the result when some of
+// the configuration isn't known yet, such as during planning when the
+// variables refer to resources that haven't been created yet. The computed
+// attributes are all unknown, because they depend on the full configuration.
+func (c *bashScriptConfig) UnknownResultObject() tftypes.Value {
+	attrs := c.argumentAttrs()
+	for _, name := range []string{"result", "result_base64", "result_sha256", "result_lines", "env_result", "variable_bash_types", "version_constraint", "sensitive_result"} {
+		attrs[name] = tftypes.NewValue(bashScriptType.AttributeTypes[name], tftypes.UnknownValue)
+	}
+	return tftypes.NewValue(bashScriptType, attrs)
+}
+
+// argumentAttrs returns the attributes of a result object with the
+// arguments, including "variables", echoed back exactly as given, even if
+// null_collection_mode or similar arguments changed the values we actually
+// render. The computed attributes are all null.
+func (c *bashScriptConfig) argumentAttrs() map[string]tftypes.Value {
+	attrs := make(map[string]tftypes.Value, len(bashScriptType.AttributeTypes))
+	for name, aty := range bashScriptType.AttributeTypes {
+		if v, ok := c.config[name]; ok {
+			attrs[name] = v
+		} else {
+			attrs[name] = tftypes.NewValue(aty, nil)
+		}
+	}
+	return attrs
+}
+
+// variableBashTypes returns the value for the computed "variable_bash_types"
+// attribute, which describes the kind of Bash variable declared for each
+// variable.
+//
+// The result is null if the variables are not declared as individual Bash
+// variables, because of either the dialect or the single_array argument.
+func (c *bashScriptConfig) variableBashTypes() tftypes.Value {
+	if c.Dialect != "bash" || c.SingleArray != "" {
+		return tftypes.NewValue(mapOfString, nil)
+	}
+	vars := variablesToGo(c.RenderVariables())
+	for name, val := range c.RawVariables {
+		vars[name] = bashgen.Raw(val)
+	}
+	opts := c.RenderOptions()
+	kinds := make(map[string]tftypes.Value, len(vars))
+	for name, val := range vars {
+		kind, err := bashgen.Kind(name, val, opts)
+		if err != nil {
+			// Should never happen, because we've already validated the
+			// variables.
+			continue
+		}
+		kinds[name] = tftypes.NewValue(tftypes.String, kind)
+	}
+	return tftypes.NewValue(mapOfString, kinds)
+}
+
+func (c *bashScriptConfig) ResultDynamicValue(result string) *tfprotov5.DynamicValue {
+	return resultDynamicValue(c.ResultObject(result))
+}
+
+func (c *bashScriptConfig) UnknownResultDynamicValue() *tfprotov5.DynamicValue {
+	return resultDynamicValue(c.UnknownResultObject())
+}
+
+func resultDynamicValue(obj tftypes.Value) *tfprotov5.DynamicValue {
+	v, err := tfprotov5.NewDynamicValue(bashScriptType, obj)
+	if err != nil {
+		// We control all of the inputs here, so any error represents a bug.
+		panic(fmt.Sprintf("failed to build dynamic value: %s", err))
+	}
+	return &v
+}
+
+// maxExactInteger is the largest magnitude of whole number that
+// integerDiags accepts. Terraform sends numbers to providers as 64-bit
+// floating-point values, so any larger whole number may already have been
+// rounded to a different value by the time we see it: 2^53+1 arrives as
+// 2^53, for example, which is why 2^53 itself is excluded too.
+const maxExactInteger = 1<<53 - 1
+
+// integerDiags checks that the given number value is a whole number that
+// Terraform can pass to us exactly, which also keeps it within the range of
+// the signed 64-bit integers Bash supports, returning an error diagnostic
+// with the given path if not. The what argument describes the value for the
+// diagnostic message, such as `element 0 of "ports"`.
+//
+// Values that are null or not yet known are assumed to be valid.
+func integerDiags(val tftypes.Value, what string, path *tftypes.AttributePath) []*tfprotov5.Diagnostic {
+	if !val.IsKnown() || val.IsNull() {
+		return nil
+	}
+	var f big.Float
+	if err := val.As(&f); err != nil {
+		// Weird!
+		return []*tfprotov5.Diagnostic{
+			{
+				Severity:  tfprotov5.DiagnosticSeverityError,
+				Summary:   "Invalid variable value",
+				Detail:    fmt.Sprintf("Failed to decode %s as a number: %s.", what, err),
+				Attribute: path,
+			},
+		}
+	}
+	if !f.IsInt() {
+		return []*tfprotov5.Diagnostic{
+			{
+				Severity:  tfprotov5.DiagnosticSeverityError,
+				Summary:   "Invalid variable value",
+				Detail:    fmt.Sprintf("Can't use %s as %s: Bash doesn't support floating-point numbers.", f.Text('f', -1), what),
+				Attribute: path,
+			},
+		}
+	}
+	if i, _ := f.Int(nil); i.CmpAbs(big.NewInt(maxExactInteger)) > 0 {
+		return []*tfprotov5.Diagnostic{
+			{
+				Severity:  tfprotov5.DiagnosticSeverityError,
+				Summary:   "Invalid variable value",
+				Detail:    fmt.Sprintf("Can't use %s as %s: whole numbers larger than %d in magnitude cannot be represented exactly, because Terraform passes numbers to providers as 64-bit floating-point values. Pass large numbers as strings and list their names in numeric_names instead.", i, what, maxExactInteger),
+				Attribute: path,
+			},
+		}
+	}
+	return nil
+}
+
+// hasMaps returns true if any of the given variables is a map, which would
+// normally be declared as an associative array.
+func hasMaps(vars map[string]tftypes.Value) bool {
+	for _, val := range vars {
+		if val.Is(mapOfString) || val.Is(mapOfNumber) {
+			return true
+		}
+	}
+	return false
+}
+
+// isCollection returns true if the given value is of one of the list or map
+// types that we accept as variables.
+func isCollection(val tftypes.Value) bool {
+	return val.Is(listOfString) || val.Is(listOfNumber) || val.Is(mapOfString) || val.Is(mapOfNumber)
+}
+
+// nulDiags checks that the given string value doesn't contain any NUL
+// characters, which Bash strings can't represent, returning an error
+// diagnostic with the given path if it does. what describes the value for
+// the diagnostic message, as for integerDiags.
+//
+// Values that are null or not yet known are assumed to be valid.
+func nulDiags(val tftypes.Value, what string, path *tftypes.AttributePath) []*tfprotov5.Diagnostic {
+	if !val.IsKnown() || val.IsNull() {
+		return nil
+	}
+	var s string
+	if err := val.As(&s); err != nil || !strings.ContainsRune(s, 0) {
+		return nil
+	}
+	return []*tfprotov5.Diagnostic{
+		{
+			Severity:  tfprotov5.DiagnosticSeverityError,
+			Summary:   "Invalid variable value",
+			Detail:    fmt.Sprintf("Can't use %s: Bash strings can't contain NUL characters, so the value would be truncated.", what),
+			Attribute: path,
+		},
+	}
+}
+
+// hasErrors returns true if any of the given diagnostics are errors.
+func hasErrors(diags []*tfprotov5.Diagnostic) bool {
+	for _, diag := range diags {
+		if diag.Severity == tfprotov5.DiagnosticSeverityError {
+			return true
+		}
+	}
+	return false
+}
+
+// fullyKnown is like tftypes.Value.IsFullyKnown, except that it also
+// accepts null values of collection and structural types, which
+// IsFullyKnown can't handle at the time of writing.
+func fullyKnown(v tftypes.Value) bool {
+	if !v.IsKnown() {
+		return false
+	}
+	if v.IsNull() {
+		return true
+	}
+	var elems []tftypes.Value
+	if err := v.As(&elems); err == nil {
+		for _, ev := range elems {
+			if !fullyKnown(ev) {
+				return false
+			}
+		}
+		return true
+	}
+	var attrs map[string]tftypes.Value
+	if err := v.As(&attrs); err == nil {
+		for _, av := range attrs {
+			if !fullyKnown(av) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// stringAttr returns the value of the optional string attribute with the
+// given name from a decoded configuration object, or def if the attribute
+// is null or not yet known.
+func stringAttr(obj map[string]tftypes.Value, name string, def string) string {
+	v := obj[name]
+	if v.IsNull() || !v.IsKnown() {
+		return def
+	}
+	var s string
+	if err := v.As(&s); err != nil {
+		panic(fmt.Sprintf("%s isn't a string", name))
+	}
+	return s
+}
+
+// intAttr returns the value of the optional number attribute with the given
+// name from a decoded configuration object, or def if the attribute is null
+// or not yet known. Fractional values are truncated, and values too large
+// to represent as an int are clamped.
+func intAttr(obj map[string]tftypes.Value, name string, def int) int {
+	v := obj[name]
+	if v.IsNull() || !v.IsKnown() {
+		return def
+	}
+	var f big.Float
+	if err := v.As(&f); err != nil {
+		panic(fmt.Sprintf("%s isn't a number", name))
+	}
+	i, _ := f.Int64()
+	if i > math.MaxInt32 {
+		return math.MaxInt32
+	}
+	if i < math.MinInt32 {
+		return math.MinInt32
+	}
+	return int(i)
+}
+
+// boolAttr returns the value of the optional bool attribute with the given
+// name from a decoded configuration object, or def if the attribute is null
+// or not yet known.
+func boolAttr(obj map[string]tftypes.Value, name string, def bool) bool {
+	v := obj[name]
+	if v.IsNull() || !v.IsKnown() {
+		return def
+	}
+	var b bool
+	if err := v.As(&b); err != nil {
+		panic(fmt.Sprintf("%s isn't a bool", name))
+	}
+	return b
+}
+
+// stringSetAttr returns the elements of the optional set of strings
+// attribute with the given name from a decoded configuration object, in
+// lexical order. Returns nil if the attribute is null or not yet known.
+func stringSetAttr(obj map[string]tftypes.Value, name string) []string {
+	v := obj[name]
+	if v.IsNull() || !v.IsKnown() {
+		return nil
+	}
+	var elems []tftypes.Value
+	if err := v.As(&elems); err != nil {
+		panic(fmt.Sprintf("%s isn't a set", name))
+	}
+	ret := make([]string, 0, len(elems))
+	for _, ev := range elems {
+		if ev.IsNull() || !ev.IsKnown() {
+			continue
+		}
+		var s string
+		if err := ev.As(&s); err != nil {
+			panic(fmt.Sprintf("%s element isn't a string", name))
+		}
+		ret = append(ret, s)
+	}
+	sort.Strings(ret)
+	return ret
+}
+
+// namedVariableDiags checks that each of the given names, which came from the
+// set-of-strings argument attrName, refers to one of the variables in
+// c.Variables. While VariablesUnknown is set we can't tell whether a name is
+// declared, so names that aren't in c.Variables are not reported.
+//
+// If check is non-nil then it's also called for each of the variables that
+// exist, and any non-empty string it returns is reported as the detail of an
+// error diagnostic.
+func (c *bashScriptConfig) namedVariableDiags(attrName string, names []string, check func(name string, val tftypes.Value) string) []*tfprotov5.Diagnostic {
+	var diags []*tfprotov5.Diagnostic
+	for _, name := range names {
+		path := &tftypes.AttributePath{
+			Steps: []tftypes.AttributePathStep{
+				tftypes.AttributeName(attrName),
+				tftypes.ElementKeyValue(tftypes.NewValue(tftypes.String, name)),
+			},
+		}
+		val, ok := c.Variables[name]
+		if !ok {
+			if c.VariablesUnknown {
+				continue
+			}
+			diags = append(diags, &tfprotov5.Diagnostic{
+				Severity:  tfprotov5.DiagnosticSeverityError,
+				Summary:   "Reference to undeclared variable",
+				Detail:    fmt.Sprintf("The %q argument refers to %q, but there is no such variable declared in \"variables\".", attrName, name),
+				Attribute: path,
+			})
+			continue
+		}
+		if check == nil {
+			continue
+		}
+		if msg := check(name, val); msg != "" {
+			diags = append(diags, &tfprotov5.Diagnostic{
+				Severity:  tfprotov5.DiagnosticSeverityError,
+				Summary:   "Unsuitable variable",
+				Detail:    msg,
+				Attribute: path,
+			})
+		}
+	}
+	return diags
+}
+
+// forEachVariableName calls fn for the name of each variable in c.Variables
+// and then each variable in c.RawVariables, in lexical order within each,
+// along with the path of the argument that declares it.
+func (c *bashScriptConfig) forEachVariableName(fn func(name string, path *tftypes.AttributePath)) {
+	names := make([]string, 0, len(c.Variables))
+	for name := range c.Variables {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fn(name, &tftypes.AttributePath{
+			Steps: []tftypes.AttributePathStep{
+				tftypes.AttributeName("variables"),
+				tftypes.AttributeName(name),
+			},
+		})
+	}
+
+	names = names[:0]
+	for name := range c.RawVariables {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fn(name, &tftypes.AttributePath{
+			Steps: []tftypes.AttributePathStep{
+				tftypes.AttributeName("raw_variables"),
+				tftypes.ElementKeyString(name),
+			},
+		})
+	}
+}
+
+// stringMapAttr returns the elements of the optional map of strings
+// attribute with the given name from a decoded configuration object, or nil
+// if the attribute is null or not yet known. Elements that are not yet known
+// are omitted.
+func stringMapAttr(obj map[string]tftypes.Value, name string) map[string]string {
+	v := obj[name]
+	if v.IsNull() || !v.IsKnown() {
+		return nil
+	}
+	var elems map[string]tftypes.Value
+	if err := v.As(&elems); err != nil {
+		panic(fmt.Sprintf("%s isn't a map", name))
+	}
+	ret := make(map[string]string, len(elems))
+	for k, ev := range elems {
+		if ev.IsNull() || !ev.IsKnown() {
+			continue
+		}
+		var s string
+		if err := ev.As(&s); err != nil {
+			panic(fmt.Sprintf("%s element isn't a string", name))
+		}
+		ret[k] = s
+	}
+	return ret
+}
+
+// stringListAttr returns the elements of the optional list of strings
+// attribute with the given name from a decoded configuration object, or nil
+// if the attribute is null or not yet known.
+func stringListAttr(obj map[string]tftypes.Value, name string) []string {
+	v := obj[name]
+	if v.IsNull() || !v.IsKnown() {
+		return nil
+	}
+	var elems []tftypes.Value
+	if err := v.As(&elems); err != nil {
+		panic(fmt.Sprintf("%s isn't a list", name))
+	}
+	ret := make([]string, 0, len(elems))
+	for _, ev := range elems {
+		if ev.IsNull() || !ev.IsKnown() {
+			continue
+		}
+		var s string
+		if err := ev.As(&s); err != nil {
+			panic(fmt.Sprintf("%s element isn't a string", name))
+		}
+		ret = append(ret, s)
+	}
+	return ret
+}
+
+// nameSet converts a slice of names into a set of names, or returns nil if
+// the given slice is empty.
+func nameSet(names []string) map[string]bool {
+	if len(names) == 0 {
+		return nil
 	}
-	return tftypes.Object{
-		AttributeTypes: atys,
+	ret := make(map[string]bool, len(names))
+	for _, name := range names {
+		ret[name] = true
 	}
+	return ret
 }
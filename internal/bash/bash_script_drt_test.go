@@ -0,0 +1,2704 @@
+package bash
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-go/tfprotov5"
+	"github.com/hashicorp/terraform-plugin-go/tfprotov5/tftypes"
+)
+
+func TestBashScriptResult(t *testing.T) {
+	tests := map[string]struct {
+		args map[string]tftypes.Value
+		want string
+	}{
+		"no variables": {
+			args: map[string]tftypes.Value{
+				"source": stringVal("echo hello\n"),
+			},
+			want: "echo hello\n",
+		},
+		"one of each type": {
+			args: map[string]tftypes.Value{
+				"source": stringVal("echo \"$greeting\"\n"),
+				"variables": objectVal(map[string]tftypes.Value{
+					"greeting": stringVal("it's me"),
+					"port":     numberVal("8080"),
+					"names":    stringListVal("a", "b c"),
+					"tags":     stringMapVal("k", "v"),
+				}),
+			},
+			want: `declare -r greeting='it'\''s me'
+declare -ra names=('a' 'b c')
+declare -ri port=8080
+declare -rA tags=(['k']='v')
+echo "$greeting"
+`,
+		},
+		"global declarations": {
+			args: map[string]tftypes.Value{
+				"source":        stringVal(""),
+				"declare_scope": stringVal("global_explicit"),
+				"variables": objectVal(map[string]tftypes.Value{
+					"s": stringVal("x"),
+					"n": numberVal("1"),
+					"l": stringListVal("a"),
+					"m": stringMapVal("k", "v"),
+				}),
+			},
+			want: `declare -gra l=('a')
+declare -grA m=(['k']='v')
+declare -gri n=1
+declare -gr s='x'
+`,
+		},
+		"number lists": {
+			args: map[string]tftypes.Value{
+				"source": stringVal(""),
+				"variables": objectVal(map[string]tftypes.Value{
+					"ports":  numberListVal("8080", "8443", "9000"),
+					"deltas": numberListVal("-1", "0", "1.0"),
+					"none":   numberListVal(),
+				}),
+			},
+			want: `declare -ria deltas=(-1 0 1)
+declare -ria none=()
+declare -ria ports=(8080 8443 9000)
+`,
+		},
+		"number maps": {
+			args: map[string]tftypes.Value{
+				"source": stringVal(""),
+				"variables": objectVal(map[string]tftypes.Value{
+					"limits": numberMapVal("memory", "512", "cpu", "2", "disk", "-1"),
+					"none":   numberMapVal(),
+				}),
+			},
+			want: `declare -rAi limits=(['cpu']=2 ['disk']=-1 ['memory']=512)
+declare -rAi none=()
+`,
+		},
+		"sorted lists": {
+			args: map[string]tftypes.Value{
+				"source":     stringVal(""),
+				"sort_lists": boolVal(true),
+				"variables": objectVal(map[string]tftypes.Value{
+					"names": stringListVal("b", "a", "c"),
+					"ports": numberListVal("443", "80", "8080"),
+				}),
+			},
+			want: `declare -ra names=('a' 'b' 'c')
+declare -ria ports=(80 443 8080)
+`,
+		},
+		"unsorted lists": {
+			args: map[string]tftypes.Value{
+				"source": stringVal(""),
+				"variables": objectVal(map[string]tftypes.Value{
+					"names": stringListVal("b", "a", "c"),
+					"ports": numberListVal("443", "80", "8080"),
+				}),
+			},
+			want: `declare -ra names=('b' 'a' 'c')
+declare -ria ports=(443 80 8080)
+`,
+		},
+		"sorted set": {
+			// Sets are already de-duplicated, so sort_lists only changes
+			// their order.
+			args: map[string]tftypes.Value{
+				"source":     stringVal(""),
+				"sort_lists": boolVal(true),
+				"variables": objectVal(map[string]tftypes.Value{
+					"names": stringSetVal("b", "a"),
+				}),
+			},
+			want: `declare -ra names=('a' 'b')
+`,
+		},
+		"large and whole numbers in sh": {
+			args: map[string]tftypes.Value{
+				"source":  stringVal(""),
+				"dialect": stringVal("sh"),
+				"variables": objectVal(map[string]tftypes.Value{
+					"big":   numberVal("9007199254740991"),
+					"whole": numberVal("3.0"),
+				}),
+			},
+			want: `big=9007199254740991
+whole=3
+`,
+		},
+		"large and whole numbers in make": {
+			args: map[string]tftypes.Value{
+				"source":  stringVal(""),
+				"dialect": stringVal("make"),
+				"variables": objectVal(map[string]tftypes.Value{
+					"big":   numberVal("9007199254740991"),
+					"whole": numberVal("3.0"),
+				}),
+			},
+			want: `big := 9007199254740991
+whole := 3
+`,
+		},
+		"large and whole numbers in dotenv": {
+			args: map[string]tftypes.Value{
+				"source":  stringVal(""),
+				"dialect": stringVal("dotenv"),
+				"variables": objectVal(map[string]tftypes.Value{
+					"big":   numberVal("9007199254740991"),
+					"whole": numberVal("3.0"),
+				}),
+			},
+			want: `big=9007199254740991
+whole=3
+`,
+		},
+		"unexport_names": {
+			args: map[string]tftypes.Value{
+				"source":         stringVal(""),
+				"unexport_names": stringSetVal("TOKEN"),
+				"variables": objectVal(map[string]tftypes.Value{
+					"a": stringVal("x"),
+				}),
+			},
+			want: `export -n TOKEN
+declare -r a='x'
+`,
+		},
+		"single_array": {
+			args: map[string]tftypes.Value{
+				"source":                   stringVal(""),
+				"single_array":             stringVal("VARS"),
+				"single_array_collections": stringVal("json"),
+				"variables": objectVal(map[string]tftypes.Value{
+					"region": stringVal("us-east-1"),
+					"port":   numberVal("8080"),
+					"zones":  stringListVal("a", "b"),
+				}),
+			},
+			want: `declare -rA VARS=([port]='8080' [region]='us-east-1' [zones]='["a","b"]')
+`,
+		},
+		"convert_windows_paths": {
+			args: map[string]tftypes.Value{
+				"source":                stringVal(""),
+				"convert_windows_paths": stringSetVal("home"),
+				"variables": objectVal(map[string]tftypes.Value{
+					"home":  stringVal(`C:\Users\example`),
+					"other": stringVal(`C:\Users\example`),
+				}),
+			},
+			want: `declare -r home='/c/Users/example'
+declare -r other='C:\Users\example'
+`,
+		},
+		"sort_assoc_by value": {
+			args: map[string]tftypes.Value{
+				"source":               stringVal(""),
+				"emit_assoc_key_order": boolVal(true),
+				"sort_assoc_by":        stringVal("value"),
+				"variables": objectVal(map[string]tftypes.Value{
+					"rank": stringMapVal("b", "1", "a", "2"),
+				}),
+			},
+			want: `declare -rA rank=(['a']='2' ['b']='1')
+declare -ra rank_keys=('b' 'a')
+`,
+		},
+		"trace_names": {
+			args: map[string]tftypes.Value{
+				"source":      stringVal(""),
+				"trace_names": stringSetVal("a"),
+				"variables": objectVal(map[string]tftypes.Value{
+					"a": stringVal("x"),
+					"b": stringVal("y"),
+				}),
+			},
+			want: `declare -rt a='x'
+declare -r b='y'
+`,
+		},
+		"map keys with array syntax characters": {
+			args: map[string]tftypes.Value{
+				"source": stringVal(""),
+				"variables": objectVal(map[string]tftypes.Value{
+					"m": stringMapVal("a]b", "1", "a[0]", "2", "a b", "3", "a=b", "4"),
+				}),
+			},
+			want: `declare -rA m=(['a b']='3' ['a=b']='4' ['a[0]']='2' ['a]b']='1')
+`,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			got := testResult(t, test.args)
+			if got != test.want {
+				t.Errorf("wrong result\ngot:\n%s\nwant:\n%s", got, test.want)
+			}
+		})
+	}
+}
+
+func TestBashScriptSets(t *testing.T) {
+	// Sets have no inherent order, so the result must be the same however
+	// the elements happen to be ordered when they arrive.
+	orders := [][]string{
+		{"b", "a", "c"},
+		{"c", "b", "a"},
+		{"a", "b", "c"},
+	}
+	want := `declare -ra names=('a' 'b' 'c')
+declare -ra none=()
+`
+
+	for _, order := range orders {
+		t.Run(strings.Join(order, ""), func(t *testing.T) {
+			got := testResult(t, map[string]tftypes.Value{
+				"source": stringVal(""),
+				"variables": objectVal(map[string]tftypes.Value{
+					"names": stringSetVal(order...),
+					"none":  stringSetVal(),
+				}),
+			})
+			if got != want {
+				t.Errorf("wrong result\ngot:\n%s\nwant:\n%s", got, want)
+			}
+		})
+	}
+}
+
+func TestBashScriptObjects(t *testing.T) {
+	tests := map[string]struct {
+		variables tftypes.Value
+		want      string
+		path      string // path of the expected error, if any
+	}{
+		"string attributes": {
+			variables: objectVal(map[string]tftypes.Value{
+				"db": objectVal(map[string]tftypes.Value{
+					"port": stringVal("5432"),
+					"host": stringVal("db"),
+				}),
+			}),
+			want: `declare -rA db=(['host']='db' ['port']='5432')
+`,
+		},
+		"same as a map": {
+			variables: objectVal(map[string]tftypes.Value{
+				"db": stringMapVal("port", "5432", "host", "db"),
+			}),
+			want: `declare -rA db=(['host']='db' ['port']='5432')
+`,
+		},
+		"no attributes": {
+			variables: objectVal(map[string]tftypes.Value{
+				"db": objectVal(map[string]tftypes.Value{}),
+			}),
+			want: `declare -rA db=()
+`,
+		},
+		"only a value attribute": {
+			// This is the structured form for a single variable, which
+			// takes precedence over declaring an associative array.
+			variables: objectVal(map[string]tftypes.Value{
+				"o": objectVal(map[string]tftypes.Value{
+					"value": stringVal("v"),
+				}),
+			}),
+			want: `declare -r o='v'
+`,
+		},
+		"map with only a value key": {
+			// A map, such as from tomap(), is never the structured form.
+			variables: objectVal(map[string]tftypes.Value{
+				"o": stringMapVal("value", "v"),
+			}),
+			want: `declare -rA o=(['value']='v')
+`,
+		},
+		"non-string attribute": {
+			variables: objectVal(map[string]tftypes.Value{
+				"db": objectVal(map[string]tftypes.Value{
+					"host": stringVal("db"),
+					"port": numberVal("5432"),
+				}),
+			}),
+			path: "variables.db.port",
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			args := map[string]tftypes.Value{
+				"source":    stringVal(""),
+				"variables": test.variables,
+			}
+			if test.path != "" {
+				diags := testValidate(t, args)
+				wantDiag(t, diags, tfprotov5.DiagnosticSeverityError, "Invalid variable value", test.path, `"port" is of type number`)
+				return
+			}
+			if got := testResult(t, args); got != test.want {
+				t.Errorf("wrong result\ngot:\n%s\nwant:\n%s", got, test.want)
+			}
+		})
+	}
+}
+
+func TestBashScriptRawVariables(t *testing.T) {
+	tests := map[string]struct {
+		args    map[string]tftypes.Value
+		want    string
+		summary string // if set, an error with this summary is expected instead
+		path    string
+	}{
+		"command substitution": {
+			args: map[string]tftypes.Value{
+				"raw_variables": stringMapVal("host", "$(hostname)"),
+				"variables": objectVal(map[string]tftypes.Value{
+					"greeting": stringVal("hi"),
+				}),
+			},
+			want: `declare -r greeting='hi'
+declare -r host=$(hostname)
+`,
+		},
+		"collision": {
+			args: map[string]tftypes.Value{
+				"raw_variables": stringMapVal("host", "$(hostname)"),
+				"variables": objectVal(map[string]tftypes.Value{
+					"host": stringVal("example.com"),
+				}),
+			},
+			summary: "Duplicate variable name",
+			path:    `raw_variables["host"]`,
+		},
+		"invalid name": {
+			args: map[string]tftypes.Value{
+				"raw_variables": stringMapVal("not-valid", "$(hostname)"),
+			},
+			summary: "Invalid variable name",
+			path:    `raw_variables["not-valid"]`,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			args := mergeArgs(map[string]tftypes.Value{
+				"source": stringVal(""),
+			}, test.args)
+			if test.summary != "" {
+				diags := testValidate(t, args)
+				wantDiag(t, diags, tfprotov5.DiagnosticSeverityError, test.summary, test.path, "")
+				return
+			}
+			if got := testResult(t, args); got != test.want {
+				t.Errorf("wrong result\ngot:\n%s\nwant:\n%s", got, test.want)
+			}
+		})
+	}
+}
+
+func TestBashScriptRawVariablesRun(t *testing.T) {
+	script := testResult(t, map[string]tftypes.Value{
+		"source":        stringVal("printf '%s\\n' \"$now\"\n"),
+		"raw_variables": stringMapVal("now", "$(echo evaluated)"),
+	})
+	if got, want := testRunBash(t, script), "evaluated\n"; got != want {
+		t.Errorf("wrong output\ngot:  %q\nwant: %q", got, want)
+	}
+}
+
+func TestBashScriptResultBase64(t *testing.T) {
+	sources := map[string]string{
+		"empty":            "",
+		"plain":            "echo hello\n",
+		"non-ASCII":        "echo 'héllo 世界'\n",
+		"no final newline": "exit 1",
+	}
+
+	for name, source := range sources {
+		t.Run(name, func(t *testing.T) {
+			attrs, diags := testRead(t, map[string]tftypes.Value{
+				"source": stringVal(source),
+				"variables": objectVal(map[string]tftypes.Value{
+					"s": stringVal("it's"),
+				}),
+			})
+			wantNoErrors(t, diags)
+			result := testStringAttr(t, attrs, "result")
+			encoded := testStringAttr(t, attrs, "result_base64")
+			decoded, err := base64.StdEncoding.DecodeString(encoded)
+			if err != nil {
+				t.Fatalf("invalid base64 %q: %s", encoded, err)
+			}
+			if got := string(decoded); got != result {
+				t.Errorf("wrong decoded result\ngot:\n%s\nwant:\n%s", got, result)
+			}
+		})
+	}
+}
+
+func TestBashScriptResultSHA256(t *testing.T) {
+	tests := map[string]map[string]tftypes.Value{
+		"source only": {
+			"source": stringVal("echo hello\n"),
+		},
+		"with declarations": {
+			"source": stringVal("echo \"$s\"\n"),
+			"variables": objectVal(map[string]tftypes.Value{
+				"s": stringVal("it's"),
+				"l": stringListVal("a", "b"),
+			}),
+		},
+		"with a trailing newline mode": {
+			"source":           stringVal("echo hello\n\n"),
+			"trailing_newline": stringVal("strip"),
+			"variables": objectVal(map[string]tftypes.Value{
+				"s": stringVal("x"),
+			}),
+		},
+	}
+
+	for name, args := range tests {
+		t.Run(name, func(t *testing.T) {
+			attrs, diags := testRead(t, args)
+			wantNoErrors(t, diags)
+			result := testStringAttr(t, attrs, "result")
+			sum := sha256.Sum256([]byte(result))
+			want := hex.EncodeToString(sum[:])
+			if got := testStringAttr(t, attrs, "result_sha256"); got != want {
+				t.Errorf("wrong digest\ngot:  %s\nwant: %s", got, want)
+			}
+		})
+	}
+}
+
+func TestBashScriptResultLines(t *testing.T) {
+	tests := map[string]struct {
+		args map[string]tftypes.Value
+		want []string
+	}{
+		"multi-line script": {
+			args: map[string]tftypes.Value{
+				"source": stringVal("echo \"$a\"\n\necho \"${l[@]}\"\n"),
+				"variables": objectVal(map[string]tftypes.Value{
+					"a": stringVal("x"),
+					"l": stringListVal("b", "c"),
+				}),
+			},
+			want: []string{
+				"declare -r a='x'",
+				"declare -ra l=('b' 'c')",
+				`echo "$a"`,
+				"",
+				`echo "${l[@]}"`,
+			},
+		},
+		"no trailing newline": {
+			args: map[string]tftypes.Value{
+				"source": stringVal("echo a\necho b"),
+			},
+			want: []string{"echo a", "echo b"},
+		},
+		"several trailing newlines": {
+			args: map[string]tftypes.Value{
+				"source": stringVal("echo a\n\n"),
+			},
+			want: []string{"echo a", ""},
+		},
+		"empty": {
+			args: map[string]tftypes.Value{
+				"source": stringVal(""),
+			},
+			want: []string{},
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			attrs, diags := testRead(t, test.args)
+			wantNoErrors(t, diags)
+			var elems []tftypes.Value
+			if err := attrs["result_lines"].As(&elems); err != nil {
+				t.Fatalf("wrong result_lines %#v: %s", attrs["result_lines"], err)
+			}
+			got := make([]string, len(elems))
+			for i, ev := range elems {
+				if err := ev.As(&got[i]); err != nil {
+					t.Fatalf("wrong element %d: %s", i, err)
+				}
+			}
+			if strings.Join(got, "\n") != strings.Join(test.want, "\n") || len(got) != len(test.want) {
+				t.Errorf("wrong result_lines\ngot:  %q\nwant: %q", got, test.want)
+			}
+		})
+	}
+}
+
+func TestBashScriptResultSensitive(t *testing.T) {
+	args := map[string]tftypes.Value{
+		"source": stringVal("echo \"$password\"\n"),
+		"variables": objectVal(map[string]tftypes.Value{
+			"password": stringVal("hunter2"),
+		}),
+	}
+	want := `declare -r password='hunter2'
+echo "$password"
+`
+	// All of these could reveal something about the sensitive variables.
+	revealing := []string{"result", "result_base64", "result_sha256", "result_lines", "env_result", "variable_bash_types"}
+
+	t.Run("not sensitive", func(t *testing.T) {
+		attrs, diags := testRead(t, args)
+		wantNoErrors(t, diags)
+		for _, name := range revealing {
+			if attrs[name].IsNull() {
+				t.Errorf("%s is null; want a value", name)
+			}
+		}
+		if !attrs["sensitive_result"].IsNull() {
+			t.Errorf("sensitive_result is %#v; want null", attrs["sensitive_result"])
+		}
+	})
+	t.Run("sensitive", func(t *testing.T) {
+		sensitiveArgs := map[string]tftypes.Value{
+			"result_sensitive": boolVal(true),
+		}
+		for k, v := range args {
+			sensitiveArgs[k] = v
+		}
+		attrs, diags := testRead(t, sensitiveArgs)
+		wantNoErrors(t, diags)
+		for _, name := range revealing {
+			if !attrs[name].IsNull() {
+				t.Errorf("%s is %#v; want null", name, attrs[name])
+			}
+		}
+		if got := testStringAttr(t, attrs, "sensitive_result"); got != want {
+			t.Errorf("wrong sensitive_result\ngot:\n%s\nwant:\n%s", got, want)
+		}
+	})
+}
+
+func TestBashScriptInvalidVariables(t *testing.T) {
+	tests := map[string]struct {
+		variables tftypes.Value
+		summary   string
+		path      string
+	}{
+		"not an object": {
+			variables: stringVal("nope"),
+			summary:   "Invalid variables",
+			path:      "variables",
+		},
+		"invalid name": {
+			variables: objectVal(map[string]tftypes.Value{
+				"not-valid": stringVal("x"),
+			}),
+			summary: "Invalid variable name",
+			path:    "variables.not-valid",
+		},
+		"unsupported type": {
+			variables: objectVal(map[string]tftypes.Value{
+				"flags": tftypes.NewValue(tftypes.List{ElementType: tftypes.Bool}, []tftypes.Value{boolVal(true)}),
+			}),
+			summary: "Invalid variable value",
+			path:    "variables.flags",
+		},
+		"fractional list element": {
+			variables: objectVal(map[string]tftypes.Value{
+				"ports": numberListVal("80", "80.5", "443"),
+			}),
+			summary: "Invalid variable value",
+			path:    "variables.ports[1]",
+		},
+		"list element out of range": {
+			variables: objectVal(map[string]tftypes.Value{
+				"ports": numberListVal("9223372036854775808"),
+			}),
+			summary: "Invalid variable value",
+			path:    "variables.ports[0]",
+		},
+		"number out of range": {
+			variables: objectVal(map[string]tftypes.Value{
+				"n": numberVal("99999999999999999999"),
+			}),
+			summary: "Invalid variable value",
+			path:    "variables.n",
+		},
+		"fractional map element": {
+			variables: objectVal(map[string]tftypes.Value{
+				"limits": numberMapVal("a", "1", "b", "2.5"),
+			}),
+			summary: "Invalid variable value",
+			path:    `variables.limits["b"]`,
+		},
+		"map element out of range": {
+			variables: objectVal(map[string]tftypes.Value{
+				"limits": numberMapVal("a", "-9223372036854775809"),
+			}),
+			summary: "Invalid variable value",
+			path:    `variables.limits["a"]`,
+		},
+		"NUL in string": {
+			variables: objectVal(map[string]tftypes.Value{
+				"s": stringVal("a\x00b"),
+			}),
+			summary: "Invalid variable value",
+			path:    "variables.s",
+		},
+		"NUL in list element": {
+			variables: objectVal(map[string]tftypes.Value{
+				"l": stringListVal("a", "b\x00"),
+			}),
+			summary: "Invalid variable value",
+			path:    "variables.l[1]",
+		},
+		"NUL in map value": {
+			variables: objectVal(map[string]tftypes.Value{
+				"m": stringMapVal("k", "\x00"),
+			}),
+			summary: "Invalid variable value",
+			path:    `variables.m["k"]`,
+		},
+		"NUL in map key": {
+			variables: objectVal(map[string]tftypes.Value{
+				"m": stringMapVal("k\x00", "v"),
+			}),
+			summary: "Invalid variable value",
+			path:    "variables.m[\"k\\x00\"]",
+		},
+		"empty map key": {
+			variables: objectVal(map[string]tftypes.Value{
+				"m": stringMapVal("", "v"),
+			}),
+			summary: "Invalid variable value",
+			path:    "variables.m",
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			args := map[string]tftypes.Value{
+				"source":    stringVal(""),
+				"variables": test.variables,
+			}
+			diags := testValidate(t, args)
+			wantDiag(t, diags, tfprotov5.DiagnosticSeverityError, test.summary, test.path, "")
+
+			// Reading must fail in the same way, rather than producing a
+			// result with corrupt declarations.
+			attrs, diags := testRead(t, args)
+			wantDiag(t, diags, tfprotov5.DiagnosticSeverityError, test.summary, test.path, "")
+			if attrs != nil {
+				t.Errorf("unexpected result %#v", attrs["result"])
+			}
+		})
+	}
+}
+
+func TestBashScriptIntegerRange(t *testing.T) {
+	// These go through the data source, rather than calling integerDiags
+	// directly, because terraform-plugin-go decodes msgpack numbers by way
+	// of float64 and so large values may have been rounded before
+	// integerDiags sees them.
+	tests := map[string]struct {
+		value   string
+		want    string // expected declaration, if valid
+		wantErr string // substring of the expected error detail, if any
+	}{
+		"maximum": {
+			value: "9007199254740991",
+			want:  "declare -ri n=9007199254740991\n",
+		},
+		"minimum": {
+			value: "-9007199254740991",
+			want:  "declare -ri n=-9007199254740991\n",
+		},
+		"zero": {
+			value: "0",
+			want:  "declare -ri n=0\n",
+		},
+		"above maximum": {
+			value:   "9007199254740992",
+			wantErr: "cannot be represented exactly",
+		},
+		"rounded to 2^53": {
+			// This arrives as 9007199254740992, so must not be declared as
+			// that value.
+			value:   "9007199254740993",
+			wantErr: "cannot be represented exactly",
+		},
+		"below minimum": {
+			value:   "-9007199254740992",
+			wantErr: "cannot be represented exactly",
+		},
+		"int64 maximum": {
+			value:   "9223372036854775807",
+			wantErr: "cannot be represented exactly",
+		},
+		"far above maximum": {
+			value:   "99999999999999999999",
+			wantErr: "cannot be represented exactly",
+		},
+		"fractional": {
+			value:   "1.5",
+			wantErr: "floating-point",
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			attrs, diags := testRead(t, map[string]tftypes.Value{
+				"source": stringVal(""),
+				"variables": objectVal(map[string]tftypes.Value{
+					"n": numberVal(test.value),
+				}),
+			})
+			if test.wantErr != "" {
+				wantDiag(t, diags, tfprotov5.DiagnosticSeverityError, "Invalid variable value", "variables.n", test.wantErr)
+				return
+			}
+			wantNoErrors(t, diags)
+			var got string
+			attrs["result"].As(&got)
+			if got != test.want {
+				t.Errorf("wrong result\ngot:  %q\nwant: %q", got, test.want)
+			}
+		})
+	}
+}
+
+func TestBashScriptInvalidArguments(t *testing.T) {
+	tests := map[string]struct {
+		args    map[string]tftypes.Value
+		summary string
+		path    string
+	}{
+		"declare_scope": {
+			args: map[string]tftypes.Value{
+				"declare_scope": stringVal("local"),
+			},
+			summary: "Invalid declaration scope",
+			path:    "declare_scope",
+		},
+		"comment_prefix": {
+			args: map[string]tftypes.Value{
+				"markers":        boolVal(true),
+				"comment_prefix": stringVal("two\nlines"),
+			},
+			summary: "Invalid comment prefix",
+			path:    "comment_prefix",
+		},
+		"unexport_names": {
+			args: map[string]tftypes.Value{
+				"unexport_names": stringSetVal("not-valid"),
+			},
+			summary: "Invalid variable name",
+			path:    `unexport_names["not-valid"]`,
+		},
+		"single_array": {
+			args: map[string]tftypes.Value{
+				"single_array": stringVal("not-valid"),
+			},
+			summary: "Invalid array name",
+			path:    "single_array",
+		},
+		"single_array_collections": {
+			args: map[string]tftypes.Value{
+				"single_array":             stringVal("VARS"),
+				"single_array_collections": stringVal("nested"),
+			},
+			summary: "Invalid collection handling mode",
+			path:    "single_array_collections",
+		},
+		"single_array_collections without single_array": {
+			args: map[string]tftypes.Value{
+				"single_array_collections": stringVal("json"),
+			},
+			summary: "Conflicting options",
+			path:    "single_array_collections",
+		},
+		"single_array with a list": {
+			args: map[string]tftypes.Value{
+				"single_array": stringVal("VARS"),
+				"variables": objectVal(map[string]tftypes.Value{
+					"zones": stringListVal("a", "b"),
+				}),
+			},
+			summary: "Invalid variable value",
+			path:    "variables.zones",
+		},
+		"convert_windows_paths with an undeclared variable": {
+			args: map[string]tftypes.Value{
+				"convert_windows_paths": stringSetVal("home"),
+			},
+			summary: "Reference to undeclared variable",
+			path:    `convert_windows_paths["home"]`,
+		},
+		"convert_windows_paths with a number": {
+			args: map[string]tftypes.Value{
+				"convert_windows_paths": stringSetVal("home"),
+				"variables": objectVal(map[string]tftypes.Value{
+					"home": numberVal("1"),
+				}),
+			},
+			summary: "Unsuitable variable",
+			path:    `convert_windows_paths["home"]`,
+		},
+		"exit_trap": {
+			args: map[string]tftypes.Value{
+				"exit_trap": stringListVal("echo ok", "echo 'unterminated"),
+			},
+			summary: "Invalid exit trap command",
+			path:    "exit_trap[1]",
+		},
+		"sort_assoc_by": {
+			args: map[string]tftypes.Value{
+				"emit_assoc_key_order": boolVal(true),
+				"sort_assoc_by":        stringVal("length"),
+			},
+			summary: "Invalid associative array sort order",
+			path:    "sort_assoc_by",
+		},
+		"env_override_prefix": {
+			args: map[string]tftypes.Value{
+				"env_override_prefix": stringVal("TF-"),
+			},
+			summary: "Invalid environment variable prefix",
+			path:    "env_override_prefix",
+		},
+		"trace_names": {
+			args: map[string]tftypes.Value{
+				"trace_names": stringSetVal("a"),
+			},
+			summary: "Reference to undeclared variable",
+			path:    `trace_names["a"]`,
+		},
+		"bool_format": {
+			args: map[string]tftypes.Value{
+				"bool_format": stringVal("yes_no"),
+			},
+			summary: "Invalid boolean format",
+			path:    "bool_format",
+		},
+		"empty_string_mode": {
+			args: map[string]tftypes.Value{
+				"empty_string_mode": stringVal("assign"),
+			},
+			summary: "Invalid empty string mode",
+			path:    "empty_string_mode",
+		},
+		"source_ifs with a variable named IFS": {
+			args: map[string]tftypes.Value{
+				"source_ifs": stringVal(","),
+				"variables": objectVal(map[string]tftypes.Value{
+					"IFS": stringVal(" "),
+				}),
+			},
+			summary: "Invalid variable name",
+			path:    "variables.IFS",
+		},
+		"source_ifs with a NUL character": {
+			args: map[string]tftypes.Value{
+				"source_ifs": stringVal("\x00"),
+			},
+			summary: "Invalid IFS value",
+			path:    "source_ifs",
+		},
+		"max_line_length": {
+			args: map[string]tftypes.Value{
+				"max_line_length": numberVal("-1"),
+			},
+			summary: "Invalid maximum line length",
+			path:    "max_line_length",
+		},
+		"required_commands": {
+			args: map[string]tftypes.Value{
+				"required_commands": stringListVal("two\nlines"),
+			},
+			summary: "Invalid required command",
+			path:    "required_commands[0]",
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			args := mergeArgs(map[string]tftypes.Value{
+				"source": stringVal("echo hello\n"),
+			}, test.args)
+			testValidateWant(t, args, tfprotov5.DiagnosticSeverityError, test.summary, test.path, "")
+		})
+	}
+}
+
+func TestBashScriptSpecialNames(t *testing.T) {
+	tests := map[string]struct {
+		args map[string]tftypes.Value
+		path string // path of the expected warning, if any
+	}{
+		"PATH": {
+			args: map[string]tftypes.Value{
+				"variables": objectVal(map[string]tftypes.Value{
+					"PATH": stringVal("/bin"),
+				}),
+			},
+			path: "variables.PATH",
+		},
+		"my_path": {
+			args: map[string]tftypes.Value{
+				"variables": objectVal(map[string]tftypes.Value{
+					"my_path": stringVal("/bin"),
+				}),
+			},
+		},
+		"different case": {
+			args: map[string]tftypes.Value{
+				"variables": objectVal(map[string]tftypes.Value{
+					"path": stringVal("/bin"),
+				}),
+			},
+		},
+		"raw variable": {
+			args: map[string]tftypes.Value{
+				"raw_variables": stringMapVal("IFS", "$'\\n'"),
+			},
+			path: `raw_variables["IFS"]`,
+		},
+		"with name_prefix": {
+			args: map[string]tftypes.Value{
+				"name_prefix": stringVal("tf_"),
+				"variables": objectVal(map[string]tftypes.Value{
+					"PATH": stringVal("/bin"),
+				}),
+			},
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			args := mergeArgs(map[string]tftypes.Value{
+				"source": stringVal("echo hello\n"),
+			}, test.args)
+			testValidateWant(t, args, tfprotov5.DiagnosticSeverityWarning, "Variable name has a special meaning", test.path, "")
+		})
+	}
+}
+
+func TestBashScriptEmptySource(t *testing.T) {
+	vars := objectVal(map[string]tftypes.Value{
+		"a": stringVal("x"),
+	})
+
+	tests := map[string]struct {
+		args map[string]tftypes.Value
+		warn bool
+	}{
+		"empty": {
+			args: map[string]tftypes.Value{
+				"source":    stringVal(""),
+				"variables": vars,
+			},
+			warn: true,
+		},
+		"only whitespace": {
+			args: map[string]tftypes.Value{
+				"source":    stringVal(" \n\t\n"),
+				"variables": vars,
+			},
+			warn: true,
+		},
+		"no variables": {
+			args: map[string]tftypes.Value{
+				"source": stringVal(""),
+			},
+		},
+		"not empty": {
+			args: map[string]tftypes.Value{
+				"source":    stringVal("echo \"$a\"\n"),
+				"variables": vars,
+			},
+		},
+		"unknown": {
+			args: map[string]tftypes.Value{
+				"source":    unknownVal(tftypes.String),
+				"variables": vars,
+			},
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			diags := testValidate(t, test.args)
+			if !test.warn {
+				wantNoDiags(t, diags)
+				return
+			}
+			wantNoErrors(t, diags)
+			wantDiag(t, diags, tfprotov5.DiagnosticSeverityWarning, "Empty script source", "source", "")
+		})
+	}
+}
+
+func TestBashScriptExpect(t *testing.T) {
+	vars := objectVal(map[string]tftypes.Value{
+		"greeting": stringVal("Hello"),
+		"names":    stringListVal("Alice", "Bob"),
+	})
+
+	tests := map[string]struct {
+		args    map[string]tftypes.Value
+		summary string // empty means no errors are expected
+		path    string
+	}{
+		"matching": {
+			args: map[string]tftypes.Value{
+				"variables": vars,
+				"expect":    stringMapVal("greeting", "'Hello'", "names", "('Alice' 'Bob')"),
+			},
+		},
+		"matching make": {
+			args: map[string]tftypes.Value{
+				"dialect":   stringVal("make"),
+				"variables": vars,
+				"expect":    stringMapVal("greeting", "Hello", "names", "Alice Bob"),
+			},
+		},
+		"not matching": {
+			args: map[string]tftypes.Value{
+				"variables": vars,
+				"expect":    stringMapVal("greeting", `"Hello"`),
+			},
+			summary: "Unexpected variable declaration",
+			path:    `expect["greeting"]`,
+		},
+		"undeclared": {
+			args: map[string]tftypes.Value{
+				"variables": vars,
+				"expect":    stringMapVal("farewell", "'Bye'"),
+			},
+			summary: "Reference to undeclared variable",
+			path:    `expect["farewell"]`,
+		},
+		"unknown value": {
+			args: map[string]tftypes.Value{
+				"variables": objectVal(map[string]tftypes.Value{
+					"greeting": unknownVal(tftypes.String),
+				}),
+				"expect": stringMapVal("greeting", "'Hello'"),
+			},
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			args := mergeArgs(map[string]tftypes.Value{
+				"source": stringVal("echo hello\n"),
+			}, test.args)
+			testValidateWant(t, args, tfprotov5.DiagnosticSeverityError, test.summary, test.path, "")
+		})
+	}
+}
+
+func TestBashScriptBooleans(t *testing.T) {
+	vars := objectVal(map[string]tftypes.Value{
+		"off": boolVal(false),
+		"on":  boolVal(true),
+	})
+
+	tests := map[string]struct {
+		args map[string]tftypes.Value
+		want string
+	}{
+		"default": {
+			args: map[string]tftypes.Value{},
+			want: `declare -r off='false'
+declare -r on='true'
+`,
+		},
+		"word": {
+			args: map[string]tftypes.Value{
+				"bool_format": stringVal("word"),
+			},
+			want: `declare -r off='false'
+declare -r on='true'
+`,
+		},
+		"integer": {
+			args: map[string]tftypes.Value{
+				"bool_format": stringVal("integer"),
+			},
+			want: `declare -ri off=0
+declare -ri on=1
+`,
+		},
+		"presence": {
+			args: map[string]tftypes.Value{
+				"bool_format": stringVal("presence"),
+			},
+			want: `declare -ri on=1
+`,
+		},
+		"presence in sh": {
+			args: map[string]tftypes.Value{
+				"dialect":     stringVal("sh"),
+				"bool_format": stringVal("presence"),
+			},
+			want: `on=1
+`,
+		},
+		"tokens": {
+			args: map[string]tftypes.Value{
+				"bool_tokens": stringMapVal("true", "yes", "false", "no"),
+			},
+			want: `declare -r off='no'
+declare -r on='yes'
+`,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			args := mergeArgs(map[string]tftypes.Value{
+				"source":    stringVal(""),
+				"variables": vars,
+			}, test.args)
+			attrs, diags := testRead(t, args)
+			wantNoErrors(t, diags)
+			if got := testStringAttr(t, attrs, "result"); got != test.want {
+				t.Errorf("wrong result\ngot:\n%s\nwant:\n%s", got, test.want)
+			}
+
+			// The variables are echoed back with their original types,
+			// regardless of how we rendered them.
+			var gotVars map[string]tftypes.Value
+			if err := attrs["variables"].As(&gotVars); err != nil {
+				t.Fatalf("wrong variables %#v: %s", attrs["variables"], err)
+			}
+			for name, want := range map[string]bool{"off": false, "on": true} {
+				var got bool
+				if err := gotVars[name].As(&got); err != nil {
+					t.Errorf("wrong value for %q %#v: %s", name, gotVars[name], err)
+				} else if got != want {
+					t.Errorf("wrong value for %q %t; want %t", name, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestBashScriptReadOnlyNames(t *testing.T) {
+	tests := map[string]struct {
+		args    map[string]tftypes.Value
+		want    string
+		summary string // if set, an error with this summary is expected instead
+		path    string
+	}{
+		"mixed": {
+			args: map[string]tftypes.Value{
+				"readonly":       boolVal(false),
+				"readonly_names": stringSetVal("hosts", "region"),
+				"variables": objectVal(map[string]tftypes.Value{
+					"hosts":   stringListVal("a.example.com"),
+					"region":  stringVal("us-west-2"),
+					"retries": numberVal("3"),
+					"tags":    stringMapVal("k", "v"),
+				}),
+			},
+			want: `declare -ra hosts=('a.example.com')
+declare -r region='us-west-2'
+declare -i retries=3
+declare -A tags=(['k']='v')
+`,
+		},
+		"with per-variable export": {
+			args: map[string]tftypes.Value{
+				"readonly":       boolVal(false),
+				"readonly_names": stringSetVal("region"),
+				"variables": objectVal(map[string]tftypes.Value{
+					"region": objectVal(map[string]tftypes.Value{
+						"value":  stringVal("us-west-2"),
+						"export": boolVal(true),
+					}),
+					"retries": numberVal("3"),
+				}),
+			},
+			want: `declare -rx region='us-west-2'
+declare -i retries=3
+`,
+		},
+		"undeclared": {
+			args: map[string]tftypes.Value{
+				"readonly":       boolVal(false),
+				"readonly_names": stringSetVal("region"),
+			},
+			summary: "Reference to undeclared variable",
+			path:    `readonly_names["region"]`,
+		},
+		"per-variable readonly false": {
+			args: map[string]tftypes.Value{
+				"readonly":       boolVal(false),
+				"readonly_names": stringSetVal("region"),
+				"variables": objectVal(map[string]tftypes.Value{
+					"region": objectVal(map[string]tftypes.Value{
+						"value":    stringVal("us-west-2"),
+						"readonly": boolVal(false),
+					}),
+				}),
+			},
+			summary: "Unsuitable variable",
+			path:    `readonly_names["region"]`,
+		},
+		"already read-only": {
+			args: map[string]tftypes.Value{
+				"readonly_names": stringSetVal("region"),
+				"variables": objectVal(map[string]tftypes.Value{
+					"region": stringVal("us-west-2"),
+				}),
+			},
+			summary: "Conflicting options",
+			path:    "readonly_names",
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			args := mergeArgs(map[string]tftypes.Value{
+				"source": stringVal(""),
+			}, test.args)
+			if test.summary != "" {
+				diags := testValidate(t, args)
+				wantDiag(t, diags, tfprotov5.DiagnosticSeverityError, test.summary, test.path, "")
+				return
+			}
+			got := testResult(t, args)
+			if got != test.want {
+				t.Errorf("wrong result\ngot:\n%s\nwant:\n%s", got, test.want)
+			}
+		})
+	}
+}
+
+func TestBashScriptExport(t *testing.T) {
+	tests := map[string]struct {
+		args  map[string]tftypes.Value
+		want  string
+		warns []string // paths of the expected "Variable can't be exported" warnings
+	}{
+		"scalars": {
+			args: map[string]tftypes.Value{
+				"export": boolVal(true),
+				"variables": objectVal(map[string]tftypes.Value{
+					"n": numberVal("1"),
+					"s": stringVal("x"),
+				}),
+			},
+			want: `declare -rxi n=1
+declare -rx s='x'
+`,
+		},
+		"associative array": {
+			args: map[string]tftypes.Value{
+				"export": boolVal(true),
+				"variables": objectVal(map[string]tftypes.Value{
+					"m": stringMapVal("k", "v"),
+					"s": stringVal("x"),
+				}),
+			},
+			want: `declare -rA m=(['k']='v')
+declare -rx s='x'
+`,
+			warns: []string{"variables.m"},
+		},
+		"per-variable export of an array": {
+			args: map[string]tftypes.Value{
+				"variables": objectVal(map[string]tftypes.Value{
+					"l": objectVal(map[string]tftypes.Value{
+						"value":  stringListVal("a"),
+						"export": boolVal(true),
+					}),
+				}),
+			},
+			want: `declare -ra l=('a')
+`,
+			warns: []string{"variables.l"},
+		},
+		"not exported": {
+			args: map[string]tftypes.Value{
+				"variables": objectVal(map[string]tftypes.Value{
+					"m": stringMapVal("k", "v"),
+				}),
+			},
+			want: `declare -rA m=(['k']='v')
+`,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			args := mergeArgs(map[string]tftypes.Value{
+				"source": stringVal("env\n"),
+			}, test.args)
+			attrs, diags := testRead(t, args)
+			wantNoErrors(t, diags)
+			if len(diags) != len(test.warns) {
+				t.Errorf("got %d diagnostics; want %d", len(diags), len(test.warns))
+			}
+			for _, path := range test.warns {
+				wantDiag(t, diags, tfprotov5.DiagnosticSeverityWarning, "Variable can't be exported", path, "")
+			}
+			if got := testStringAttr(t, attrs, "result"); got != test.want+"env\n" {
+				t.Errorf("wrong result\ngot:\n%s\nwant:\n%s", got, test.want)
+			}
+		})
+	}
+}
+
+func TestBashScriptStructuredVariables(t *testing.T) {
+	tests := map[string]struct {
+		variables tftypes.Value
+		args      map[string]tftypes.Value
+		want      string
+		summary   string // if set, an error with this summary is expected instead
+		path      string
+	}{
+		"mixed with bare": {
+			variables: objectVal(map[string]tftypes.Value{
+				"bare": stringVal("a"),
+				"exported": objectVal(map[string]tftypes.Value{
+					"value":  stringVal("b"),
+					"export": boolVal(true),
+				}),
+				"mutable": objectVal(map[string]tftypes.Value{
+					"value":    numberVal("1"),
+					"readonly": boolVal(false),
+				}),
+				"plain": objectVal(map[string]tftypes.Value{
+					"value": stringListVal("c"),
+				}),
+			}),
+			want: `declare -r bare='a'
+declare -rx exported='b'
+declare -i mutable=1
+declare -ra plain=('c')
+`,
+		},
+		"overriding the arguments": {
+			variables: objectVal(map[string]tftypes.Value{
+				"bare": stringVal("a"),
+				"fixed": objectVal(map[string]tftypes.Value{
+					"value":    stringVal("b"),
+					"readonly": boolVal(true),
+					"export":   boolVal(false),
+				}),
+			}),
+			args: map[string]tftypes.Value{
+				"readonly": boolVal(false),
+				"export":   boolVal(true),
+			},
+			want: `declare -x bare='a'
+declare -r fixed='b'
+`,
+		},
+		"object with other attributes": {
+			variables: objectVal(map[string]tftypes.Value{
+				"obj": objectVal(map[string]tftypes.Value{
+					"value": stringVal("a"),
+					"other": stringVal("b"),
+				}),
+			}),
+			want: `declare -rA obj=(['other']='b' ['value']='a')
+`,
+		},
+		"non-bool setting": {
+			variables: objectVal(map[string]tftypes.Value{
+				"v": objectVal(map[string]tftypes.Value{
+					"value":  stringVal("a"),
+					"export": stringVal("yes"),
+				}),
+			}),
+			summary: "Invalid variable settings",
+			path:    "variables.v.export",
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			args := mergeArgs(map[string]tftypes.Value{
+				"source":    stringVal(""),
+				"variables": test.variables,
+			}, test.args)
+			if test.summary != "" {
+				diags := testValidate(t, args)
+				wantDiag(t, diags, tfprotov5.DiagnosticSeverityError, test.summary, test.path, "")
+				return
+			}
+			got := testResult(t, args)
+			if got != test.want {
+				t.Errorf("wrong result\ngot:\n%s\nwant:\n%s", got, test.want)
+			}
+		})
+	}
+}
+
+func TestBashScriptNamePrefix(t *testing.T) {
+	tests := map[string]struct {
+		args    map[string]tftypes.Value
+		want    string
+		summary string // if set, an error with this summary is expected instead
+		path    string
+		detail  string
+	}{
+		"several variables": {
+			args: map[string]tftypes.Value{
+				"variables": objectVal(map[string]tftypes.Value{
+					"region": stringVal("us-east-1"),
+					"port":   numberVal("8080"),
+					"zones":  stringListVal("a", "b"),
+					"tags":   stringMapVal("k", "v"),
+				}),
+			},
+			want: `declare -ri tf_port=8080
+declare -r tf_region='us-east-1'
+declare -rA tf_tags=(['k']='v')
+declare -ra tf_zones=('a' 'b')
+`,
+		},
+		"valid only with the prefix": {
+			args: map[string]tftypes.Value{
+				"variables": objectVal(map[string]tftypes.Value{
+					"1st": stringVal("x"),
+				}),
+			},
+			want: `declare -r tf_1st='x'
+`,
+		},
+		"names in other arguments": {
+			args: map[string]tftypes.Value{
+				"trace_names": stringSetVal("a"),
+				"variables": objectVal(map[string]tftypes.Value{
+					"a": stringVal("x"),
+				}),
+			},
+			want: `declare -rt tf_a='x'
+`,
+		},
+		"invalid with the prefix": {
+			args: map[string]tftypes.Value{
+				"variables": objectVal(map[string]tftypes.Value{
+					"not-valid": stringVal("x"),
+				}),
+			},
+			summary: "Invalid variable name",
+			path:    "variables.not-valid",
+			detail:  `"tf_not-valid"`,
+		},
+		"invalid prefix": {
+			args: map[string]tftypes.Value{
+				"name_prefix": stringVal("1tf_"),
+			},
+			summary: "Invalid name prefix",
+			path:    "name_prefix",
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			args := mergeArgs(map[string]tftypes.Value{
+				"source":      stringVal(""),
+				"name_prefix": stringVal("tf_"),
+			}, test.args)
+			if test.summary != "" {
+				diags := testValidate(t, args)
+				wantDiag(t, diags, tfprotov5.DiagnosticSeverityError, test.summary, test.path, test.detail)
+				return
+			}
+			if got := testResult(t, args); got != test.want {
+				t.Errorf("wrong result\ngot:\n%s\nwant:\n%s", got, test.want)
+			}
+		})
+	}
+}
+
+func TestBashScriptSingleArray(t *testing.T) {
+	vars := objectVal(map[string]tftypes.Value{
+		"region": stringVal("us-east-1"),
+		"port":   numberVal("8080"),
+		"zones":  stringListVal("b", "a"),
+		"tags":   stringMapVal("k", "v"),
+		"alpha":  stringVal("first"),
+	})
+
+	tests := map[string]struct {
+		args    map[string]tftypes.Value
+		want    string
+		summary string // if set, an error with this summary is expected instead
+		path    string
+	}{
+		"json collections": {
+			args: map[string]tftypes.Value{
+				"single_array_collections": stringVal("json"),
+			},
+			want: `declare -rA VARS=([alpha]='first' [port]='8080' [region]='us-east-1' [tags]='{"k":"v"}' [zones]='["b","a"]')
+`,
+		},
+		"collections rejected": {
+			args:    map[string]tftypes.Value{},
+			summary: "Invalid variable value",
+			path:    "variables.tags",
+		},
+		"invalid name": {
+			args: map[string]tftypes.Value{
+				"single_array":             stringVal("not-valid"),
+				"single_array_collections": stringVal("json"),
+			},
+			summary: "Invalid array name",
+			path:    "single_array",
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			args := mergeArgs(map[string]tftypes.Value{
+				"source":       stringVal(""),
+				"single_array": stringVal("VARS"),
+				"variables":    vars,
+			}, test.args)
+			if test.summary != "" {
+				diags := testValidate(t, args)
+				wantDiag(t, diags, tfprotov5.DiagnosticSeverityError, test.summary, test.path, "")
+				return
+			}
+			// The keys must come out in the same order every time, even
+			// though Go randomizes map iteration order.
+			for i := 0; i < 10; i++ {
+				if got := testResult(t, args); got != test.want {
+					t.Fatalf("wrong result\ngot:\n%s\nwant:\n%s", got, test.want)
+				}
+			}
+		})
+	}
+}
+
+func TestBashScriptEmptyStrings(t *testing.T) {
+	vars := objectVal(map[string]tftypes.Value{
+		"empty":    stringVal(""),
+		"nonempty": stringVal("x"),
+		"list":     stringListVal(""),
+	})
+
+	tests := map[string]struct {
+		mode tftypes.Value
+		want string
+	}{
+		"default": {
+			mode: nullVal(tftypes.String),
+			want: `declare -r empty=''
+declare -ra list=('')
+declare -r nonempty='x'
+`,
+		},
+		"declare_empty": {
+			mode: stringVal("declare_empty"),
+			want: `declare -r empty=''
+declare -ra list=('')
+declare -r nonempty='x'
+`,
+		},
+		"skip": {
+			mode: stringVal("skip"),
+			want: `declare -ra list=('')
+declare -r nonempty='x'
+`,
+		},
+		"declare_null": {
+			mode: stringVal("declare_null"),
+			want: `declare -r empty
+declare -ra list=('')
+declare -r nonempty='x'
+`,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			got := testResult(t, map[string]tftypes.Value{
+				"source":            stringVal(""),
+				"variables":         vars,
+				"empty_string_mode": test.mode,
+			})
+			if got != test.want {
+				t.Errorf("wrong result\ngot:\n%s\nwant:\n%s", got, test.want)
+			}
+		})
+	}
+}
+
+func TestBashScriptPreview(t *testing.T) {
+	args := map[string]tftypes.Value{
+		"source":  stringVal("echo \"$password\"\n"),
+		"preview": boolVal(true),
+		"variables": objectVal(map[string]tftypes.Value{
+			"password": stringVal("hunter2"),
+		}),
+	}
+
+	t.Run("preview", func(t *testing.T) {
+		diags := testValidate(t, args)
+		wantDiag(t, diags, tfprotov5.DiagnosticSeverityWarning, "Script preview", "", "declare -r password='hunter2'\necho \"$password\"\n")
+	})
+	t.Run("sensitive", func(t *testing.T) {
+		sensitiveArgs := map[string]tftypes.Value{
+			"result_sensitive": boolVal(true),
+		}
+		for k, v := range args {
+			sensitiveArgs[k] = v
+		}
+		diags := testValidate(t, sensitiveArgs)
+		wantDiag(t, diags, tfprotov5.DiagnosticSeverityWarning, "Script preview", "", "not shown")
+		for _, diag := range diags {
+			if strings.Contains(diag.Detail, "hunter2") {
+				t.Errorf("diagnostic reveals the sensitive value: %s", diagString(diag))
+			}
+		}
+	})
+	t.Run("unknown", func(t *testing.T) {
+		unknownArgs := map[string]tftypes.Value{
+			"variables": objectVal(map[string]tftypes.Value{
+				"password": unknownVal(tftypes.String),
+			}),
+		}
+		for k, v := range args {
+			if _, ok := unknownArgs[k]; !ok {
+				unknownArgs[k] = v
+			}
+		}
+		wantNoDiags(t, testValidate(t, unknownArgs))
+	})
+}
+
+func TestBashScriptNumericNames(t *testing.T) {
+	tests := map[string]struct {
+		value  tftypes.Value
+		want   string
+		detail string // if set, an error containing this is expected instead
+	}{
+		"whole number": {
+			value: stringVal("8080"),
+			want: `declare -ri port=8080
+`,
+		},
+		"negative": {
+			value: stringVal("-1"),
+			want: `declare -ri port=-1
+`,
+		},
+		"fractional": {
+			value:  stringVal("1.5"),
+			detail: "isn't a whole number",
+		},
+		"not a number": {
+			value:  stringVal("http"),
+			detail: "isn't a whole number",
+		},
+		"too large": {
+			value:  stringVal("9223372036854775808"),
+			detail: "outside the range of Bash integers",
+		},
+		"not a string": {
+			value:  stringListVal("8080"),
+			detail: "isn't a string variable",
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			args := map[string]tftypes.Value{
+				"source":        stringVal(""),
+				"numeric_names": stringSetVal("port"),
+				"variables": objectVal(map[string]tftypes.Value{
+					"port": test.value,
+				}),
+			}
+			if test.detail != "" {
+				diags := testValidate(t, args)
+				wantDiag(t, diags, tfprotov5.DiagnosticSeverityError, "Unsuitable variable", `numeric_names["port"]`, test.detail)
+				return
+			}
+			got := testResult(t, args)
+			if got != test.want {
+				t.Errorf("wrong result\ngot:\n%s\nwant:\n%s", got, test.want)
+			}
+		})
+	}
+}
+
+func TestBashScriptVariableBashTypes(t *testing.T) {
+	tests := map[string]struct {
+		args map[string]tftypes.Value
+		want map[string]string // nil means the result should be null
+	}{
+		"each kind": {
+			args: map[string]tftypes.Value{
+				"variables": objectVal(map[string]tftypes.Value{
+					"s": stringVal("x"),
+					"n": numberVal("1"),
+					"l": stringListVal("a"),
+					"m": stringMapVal("k", "v"),
+				}),
+			},
+			want: map[string]string{
+				"s": "scalar",
+				"n": "integer",
+				"l": "indexed_array",
+				"m": "associative_array",
+			},
+		},
+		"null delimited": {
+			args: map[string]tftypes.Value{
+				"list_as_null_delimited": stringSetVal("l"),
+				"variables": objectVal(map[string]tftypes.Value{
+					"l": stringListVal("a"),
+				}),
+			},
+			want: map[string]string{
+				"l": "function",
+			},
+		},
+		"single array": {
+			args: map[string]tftypes.Value{
+				"single_array": stringVal("config"),
+				"variables": objectVal(map[string]tftypes.Value{
+					"s": stringVal("x"),
+				}),
+			},
+			want: nil,
+		},
+		"dialect": {
+			args: map[string]tftypes.Value{
+				"dialect": stringVal("sh"),
+				"variables": objectVal(map[string]tftypes.Value{
+					"s": stringVal("x"),
+				}),
+			},
+			want: nil,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			args := mergeArgs(map[string]tftypes.Value{
+				"source": stringVal(""),
+			}, test.args)
+			attrs, diags := testRead(t, args)
+			wantNoErrors(t, diags)
+			v := attrs["variable_bash_types"]
+			if test.want == nil {
+				if !v.IsNull() {
+					t.Errorf("wrong result %#v; want null", v)
+				}
+				return
+			}
+			var vals map[string]tftypes.Value
+			if err := v.As(&vals); err != nil {
+				t.Fatalf("wrong result %#v: %s", v, err)
+			}
+			got := make(map[string]string, len(vals))
+			for name, val := range vals {
+				var kind string
+				if err := val.As(&kind); err != nil {
+					t.Fatalf("wrong result for %q: %s", name, err)
+				}
+				got[name] = kind
+			}
+			if len(got) != len(test.want) {
+				t.Errorf("wrong result\ngot:  %#v\nwant: %#v", got, test.want)
+			}
+			for name, want := range test.want {
+				if got[name] != want {
+					t.Errorf("wrong kind for %q %q; want %q", name, got[name], want)
+				}
+			}
+		})
+	}
+}
+
+func TestBashScriptListAsWords(t *testing.T) {
+	tests := map[string]struct {
+		value   tftypes.Value
+		want    string
+		warning string // path of the expected warning, if any
+	}{
+		"safe elements": {
+			value: stringListVal("-v", "--color=auto"),
+			want: `declare -r args='-v --color=auto'
+`,
+		},
+		"no elements": {
+			value: stringListVal(),
+			want: `declare -r args=''
+`,
+		},
+		"element with space": {
+			value: stringListVal("a", "b c"),
+			want: `declare -r args='a b c'
+`,
+			warning: "variables.args[1]",
+		},
+		"element with newline": {
+			value: stringListVal("a\nb"),
+			want: `declare -r args=$'a\nb'
+`,
+			warning: "variables.args[0]",
+		},
+		"empty element": {
+			value: stringListVal("a", ""),
+			want: `declare -r args='a '
+`,
+			warning: "variables.args[1]",
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			args := map[string]tftypes.Value{
+				"source":        stringVal("echo $args\n"),
+				"list_as_words": stringSetVal("args"),
+				"variables": objectVal(map[string]tftypes.Value{
+					"args": test.value,
+				}),
+			}
+			diags := testValidate(t, args)
+			if test.warning != "" {
+				wantDiag(t, diags, tfprotov5.DiagnosticSeverityWarning, "List element will not survive word splitting", test.warning, "")
+			} else {
+				wantNoDiags(t, diags)
+			}
+			got := strings.TrimSuffix(testResult(t, args), "echo $args\n")
+			if got != test.want {
+				t.Errorf("wrong result\ngot:\n%s\nwant:\n%s", got, test.want)
+			}
+		})
+	}
+}
+
+func TestBashScriptNullCollections(t *testing.T) {
+	nulls := objectVal(map[string]tftypes.Value{
+		"l": nullVal(listOfString),
+		"m": nullVal(mapOfString),
+		"s": stringVal("x"),
+	})
+
+	tests := map[string]struct {
+		args    map[string]tftypes.Value
+		want    string
+		summary string   // summary of the expected errors, if any
+		paths   []string // paths of the expected errors
+	}{
+		"default": {
+			args: map[string]tftypes.Value{},
+			want: `declare -r s='x'
+`,
+		},
+		"omit": {
+			args: map[string]tftypes.Value{
+				"null_collection_mode": stringVal("omit"),
+			},
+			want: `declare -r s='x'
+`,
+		},
+		"empty": {
+			args: map[string]tftypes.Value{
+				"null_collection_mode": stringVal("empty"),
+			},
+			want: `declare -ra l=()
+declare -rA m=()
+declare -r s='x'
+`,
+		},
+		"error": {
+			args: map[string]tftypes.Value{
+				"null_collection_mode": stringVal("error"),
+			},
+			summary: "Invalid variable value",
+			paths:   []string{"variables.l", "variables.m"},
+		},
+		"omit with omit_null": {
+			args: map[string]tftypes.Value{
+				"null_collection_mode": stringVal("omit"),
+				"omit_null":            boolVal(true),
+			},
+			want: `declare -r s='x'
+`,
+		},
+		"invalid": {
+			args: map[string]tftypes.Value{
+				"null_collection_mode": stringVal("skip"),
+			},
+			summary: "Invalid null collection mode",
+			paths:   []string{"null_collection_mode"},
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			args := mergeArgs(map[string]tftypes.Value{
+				"source":    stringVal(""),
+				"variables": nulls,
+			}, test.args)
+			if test.summary != "" {
+				diags := testValidate(t, args)
+				for _, path := range test.paths {
+					wantDiag(t, diags, tfprotov5.DiagnosticSeverityError, test.summary, path, "")
+				}
+				return
+			}
+			attrs, diags := testRead(t, args)
+			wantNoErrors(t, diags)
+			if got := testStringAttr(t, attrs, "result"); got != test.want {
+				t.Errorf("wrong result\ngot:\n%s\nwant:\n%s", got, test.want)
+			}
+			// The variables are echoed back exactly as given, even though
+			// the null collections were rendered differently.
+			var vars map[string]tftypes.Value
+			if err := attrs["variables"].As(&vars); err != nil {
+				t.Fatalf("wrong variables %#v: %s", attrs["variables"], err)
+			}
+			for _, name := range []string{"l", "m"} {
+				if !vars[name].IsNull() {
+					t.Errorf("variables[%q] is %#v; want null", name, vars[name])
+				}
+			}
+		})
+	}
+}
+
+func TestBashScriptNullValues(t *testing.T) {
+	nulls := objectVal(map[string]tftypes.Value{
+		"b": nullVal(tftypes.Bool),
+		"k": stringVal("x"),
+		"n": nullVal(tftypes.Number),
+		"s": nullVal(tftypes.String),
+	})
+
+	tests := map[string]struct {
+		args  map[string]tftypes.Value
+		want  string
+		paths []string // paths of the expected errors, if any
+	}{
+		"default": {
+			args:  map[string]tftypes.Value{},
+			paths: []string{"variables.b", "variables.n", "variables.s"},
+		},
+		"omit_null false": {
+			args: map[string]tftypes.Value{
+				"omit_null": boolVal(false),
+			},
+			paths: []string{"variables.b", "variables.n", "variables.s"},
+		},
+		"omit_null true": {
+			args: map[string]tftypes.Value{
+				"omit_null": boolVal(true),
+			},
+			want: `declare -r k='x'
+`,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			args := mergeArgs(map[string]tftypes.Value{
+				"source":    stringVal("echo \"$k\"\n"),
+				"variables": nulls,
+			}, test.args)
+			if len(test.paths) != 0 {
+				diags := testValidate(t, args)
+				for _, path := range test.paths {
+					wantDiag(t, diags, tfprotov5.DiagnosticSeverityError, "Invalid variable value", path, "omit_null")
+				}
+				return
+			}
+			attrs, diags := testRead(t, args)
+			wantNoDiags(t, diags)
+			if got := testStringAttr(t, attrs, "result"); got != test.want+"echo \"$k\"\n" {
+				t.Errorf("wrong result\ngot:\n%s\nwant:\n%s", got, test.want)
+			}
+			// Omitted variables don't appear in variable_bash_types either.
+			var kinds map[string]tftypes.Value
+			if err := attrs["variable_bash_types"].As(&kinds); err != nil {
+				t.Fatalf("wrong variable_bash_types %#v: %s", attrs["variable_bash_types"], err)
+			}
+			if len(kinds) != 1 || kinds["k"].IsNull() {
+				t.Errorf("wrong variable_bash_types %#v; want only \"k\"", kinds)
+			}
+		})
+	}
+}
+
+func TestBashScriptFunctionName(t *testing.T) {
+	tests := map[string]struct {
+		args    map[string]tftypes.Value
+		want    string
+		summary string // if set, an error with this summary is expected instead
+	}{
+		"each type": {
+			args: map[string]tftypes.Value{
+				"variables": objectVal(map[string]tftypes.Value{
+					"s": stringVal("x"),
+					"l": stringListVal("a", "b"),
+					"m": stringMapVal("k", "v"),
+				}),
+			},
+			want: `setup() {
+  local -ra l=('a' 'b')
+  local -rA m=(['k']='v')
+  local -r s='x'
+echo "$s ${l[1]} ${m[k]}"
+}
+`,
+		},
+		"mutable": {
+			args: map[string]tftypes.Value{
+				"readonly": boolVal(false),
+				"variables": objectVal(map[string]tftypes.Value{
+					"s": stringVal("x"),
+					"l": stringListVal("a", "b"),
+					"m": stringMapVal("k", "v"),
+				}),
+			},
+			want: `setup() {
+  local -a l=('a' 'b')
+  local -A m=(['k']='v')
+  local s='x'
+echo "$s ${l[1]} ${m[k]}"
+}
+`,
+		},
+		"invalid name": {
+			args: map[string]tftypes.Value{
+				"function_name": stringVal("not-valid"),
+			},
+			summary: "Invalid function name",
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			args := mergeArgs(map[string]tftypes.Value{
+				"source":        stringVal("echo \"$s ${l[1]} ${m[k]}\"\n"),
+				"function_name": stringVal("setup"),
+			}, test.args)
+			if test.summary != "" {
+				diags := testValidate(t, args)
+				wantDiag(t, diags, tfprotov5.DiagnosticSeverityError, test.summary, "function_name", "")
+				return
+			}
+			got := testResult(t, args)
+			if got != test.want {
+				t.Errorf("wrong result\ngot:\n%s\nwant:\n%s", got, test.want)
+			}
+
+			// The variables are local to the function, so they're visible
+			// only while it runs.
+			out := testRunBash(t, got+"setup\necho \"${s-unset}\"\n")
+			if want := "x b v\nunset\n"; out != want {
+				t.Errorf("wrong output\ngot:  %q\nwant: %q", out, want)
+			}
+		})
+	}
+}
+
+func TestBashScriptGlobalNames(t *testing.T) {
+	args := map[string]tftypes.Value{
+		"source":        stringVal("echo \"$loc $glob\"\n"),
+		"function_name": stringVal("setup"),
+		"global_names":  stringSetVal("glob", "globs"),
+		"variables": objectVal(map[string]tftypes.Value{
+			"loc":   stringVal("l"),
+			"glob":  stringVal("g"),
+			"globs": stringMapVal("k", "v"),
+		}),
+	}
+
+	t.Run("declarations", func(t *testing.T) {
+		got := testResult(t, args)
+		want := `setup() {
+  declare -gr glob='g'
+  declare -grA globs=(['k']='v')
+  local -r loc='l'
+echo "$loc $glob"
+}
+`
+		if got != want {
+			t.Errorf("wrong result\ngot:\n%s\nwant:\n%s", got, want)
+		}
+	})
+	t.Run("run", func(t *testing.T) {
+		got := testRunBash(t, testResult(t, args)+"setup\necho \"${loc-unset} ${glob-unset} ${globs[k]-unset}\"\n")
+		if want := "l g\nunset g v\n"; got != want {
+			t.Errorf("wrong output\ngot:  %q\nwant: %q", got, want)
+		}
+	})
+	t.Run("undeclared", func(t *testing.T) {
+		diags := testValidate(t, map[string]tftypes.Value{
+			"source":       stringVal("echo hello\n"),
+			"global_names": stringSetVal("nope"),
+		})
+		wantDiag(t, diags, tfprotov5.DiagnosticSeverityError, "Reference to undeclared variable", `global_names["nope"]`, "")
+	})
+}
+
+func TestBashScriptSplitToArray(t *testing.T) {
+	tests := map[string]struct {
+		value   string
+		delim   string
+		dialect string
+		want    string
+		summary string // if set, an error with this summary is expected instead
+		path    string // path of the expected error
+	}{
+		"colon": {
+			value: "/usr/local/bin:/usr/bin:/bin",
+			delim: ":",
+			want: `declare -ra p=('/usr/local/bin' '/usr/bin' '/bin')
+`,
+		},
+		"comma and space": {
+			value: "a, b c, d",
+			delim: ", ",
+			want: `declare -ra p=('a' 'b c' 'd')
+`,
+		},
+		"newline": {
+			value: "a\nb\n",
+			delim: "\n",
+			want: `declare -ra p=('a' 'b' '')
+`,
+		},
+		"no delimiter in value": {
+			value: "abc",
+			delim: ":",
+			want: `declare -ra p=('abc')
+`,
+		},
+		"empty value": {
+			value: "",
+			delim: ":",
+			want: `declare -ra p=()
+`,
+		},
+		"empty segments": {
+			value: ":a::b:",
+			delim: ":",
+			want: `declare -ra p=('' 'a' '' 'b' '')
+`,
+		},
+		"only the delimiter": {
+			value: ":",
+			delim: ":",
+			want: `declare -ra p=('' '')
+`,
+		},
+		"empty delimiter": {
+			value:   "a:b",
+			delim:   "",
+			summary: "Invalid delimiter",
+			path:    `split_to_array["p"]`,
+		},
+		"make": {
+			value:   "a:b",
+			delim:   ":",
+			dialect: "make",
+			want: `p := a b
+`,
+		},
+		"make with empty segments": {
+			value:   "a::b",
+			delim:   ":",
+			dialect: "make",
+			summary: "Invalid variable value",
+			path:    `split_to_array["p"]`,
+		},
+		"sh": {
+			value:   "a:b",
+			delim:   ":",
+			dialect: "sh",
+			summary: "Invalid variable value",
+			path:    `split_to_array["p"]`,
+		},
+		"dotenv": {
+			value:   "a:b",
+			delim:   ":",
+			dialect: "dotenv",
+			summary: "Invalid variable value",
+			path:    `split_to_array["p"]`,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			args := map[string]tftypes.Value{
+				"source":         stringVal(""),
+				"split_to_array": stringMapVal("p", test.delim),
+				"variables": objectVal(map[string]tftypes.Value{
+					"p": stringVal(test.value),
+				}),
+			}
+			if test.dialect != "" {
+				args["dialect"] = stringVal(test.dialect)
+			}
+			if test.summary != "" {
+				diags := testValidate(t, args)
+				wantDiag(t, diags, tfprotov5.DiagnosticSeverityError, test.summary, test.path, "")
+				_, diags = testRead(t, args)
+				wantDiag(t, diags, tfprotov5.DiagnosticSeverityError, test.summary, test.path, "")
+				return
+			}
+			got := testResult(t, args)
+			if got != test.want {
+				t.Errorf("wrong result\ngot:\n%s\nwant:\n%s", got, test.want)
+			}
+		})
+	}
+}
+
+func TestBashScriptDialectTransformedValues(t *testing.T) {
+	// Arguments that transform the variable values must produce values
+	// that the selected dialect can represent.
+	tests := map[string]struct {
+		args    map[string]tftypes.Value
+		want    string
+		summary string // if set, an error with this summary is expected instead
+		path    string // path of the expected error
+	}{
+		"numeric_names in sh": {
+			args: map[string]tftypes.Value{
+				"dialect":       stringVal("sh"),
+				"numeric_names": stringSetVal("v"),
+				"variables": objectVal(map[string]tftypes.Value{
+					"v": stringVal("8080"),
+				}),
+			},
+			want: `v=8080
+`,
+		},
+		"convert_windows_paths in make": {
+			args: map[string]tftypes.Value{
+				"dialect":               stringVal("make"),
+				"convert_windows_paths": stringSetVal("v"),
+				"variables": objectVal(map[string]tftypes.Value{
+					"v": stringVal(`C:\Users`),
+				}),
+			},
+			want: `v := /c/Users
+`,
+		},
+		"integer booleans in dotenv": {
+			args: map[string]tftypes.Value{
+				"dialect":     stringVal("dotenv"),
+				"bool_format": stringVal("integer"),
+				"variables": objectVal(map[string]tftypes.Value{
+					"v": boolVal(true),
+				}),
+			},
+			want: `v=1
+`,
+		},
+		"empty null collection in sh": {
+			args: map[string]tftypes.Value{
+				"dialect":              stringVal("sh"),
+				"null_collection_mode": stringVal("empty"),
+				"variables": objectVal(map[string]tftypes.Value{
+					"v": nullVal(listOfString),
+				}),
+			},
+			summary: "Invalid variable value",
+			path:    "variables.v",
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			args := mergeArgs(map[string]tftypes.Value{
+				"source": stringVal(""),
+			}, test.args)
+			if test.summary != "" {
+				diags := testValidate(t, args)
+				wantDiag(t, diags, tfprotov5.DiagnosticSeverityError, test.summary, test.path, "")
+				return
+			}
+			got := testResult(t, args)
+			if got != test.want {
+				t.Errorf("wrong result\ngot:\n%s\nwant:\n%s", got, test.want)
+			}
+		})
+	}
+}
+
+func TestBashScriptVariablesMap(t *testing.T) {
+	tests := map[string]struct {
+		variables tftypes.Value
+		want      string
+	}{
+		"map of strings": {
+			variables: stringMapVal("region", "us-east-1", "zone", "a"),
+			want: `declare -r region='us-east-1'
+declare -r zone='a'
+`,
+		},
+		"map of numbers": {
+			variables: numberMapVal("port", "8080", "retries", "3"),
+			want: `declare -ri port=8080
+declare -ri retries=3
+`,
+		},
+		"map of lists": {
+			variables: tftypes.NewValue(tftypes.Map{AttributeType: listOfString}, map[string]tftypes.Value{
+				"names": stringListVal("a", "b"),
+			}),
+			want: `declare -ra names=('a' 'b')
+`,
+		},
+		"empty map": {
+			variables: stringMapVal(),
+			want:      "",
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			attrs, diags := testRead(t, map[string]tftypes.Value{
+				"source":    stringVal(""),
+				"variables": test.variables,
+			})
+			wantNoErrors(t, diags)
+			if got := testStringAttr(t, attrs, "result"); got != test.want {
+				t.Errorf("wrong result\ngot:\n%s\nwant:\n%s", got, test.want)
+			}
+			// The variables are echoed back with their original map type.
+			if v := attrs["variables"]; !v.Is(tftypes.Map{}) {
+				t.Errorf("variables is %#v; want a map", v)
+			}
+		})
+	}
+}
+
+func TestBashScriptEmptyCollections(t *testing.T) {
+	tests := map[string]struct {
+		value tftypes.Value
+		want  string
+		kind  string // expected element of variable_bash_types
+	}{
+		"list of strings": {
+			value: stringListVal(),
+			want:  "declare -ra v=()\n",
+			kind:  "indexed_array",
+		},
+		"map of strings": {
+			value: stringMapVal(),
+			want:  "declare -rA v=()\n",
+			kind:  "associative_array",
+		},
+		"empty tuple": {
+			value: tupleVal(),
+			want:  "declare -ra v=()\n",
+			kind:  "indexed_array",
+		},
+		"list of unknown element type": {
+			value: tftypes.NewValue(tftypes.List{ElementType: tftypes.DynamicPseudoType}, []tftypes.Value{}),
+			want:  "declare -ra v=()\n",
+			kind:  "indexed_array",
+		},
+		"map of unknown element type": {
+			value: tftypes.NewValue(tftypes.Map{AttributeType: tftypes.DynamicPseudoType}, map[string]tftypes.Value{}),
+			want:  "declare -rA v=()\n",
+			kind:  "associative_array",
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			variables := tftypes.NewValue(tftypes.Object{AttributeTypes: map[string]tftypes.Type{
+				"v": testTypeOf(test.value),
+			}}, map[string]tftypes.Value{
+				"v": test.value,
+			})
+			attrs, diags := testRead(t, map[string]tftypes.Value{
+				"source":    stringVal(""),
+				"variables": variables,
+			})
+			wantNoErrors(t, diags)
+			if got := testStringAttr(t, attrs, "result"); got != test.want {
+				t.Errorf("wrong result\ngot:  %s\nwant: %s", got, test.want)
+			}
+			var kinds map[string]tftypes.Value
+			if err := attrs["variable_bash_types"].As(&kinds); err != nil {
+				t.Fatalf("wrong variable_bash_types %#v: %s", attrs["variable_bash_types"], err)
+			}
+			var kind string
+			if err := kinds["v"].As(&kind); err != nil || kind != test.kind {
+				t.Errorf("wrong kind %#v; want %q", kinds["v"], test.kind)
+			}
+		})
+	}
+}
+
+func TestBashScriptMergedVariables(t *testing.T) {
+	base := objectVal(map[string]tftypes.Value{
+		"region": stringVal("us-east-1"),
+		"port":   numberVal("80"),
+		"names":  stringListVal("a"),
+	})
+
+	tests := map[string]struct {
+		variables tftypes.Value
+		want      string
+		summary   string // if set, an error with this summary is expected instead
+		path      string // path of the expected error
+	}{
+		"single layer": {
+			variables: tupleVal(base),
+			want: `declare -ra names=('a')
+declare -ri port=80
+declare -r region='us-east-1'
+`,
+		},
+		"later layers win": {
+			variables: tupleVal(
+				base,
+				objectVal(map[string]tftypes.Value{
+					"region": stringVal("eu-west-1"),
+					"names":  stringListVal("b", "c"),
+				}),
+				objectVal(map[string]tftypes.Value{
+					"region": stringVal("ap-south-1"),
+					"extra":  boolVal(true),
+				}),
+			),
+			want: `declare -r extra='true'
+declare -ra names=('b' 'c')
+declare -ri port=80
+declare -r region='ap-south-1'
+`,
+		},
+		"list of maps": {
+			variables: tftypes.NewValue(tftypes.List{ElementType: mapOfString}, []tftypes.Value{
+				stringMapVal("a", "1", "b", "2"),
+				stringMapVal("b", "3"),
+			}),
+			want: `declare -r a='1'
+declare -r b='3'
+`,
+		},
+		"null layer": {
+			variables: tupleVal(base, nullVal(tftypes.Object{AttributeTypes: map[string]tftypes.Type{}})),
+			want: `declare -ra names=('a')
+declare -ri port=80
+declare -r region='us-east-1'
+`,
+		},
+		"no layers": {
+			variables: tupleVal(),
+			want:      "",
+		},
+		"type conflict": {
+			variables: tupleVal(
+				base,
+				objectVal(map[string]tftypes.Value{
+					"names": stringVal("b"),
+				}),
+			),
+			summary: "Conflicting variable types",
+			path:    "variables[1].names",
+		},
+		"bare and structured forms": {
+			// Merging happens before the structured form is recognized, so
+			// an override must use the same form as the value it replaces.
+			variables: tupleVal(
+				objectVal(map[string]tftypes.Value{
+					"region": stringVal("us-east-1"),
+				}),
+				objectVal(map[string]tftypes.Value{
+					"region": objectVal(map[string]tftypes.Value{
+						"value":  stringVal("eu-west-1"),
+						"export": boolVal(true),
+					}),
+				}),
+			),
+			summary: "Conflicting variable types",
+			path:    "variables[1].region",
+		},
+		"layer not an object": {
+			variables: tupleVal(base, stringVal("nope")),
+			summary:   "Invalid variables",
+			path:      "variables[1]",
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			args := map[string]tftypes.Value{
+				"source":    stringVal(""),
+				"variables": test.variables,
+			}
+			if test.summary != "" {
+				diags := testValidate(t, args)
+				wantDiag(t, diags, tfprotov5.DiagnosticSeverityError, test.summary, test.path, "")
+				return
+			}
+			got := testResult(t, args)
+			if got != test.want {
+				t.Errorf("wrong result\ngot:\n%s\nwant:\n%s", got, test.want)
+			}
+		})
+	}
+}
+
+func TestBashScriptUnknownVariables(t *testing.T) {
+	// None of these can be checked for undeclared names until "variables"
+	// is known, so they must not report any errors in the meantime.
+	refs := map[string]tftypes.Value{
+		"trace_names":    stringSetVal("a"),
+		"expect":         stringMapVal("a", "'1'"),
+		"split_to_array": stringMapVal("b", ","),
+		"list_as_words":  stringSetVal("c"),
+		"readonly":       boolVal(false),
+		"readonly_names": stringSetVal("d"),
+		"global_names":   stringSetVal("e"),
+		"numeric_names":  stringSetVal("f"),
+	}
+
+	tests := map[string]tftypes.Value{
+		"wholly unknown": unknownVal(tftypes.DynamicPseudoType),
+		"unknown object": unknownVal(tftypes.Object{AttributeTypes: map[string]tftypes.Type{
+			"a": tftypes.String,
+		}}),
+		"unknown scalar": unknownVal(tftypes.String),
+		"unknown layer": tftypes.NewValue(tftypes.List{ElementType: mapOfString}, []tftypes.Value{
+			stringMapVal("a", "1"),
+			unknownVal(mapOfString),
+		}),
+	}
+
+	for name, variables := range tests {
+		t.Run(name, func(t *testing.T) {
+			args := map[string]tftypes.Value{
+				"source":    stringVal("echo hello\n"),
+				"variables": variables,
+			}
+			for k, v := range refs {
+				args[k] = v
+			}
+			wantNoErrors(t, testValidate(t, args))
+
+			attrs, diags := testRead(t, args)
+			wantNoErrors(t, diags)
+			if result := attrs["result"]; result.IsKnown() {
+				t.Errorf("result is known, but should be unknown")
+			}
+		})
+	}
+}
+
+func TestBashScriptArrayCounts(t *testing.T) {
+	script := testResult(t, map[string]tftypes.Value{
+		"source":            stringVal("for v in l s m e; do c=\"${v}_count\"; declare -n a=\"$v\"; echo \"$v ${#a[@]} ${!c}\"; unset -n a; done\n"),
+		"emit_array_counts": boolVal(true),
+		"variables": objectVal(map[string]tftypes.Value{
+			"l": stringListVal("a", "b", "c"),
+			"s": stringSetVal("x", "y"),
+			"m": stringMapVal("k", "v"),
+			"e": stringListVal(),
+		}),
+	})
+	got := testRunBash(t, script)
+	want := "l 3 3\ns 2 2\nm 1 1\ne 0 0\n"
+	if got != want {
+		t.Errorf("wrong output\ngot:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestBashScriptBoolTokens(t *testing.T) {
+	tests := map[string]struct {
+		tokens tftypes.Value
+		want   string
+		detail string // if set, an error containing this is expected instead
+		path   string // path of the expected error
+	}{
+		"on and off": {
+			tokens: stringMapVal("true", "on", "false", "off"),
+			want: `declare -r off='off'
+declare -r on='on'
+`,
+		},
+		"enabled and disabled": {
+			tokens: stringMapVal("true", "enabled", "false", "disabled"),
+			want: `declare -r off='disabled'
+declare -r on='enabled'
+`,
+		},
+		"needs quoting": {
+			tokens: stringMapVal("true", "it's on", "false", "$off"),
+			want: `declare -r off='$off'
+declare -r on='it'\''s on'
+`,
+		},
+		"digits are still strings": {
+			tokens: stringMapVal("true", "1", "false", "0"),
+			want: `declare -r off='0'
+declare -r on='1'
+`,
+		},
+		"empty token": {
+			tokens: stringMapVal("true", "yes", "false", ""),
+			want: `declare -r off=''
+declare -r on='yes'
+`,
+		},
+		"missing false": {
+			tokens: stringMapVal("true", "yes"),
+			detail: `"false" is missing`,
+			path:   "bool_tokens",
+		},
+		"missing true": {
+			tokens: stringMapVal("false", "no"),
+			detail: `"true" is missing`,
+			path:   "bool_tokens",
+		},
+		"extra key": {
+			tokens: stringMapVal("true", "yes", "false", "no", "maybe", "perhaps"),
+			detail: `"maybe" is not allowed`,
+			path:   `bool_tokens["maybe"]`,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			args := map[string]tftypes.Value{
+				"source":      stringVal(""),
+				"bool_tokens": test.tokens,
+				"variables": objectVal(map[string]tftypes.Value{
+					"off": boolVal(false),
+					"on":  boolVal(true),
+				}),
+			}
+			if test.detail != "" {
+				diags := testValidate(t, args)
+				wantDiag(t, diags, tfprotov5.DiagnosticSeverityError, "Invalid boolean tokens", test.path, test.detail)
+				return
+			}
+			got := testResult(t, args)
+			if got != test.want {
+				t.Errorf("wrong result\ngot:\n%s\nwant:\n%s", got, test.want)
+			}
+		})
+	}
+}
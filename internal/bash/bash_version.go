@@ -0,0 +1,82 @@
+package bash
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// bashVersion represents a Bash release version, for the purpose of
+// describing which Bash version a generated script requires.
+type bashVersion struct {
+	Major, Minor int
+}
+
+func (v bashVersion) String() string {
+	return fmt.Sprintf("%d.%d", v.Major, v.Minor)
+}
+
+// Less returns true if v is an earlier version than other.
+func (v bashVersion) Less(other bashVersion) bool {
+	if v.Major != other.Major {
+		return v.Major < other.Major
+	}
+	return v.Minor < other.Minor
+}
+
+// parseBashVersion parses a version string like "4.2" into a bashVersion.
+// The minor version may be omitted, in which case it's assumed to be zero.
+func parseBashVersion(s string) (bashVersion, error) {
+	majorStr, minorStr := s, "0"
+	if dot := strings.IndexByte(s, '.'); dot >= 0 {
+		majorStr, minorStr = s[:dot], s[dot+1:]
+	}
+	major, err := strconv.ParseUint(majorStr, 10, 16)
+	if err != nil {
+		return bashVersion{}, fmt.Errorf("invalid major version %q", majorStr)
+	}
+	minor, err := strconv.ParseUint(minorStr, 10, 16)
+	if err != nil {
+		return bashVersion{}, fmt.Errorf("invalid minor version %q", minorStr)
+	}
+	return bashVersion{int(major), int(minor)}, nil
+}
+
+var (
+	// bashVersionBaseline is the version we assume for the features we
+	// use unconditionally, such as indexed arrays and the declare builtin.
+	bashVersionBaseline = bashVersion{3, 0}
+
+	// bashVersionAssocArrays is the version that introduced associative
+	// arrays, using declare -A.
+	bashVersionAssocArrays = bashVersion{4, 0}
+
+	// bashVersionGlobalDeclare is the version that introduced declare -g.
+	bashVersionGlobalDeclare = bashVersion{4, 2}
+)
+
+// RequiredBashVersion returns the earliest version of Bash that can run the
+// script generated for this configuration.
+//
+// This considers only the code that bash_script generates itself. The
+// source code from the configuration might have its own requirements.
+func (c *bashScriptConfig) RequiredBashVersion() bashVersion {
+	ret := bashVersionBaseline
+	require := func(v bashVersion) {
+		if ret.Less(v) {
+			ret = v
+		}
+	}
+
+	if c.SingleArray != "" {
+		require(bashVersionAssocArrays)
+	}
+	if !c.VersionFallback && !c.MapsAsParallelArrays && hasMaps(c.Variables) {
+		require(bashVersionAssocArrays)
+	}
+	if c.DeclareScope == "global_explicit" || len(c.GlobalNames) != 0 {
+		require(bashVersionGlobalDeclare)
+	}
+
+	return ret
+}
@@ -0,0 +1,273 @@
+package bash
+
+import (
+	"context"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-go/tfprotov5"
+	"github.com/hashicorp/terraform-plugin-go/tfprotov5/tftypes"
+)
+
+func TestParseBashVersion(t *testing.T) {
+	tests := []struct {
+		input   string
+		want    bashVersion
+		wantErr bool
+	}{
+		{"4.2", bashVersion{4, 2}, false},
+		{"5", bashVersion{5, 0}, false},
+		{"3.10", bashVersion{3, 10}, false},
+		{"", bashVersion{}, true},
+		{"4.", bashVersion{}, true},
+		{"four", bashVersion{}, true},
+		{"-4.2", bashVersion{}, true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.input, func(t *testing.T) {
+			got, err := parseBashVersion(test.input)
+			if test.wantErr {
+				if err == nil {
+					t.Fatalf("unexpected success for %q: got %s", test.input, got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error for %q: %s", test.input, err)
+			}
+			if got != test.want {
+				t.Errorf("wrong result for %q: got %s, want %s", test.input, got, test.want)
+			}
+		})
+	}
+}
+
+func TestBashScriptVersionConstraint(t *testing.T) {
+	tests := map[string]struct {
+		args map[string]tftypes.Value
+		want string // empty means the attribute should be null
+	}{
+		"baseline": {
+			args: map[string]tftypes.Value{
+				"variables": objectVal(map[string]tftypes.Value{
+					"a": stringListVal("x"),
+				}),
+			},
+			want: ">= 3.0",
+		},
+		"associative array": {
+			args: map[string]tftypes.Value{
+				"variables": objectVal(map[string]tftypes.Value{
+					"m": stringMapVal("k", "v"),
+				}),
+			},
+			want: ">= 4.0",
+		},
+		"associative array with Bash 3 fallback": {
+			args: map[string]tftypes.Value{
+				"version_fallback": boolVal(true),
+				"variables": objectVal(map[string]tftypes.Value{
+					"m": stringMapVal("k", "v"),
+				}),
+			},
+			want: ">= 3.0",
+		},
+		"single array": {
+			args: map[string]tftypes.Value{
+				"single_array": stringVal("VARS"),
+			},
+			want: ">= 4.0",
+		},
+		"global declarations": {
+			args: map[string]tftypes.Value{
+				"declare_scope": stringVal("global_explicit"),
+				"variables": objectVal(map[string]tftypes.Value{
+					"m": stringMapVal("k", "v"),
+				}),
+			},
+			want: ">= 4.2",
+		},
+		"make dialect": {
+			args: map[string]tftypes.Value{
+				"dialect": stringVal("make"),
+			},
+			want: "",
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			args := mergeArgs(map[string]tftypes.Value{
+				"source": stringVal("echo hello\n"),
+			}, test.args)
+			attrs, diags := testRead(t, args)
+			wantNoErrors(t, diags)
+			if test.want == "" {
+				if v := attrs["version_constraint"]; !v.IsNull() {
+					t.Fatalf("version_constraint is %#v; want null", v)
+				}
+				return
+			}
+			got := testStringAttr(t, attrs, "version_constraint")
+			if got != test.want {
+				t.Errorf("wrong version_constraint\ngot:  %s\nwant: %s", got, test.want)
+			}
+		})
+	}
+}
+
+func TestProviderMinBashVersion(t *testing.T) {
+	oneMap := objectVal(map[string]tftypes.Value{
+		"m": stringMapVal("k", "v"),
+	})
+
+	tests := map[string]struct {
+		provider string // empty means the provider's min_bash_version is null
+		args     map[string]tftypes.Value
+		wantErr  string // path of the expected error, if any
+	}{
+		"no minimum": {
+			args: map[string]tftypes.Value{
+				"variables": oneMap,
+			},
+		},
+		"provider minimum satisfied": {
+			provider: "4.0",
+			args: map[string]tftypes.Value{
+				"variables": oneMap,
+			},
+		},
+		"provider minimum not satisfied": {
+			provider: "3.2",
+			args: map[string]tftypes.Value{
+				"variables": oneMap,
+			},
+			wantErr: "variables.m",
+		},
+		"data source raises the minimum": {
+			provider: "3.2",
+			args: map[string]tftypes.Value{
+				"min_bash_version": stringVal("4.0"),
+				"variables":        oneMap,
+			},
+		},
+		"data source lowers the minimum": {
+			provider: "4.0",
+			args: map[string]tftypes.Value{
+				"min_bash_version": stringVal("3.2"),
+				"variables":        oneMap,
+			},
+			wantErr: "variables.m",
+		},
+		"provider minimum with another feature": {
+			provider: "4.1",
+			args: map[string]tftypes.Value{
+				"declare_scope": stringVal("global_explicit"),
+			},
+			wantErr: "min_bash_version",
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			p := testConfiguredProvider(t, test.provider)
+			args := mergeArgs(map[string]tftypes.Value{
+				"source": stringVal("echo hello\n"),
+			}, test.args)
+			_, diags := testReadWithProvider(t, p, args)
+			if test.wantErr == "" {
+				wantNoErrors(t, diags)
+				return
+			}
+			wantDiag(t, diags, tfprotov5.DiagnosticSeverityError, "Unsupported Bash version", test.wantErr, "")
+		})
+	}
+}
+
+func TestBashScriptBash3Maps(t *testing.T) {
+	vars := objectVal(map[string]tftypes.Value{
+		"m": stringMapVal("b", "2", "a", "1"),
+		"s": stringVal("x"),
+	})
+
+	tests := map[string]struct {
+		args    map[string]tftypes.Value
+		want    string
+		wantErr string // path of the expected error, if any
+	}{
+		"rejected": {
+			args:    map[string]tftypes.Value{},
+			wantErr: "variables.m",
+		},
+		"parallel arrays": {
+			args: map[string]tftypes.Value{
+				"maps_as_parallel_arrays": boolVal(true),
+			},
+			want: `declare -ra m_keys=('a' 'b')
+declare -ra m_values=('1' '2')
+declare -r s='x'
+`,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			args := mergeArgs(map[string]tftypes.Value{
+				"source":           stringVal(""),
+				"min_bash_version": stringVal("3.2"),
+				"variables":        vars,
+			}, test.args)
+			if test.wantErr != "" {
+				diags := testValidate(t, args)
+				wantDiag(t, diags, tfprotov5.DiagnosticSeverityError, "Unsupported Bash version", test.wantErr, "associative arrays require Bash 4.0")
+				return
+			}
+			got := testResult(t, args)
+			if got != test.want {
+				t.Errorf("wrong result\ngot:\n%s\nwant:\n%s", got, test.want)
+			}
+		})
+	}
+}
+
+func TestProviderMinBashVersionInvalid(t *testing.T) {
+	resp, err := NewProvider().PrepareProviderConfig(context.Background(), &tfprotov5.PrepareProviderConfigRequest{
+		Config: testProviderConfig(t, "four"),
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	wantDiag(t, resp.Diagnostics, tfprotov5.DiagnosticSeverityError, "Invalid minimum Bash version", "min_bash_version", `"four"`)
+}
+
+// testConfiguredProvider returns a provider configured with the given
+// min_bash_version, or with it unset if the given version is empty.
+func testConfiguredProvider(t *testing.T, minBashVersion string) *Provider {
+	t.Helper()
+	p := &Provider{}
+	resp, err := p.ConfigureProvider(context.Background(), &tfprotov5.ConfigureProviderRequest{
+		Config: testProviderConfig(t, minBashVersion),
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	wantNoErrors(t, resp.Diagnostics)
+	return p
+}
+
+// testProviderConfig returns a provider configuration with the given
+// min_bash_version, or with it unset if the given version is empty.
+func testProviderConfig(t *testing.T, minBashVersion string) *tfprotov5.DynamicValue {
+	t.Helper()
+	v := tftypes.NewValue(tftypes.String, nil)
+	if minBashVersion != "" {
+		v = stringVal(minBashVersion)
+	}
+	config, err := tfprotov5.NewDynamicValue(providerConfigType, tftypes.NewValue(providerConfigType, map[string]tftypes.Value{
+		"min_bash_version": v,
+	}))
+	if err != nil {
+		t.Fatalf("failed to encode provider configuration: %s", err)
+	}
+	return &config
+}
@@ -0,0 +1,83 @@
+package bash
+
+import (
+	"fmt"
+	"math/big"
+	"sort"
+	"strings"
+
+	"github.com/apparentlymart/terraform-provider-bash/bashgen"
+	"github.com/hashicorp/terraform-plugin-go/tfprotov5/tftypes"
+)
+
+// variablesToDotenv produces the content of a "dotenv" file, with one
+// KEY=value line per string or number variable in vars, in lexical order by
+// name.
+//
+// Dotenv files have no way to represent lists or maps, so variables of
+// those types are skipped.
+func variablesToDotenv(vars map[string]tftypes.Value) string {
+	names := make([]string, 0, len(vars))
+	for name := range vars {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var buf strings.Builder
+	for _, name := range names {
+		raw, err := dotenvValue(vars[name])
+		if err != nil {
+			continue
+		}
+		buf.WriteString(name)
+		buf.WriteString("=")
+		buf.WriteString(raw)
+		buf.WriteString("\n")
+	}
+	return buf.String()
+}
+
+// dotenvValue returns the part of a dotenv line after the equals sign that
+// would produce the given value, or an error if the value is of a type that
+// dotenv files can't represent.
+func dotenvValue(val tftypes.Value) (string, error) {
+	switch {
+	case val.Is(tftypes.String):
+		var s string
+		val.As(&s)
+		return dotenvQuote(s), nil
+	case val.Is(tftypes.Number):
+		var f big.Float
+		val.As(&f)
+		return bashgen.FormatNumber(&f), nil
+	default:
+		return "", fmt.Errorf("dotenv files only support strings, numbers, and booleans")
+	}
+}
+
+// dotenvQuote returns the given string in a form that the common dotenv
+// parsers will all interpret as the literal string.
+//
+// There is no formal specification for the dotenv format, so we use the
+// most conservative form possible for each string: no quotes at all if the
+// string contains only characters that can't be misinterpreted, single
+// quotes if the string contains no single quotes or line breaks, and
+// otherwise double quotes with backslash escapes.
+func dotenvQuote(s string) string {
+	if s != "" && strings.Trim(s, dotenvSafeChars) == "" {
+		return s
+	}
+	if !strings.ContainsAny(s, "'\r\n") {
+		return "'" + s + "'"
+	}
+	r := strings.NewReplacer(
+		`\`, `\\`,
+		`"`, `\"`,
+		`$`, `\$`,
+		"\n", `\n`,
+		"\r", `\r`,
+	)
+	return `"` + r.Replace(s) + `"`
+}
+
+const dotenvSafeChars = "ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz0123456789_-.,:/@%+"
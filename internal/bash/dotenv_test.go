@@ -0,0 +1,118 @@
+package bash
+
+import (
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-go/tfprotov5"
+	"github.com/hashicorp/terraform-plugin-go/tfprotov5/tftypes"
+)
+
+func TestDotenvQuote(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{``, `''`},
+		{`us-east-1`, `us-east-1`},
+		{`/usr/local/bin:/usr/bin`, `/usr/local/bin:/usr/bin`},
+		{`hello world`, `'hello world'`},
+		{`$HOME`, `'$HOME'`},
+		{`it's`, `"it's"`},
+		{"two\nlines", `"two\nlines"`},
+		{"it's $5 \\ \"quoted\"\r\n", `"it's \$5 \\ \"quoted\"\r\n"`},
+	}
+
+	for _, test := range tests {
+		t.Run(test.input, func(t *testing.T) {
+			got := dotenvQuote(test.input)
+			if got != test.want {
+				t.Errorf("wrong result for %q\ngot:  %s\nwant: %s", test.input, got, test.want)
+			}
+		})
+	}
+}
+
+func TestBashScriptEnvResult(t *testing.T) {
+	attrs, diags := testRead(t, map[string]tftypes.Value{
+		"source": stringVal(""),
+		"variables": objectVal(map[string]tftypes.Value{
+			"region":  stringVal("us-east-1"),
+			"port":    numberVal("8080"),
+			"enabled": boolVal(true),
+			"zones":   stringListVal("a", "b"),
+			"tags":    stringMapVal("k", "v"),
+		}),
+	})
+	wantNoErrors(t, diags)
+
+	// The collections can't be represented in a dotenv file, so they are
+	// skipped, while the bash result still includes them.
+	got := testStringAttr(t, attrs, "env_result")
+	want := `enabled=true
+port=8080
+region=us-east-1
+`
+	if got != want {
+		t.Errorf("wrong env_result\ngot:\n%s\nwant:\n%s", got, want)
+	}
+	gotBash := testStringAttr(t, attrs, "result")
+	wantBash := `declare -r enabled='true'
+declare -ri port=8080
+declare -r region='us-east-1'
+declare -rA tags=(['k']='v')
+declare -ra zones=('a' 'b')
+`
+	if gotBash != wantBash {
+		t.Errorf("wrong result\ngot:\n%s\nwant:\n%s", gotBash, wantBash)
+	}
+}
+
+func TestBashScriptDotenvDialect(t *testing.T) {
+	tests := map[string]struct {
+		variables tftypes.Value
+		want      string
+		path      string // path of the expected error, if any
+	}{
+		"quoting only where needed": {
+			variables: objectVal(map[string]tftypes.Value{
+				"greeting": stringVal("hello world"),
+				"region":   stringVal("us-east-1"),
+				"port":     numberVal("8080"),
+			}),
+			want: `greeting='hello world'
+port=8080
+region=us-east-1
+`,
+		},
+		"list": {
+			variables: objectVal(map[string]tftypes.Value{
+				"zones": stringListVal("a", "b"),
+			}),
+			path: "variables.zones",
+		},
+		"map": {
+			variables: objectVal(map[string]tftypes.Value{
+				"tags": stringMapVal("k", "v"),
+			}),
+			path: "variables.tags",
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			args := map[string]tftypes.Value{
+				"source":    stringVal(""),
+				"dialect":   stringVal("dotenv"),
+				"variables": test.variables,
+			}
+			if test.path != "" {
+				diags := testValidate(t, args)
+				wantDiag(t, diags, tfprotov5.DiagnosticSeverityError, "Invalid variable value", test.path, "dotenv files only support")
+				return
+			}
+			if got := testResult(t, args); got != test.want {
+				t.Errorf("wrong result\ngot:\n%s\nwant:\n%s", got, test.want)
+			}
+		})
+	}
+}
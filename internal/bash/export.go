@@ -0,0 +1,51 @@
+package bash
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/hashicorp/terraform-plugin-go/tfprotov5"
+	"github.com/hashicorp/terraform-plugin-go/tfprotov5/tftypes"
+)
+
+// exportDiags checks for variables that the export argument, or the export
+// setting in the structured form of a variable, can't actually export,
+// returning a warning diagnostic for each one it finds.
+func exportDiags(c *bashScriptConfig) []*tfprotov5.Diagnostic {
+	exported := func(name string) bool {
+		if export, ok := c.ExportNames[name]; ok {
+			return export
+		}
+		return c.Export
+	}
+
+	vars := c.RenderVariables()
+	names := make([]string, 0, len(vars))
+	for name, val := range vars {
+		if isCollection(val) && exported(name) {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	nullDelimited := nameSet(c.ListAsNullDelimited)
+	var diags []*tfprotov5.Diagnostic
+	for _, name := range names {
+		detail := fmt.Sprintf("Bash can't export arrays, so %q won't be available to child processes.", name)
+		if nullDelimited[name] {
+			detail = fmt.Sprintf("The %q variable is declared as a function because of \"list_as_null_delimited\", and functions aren't exported, so it won't be available to child processes.", name)
+		}
+		diags = append(diags, &tfprotov5.Diagnostic{
+			Severity: tfprotov5.DiagnosticSeverityWarning,
+			Summary:  "Variable can't be exported",
+			Detail:   detail,
+			Attribute: &tftypes.AttributePath{
+				Steps: []tftypes.AttributePathStep{
+					tftypes.AttributeName("variables"),
+					tftypes.AttributeName(name),
+				},
+			},
+		})
+	}
+	return diags
+}
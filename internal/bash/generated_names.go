@@ -0,0 +1,57 @@
+package bash
+
+import (
+	"fmt"
+
+	"github.com/hashicorp/terraform-plugin-go/tfprotov5"
+	"github.com/hashicorp/terraform-plugin-go/tfprotov5/tftypes"
+)
+
+// generatedNameDiags checks for variables whose names are the same as one
+// of the companion variables that other arguments generate, such as the
+// "_count" variables of emit_array_counts, returning an error diagnostic
+// for each one it finds.
+//
+// Such a script would declare the same name twice, which fails at runtime
+// when the first declaration is read-only.
+func generatedNameDiags(c *bashScriptConfig) []*tfprotov5.Diagnostic {
+	if c.Dialect != "bash" || c.SingleArray != "" {
+		return nil
+	}
+
+	// generated maps each generated name to a description of why it's
+	// generated, for the error message.
+	generated := make(map[string]string)
+	nullDelimited := nameSet(c.ListAsNullDelimited)
+	for name, val := range c.RenderVariables() {
+		if !isCollection(val) || nullDelimited[name] {
+			continue
+		}
+		isMap := val.Is(mapOfString) || val.Is(mapOfNumber)
+		if isMap && c.MapsAsParallelArrays {
+			generated[name+"_keys"] = fmt.Sprintf("\"maps_as_parallel_arrays\" declares it to hold the keys of %q", name)
+			generated[name+"_values"] = fmt.Sprintf("\"maps_as_parallel_arrays\" declares it to hold the values of %q", name)
+		} else if isMap && c.EmitAssocKeyOrder {
+			generated[name+"_keys"] = fmt.Sprintf("\"emit_assoc_key_order\" declares it to hold the keys of %q", name)
+		}
+		if c.EmitArrayCounts {
+			generated[name+"_count"] = fmt.Sprintf("\"emit_array_counts\" declares it to hold the number of elements in %q", name)
+		}
+	}
+
+	var diags []*tfprotov5.Diagnostic
+	check := func(name string, path *tftypes.AttributePath) {
+		reason, ok := generated[name]
+		if !ok {
+			return
+		}
+		diags = append(diags, &tfprotov5.Diagnostic{
+			Severity:  tfprotov5.DiagnosticSeverityError,
+			Summary:   "Duplicate variable name",
+			Detail:    fmt.Sprintf("Can't declare a variable named %q, because %s.", name, reason),
+			Attribute: path,
+		})
+	}
+	c.forEachVariableName(check)
+	return diags
+}
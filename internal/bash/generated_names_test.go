@@ -0,0 +1,92 @@
+package bash
+
+import (
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-go/tfprotov5"
+	"github.com/hashicorp/terraform-plugin-go/tfprotov5/tftypes"
+)
+
+func TestBashScriptGeneratedNames(t *testing.T) {
+	tests := map[string]struct {
+		args   map[string]tftypes.Value
+		path   string // path of the expected error, if any
+		detail string
+	}{
+		"array count": {
+			args: map[string]tftypes.Value{
+				"emit_array_counts": boolVal(true),
+				"variables": objectVal(map[string]tftypes.Value{
+					"l":       stringListVal("a"),
+					"l_count": numberVal("1"),
+				}),
+			},
+			path:   "variables.l_count",
+			detail: `"emit_array_counts"`,
+		},
+		"array count without emit_array_counts": {
+			args: map[string]tftypes.Value{
+				"variables": objectVal(map[string]tftypes.Value{
+					"l":       stringListVal("a"),
+					"l_count": numberVal("1"),
+				}),
+			},
+		},
+		"assoc key order": {
+			args: map[string]tftypes.Value{
+				"emit_assoc_key_order": boolVal(true),
+				"variables": objectVal(map[string]tftypes.Value{
+					"m":      stringMapVal("k", "v"),
+					"m_keys": stringListVal("k"),
+				}),
+			},
+			path:   "variables.m_keys",
+			detail: `"emit_assoc_key_order"`,
+		},
+		"parallel array values": {
+			args: map[string]tftypes.Value{
+				"maps_as_parallel_arrays": boolVal(true),
+				"variables": objectVal(map[string]tftypes.Value{
+					"m":        stringMapVal("k", "v"),
+					"m_values": stringVal("x"),
+				}),
+			},
+			path:   "variables.m_values",
+			detail: `"maps_as_parallel_arrays"`,
+		},
+		"structured form": {
+			args: map[string]tftypes.Value{
+				"emit_array_counts": boolVal(true),
+				"variables": objectVal(map[string]tftypes.Value{
+					"l": stringListVal("a"),
+					"l_count": objectVal(map[string]tftypes.Value{
+						"value":  numberVal("1"),
+						"export": boolVal(true),
+					}),
+				}),
+			},
+			path:   "variables.l_count",
+			detail: `"emit_array_counts"`,
+		},
+		"raw variable": {
+			args: map[string]tftypes.Value{
+				"emit_array_counts": boolVal(true),
+				"raw_variables":     stringMapVal("l_count", "$(echo 1)"),
+				"variables": objectVal(map[string]tftypes.Value{
+					"l": stringListVal("a"),
+				}),
+			},
+			path:   `raw_variables["l_count"]`,
+			detail: `"emit_array_counts"`,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			args := mergeArgs(map[string]tftypes.Value{
+				"source": stringVal("echo hello\n"),
+			}, test.args)
+			testValidateWant(t, args, tfprotov5.DiagnosticSeverityError, "Duplicate variable name", test.path, test.detail)
+		})
+	}
+}
@@ -0,0 +1,382 @@
+package bash
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"os/exec"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-go/tfprotov5"
+	"github.com/hashicorp/terraform-plugin-go/tfprotov5/tftypes"
+)
+
+// testBashScriptConfig returns a bash_script configuration with the given
+// arguments set, and all of the other arguments null.
+func testBashScriptConfig(t *testing.T, args map[string]tftypes.Value) *tfprotov5.DynamicValue {
+	t.Helper()
+	attrs := make(map[string]tftypes.Value, len(bashScriptType.AttributeTypes))
+	for name, aty := range bashScriptType.AttributeTypes {
+		attrs[name] = tftypes.NewValue(aty, nil)
+	}
+	for name, v := range args {
+		aty, ok := bashScriptType.AttributeTypes[name]
+		if !ok {
+			t.Fatalf("bash_script has no argument named %q", name)
+		}
+		if !aty.Is(tftypes.DynamicPseudoType) && !v.Is(aty) {
+			t.Fatalf("wrong type for argument %q", name)
+		}
+		attrs[name] = v
+	}
+	dv, err := tfprotov5.NewDynamicValue(bashScriptType, tftypes.NewValue(bashScriptType, attrs))
+	if err != nil {
+		t.Fatalf("failed to encode configuration: %s", err)
+	}
+	return &dv
+}
+
+// testValidate calls ValidateDataSourceConfig for a bash_script data source
+// with the given arguments, returning the resulting diagnostics.
+func testValidate(t *testing.T, args map[string]tftypes.Value) []*tfprotov5.Diagnostic {
+	t.Helper()
+	resp, err := NewProvider().ValidateDataSourceConfig(context.Background(), &tfprotov5.ValidateDataSourceConfigRequest{
+		TypeName: "bash_script",
+		Config:   testBashScriptConfig(t, args),
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	return resp.Diagnostics
+}
+
+// testValidateWant calls testValidate with the given arguments and checks
+// that the result includes a diagnostic with the given severity, summary,
+// and path, whose detail contains the given string, as for wantDiag.
+//
+// If path is empty, it instead checks that the result has no diagnostics at
+// least as severe as the given severity. Expecting a warning also always
+// requires that there be no errors.
+func testValidateWant(t *testing.T, args map[string]tftypes.Value, severity tfprotov5.DiagnosticSeverity, summary, path, detail string) {
+	t.Helper()
+	diags := testValidate(t, args)
+	switch {
+	case path == "" && severity == tfprotov5.DiagnosticSeverityError:
+		wantNoErrors(t, diags)
+	case path == "":
+		wantNoDiags(t, diags)
+	default:
+		if severity != tfprotov5.DiagnosticSeverityError {
+			wantNoErrors(t, diags)
+		}
+		wantDiag(t, diags, severity, summary, path, detail)
+	}
+}
+
+// mergeArgs returns a new map of arguments containing those in base,
+// overridden by those in args, so that a table-driven test can give only
+// the arguments that differ from a common base.
+func mergeArgs(base, args map[string]tftypes.Value) map[string]tftypes.Value {
+	ret := make(map[string]tftypes.Value, len(base)+len(args))
+	for k, v := range base {
+		ret[k] = v
+	}
+	for k, v := range args {
+		ret[k] = v
+	}
+	return ret
+}
+
+// testRead calls ReadDataSource for a bash_script data source with the given
+// arguments, returning the attributes of the resulting state, or nil if there
+// is no state, along with the diagnostics.
+func testRead(t *testing.T, args map[string]tftypes.Value) (map[string]tftypes.Value, []*tfprotov5.Diagnostic) {
+	t.Helper()
+	return testReadWithProvider(t, &Provider{}, args)
+}
+
+// testReadWithProvider is like testRead, but uses the given provider, such
+// as one that has been configured.
+func testReadWithProvider(t *testing.T, p *Provider, args map[string]tftypes.Value) (map[string]tftypes.Value, []*tfprotov5.Diagnostic) {
+	t.Helper()
+	resp, err := p.ReadDataSource(context.Background(), &tfprotov5.ReadDataSourceRequest{
+		TypeName: "bash_script",
+		Config:   testBashScriptConfig(t, args),
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if resp.State == nil {
+		return nil, resp.Diagnostics
+	}
+	v, err := resp.State.Unmarshal(bashScriptType)
+	if err != nil {
+		t.Fatalf("failed to decode new state: %s", err)
+	}
+	var attrs map[string]tftypes.Value
+	if err := v.As(&attrs); err != nil {
+		t.Fatalf("failed to decode new state: %s", err)
+	}
+	return attrs, resp.Diagnostics
+}
+
+// testResult calls ReadDataSource for a bash_script data source with the
+// given arguments and returns its result, failing the test if there are any
+// error diagnostics.
+func testResult(t *testing.T, args map[string]tftypes.Value) string {
+	t.Helper()
+	attrs, diags := testRead(t, args)
+	wantNoErrors(t, diags)
+	return testStringAttr(t, attrs, "result")
+}
+
+// testStringAttr returns the value of the string attribute with the given
+// name from the given state attributes, failing the test if it's null or
+// unknown.
+func testStringAttr(t *testing.T, attrs map[string]tftypes.Value, name string) string {
+	t.Helper()
+	v, ok := attrs[name]
+	if !ok {
+		t.Fatalf("no attribute named %q", name)
+	}
+	if !v.IsKnown() || v.IsNull() {
+		t.Fatalf("attribute %q is null or unknown", name)
+	}
+	var s string
+	if err := v.As(&s); err != nil {
+		t.Fatalf("attribute %q isn't a string: %s", name, err)
+	}
+	return s
+}
+
+// wantNoErrors fails the test if any of the given diagnostics are errors.
+func wantNoErrors(t *testing.T, diags []*tfprotov5.Diagnostic) {
+	t.Helper()
+	for _, diag := range diags {
+		if diag.Severity == tfprotov5.DiagnosticSeverityError {
+			t.Fatalf("unexpected error: %s", diagString(diag))
+		}
+	}
+}
+
+// wantNoDiags fails the test if there are any diagnostics at all.
+func wantNoDiags(t *testing.T, diags []*tfprotov5.Diagnostic) {
+	t.Helper()
+	for _, diag := range diags {
+		t.Errorf("unexpected diagnostic: %s", diagString(diag))
+	}
+}
+
+// wantDiag fails the test unless the given diagnostics include one with the
+// given severity and summary whose attribute path, as formatted by
+// pathString, is path. The detail must also contain detail, if it's not
+// empty.
+func wantDiag(t *testing.T, diags []*tfprotov5.Diagnostic, severity tfprotov5.DiagnosticSeverity, summary, path, detail string) {
+	t.Helper()
+	for _, diag := range diags {
+		if diag.Severity == severity && diag.Summary == summary && pathString(diag.Attribute) == path && strings.Contains(diag.Detail, detail) {
+			return
+		}
+	}
+	var got []string
+	for _, diag := range diags {
+		got = append(got, diagString(diag))
+	}
+	t.Fatalf("missing %s diagnostic %q at %q containing %q\ngot:\n%s", severity, summary, path, detail, strings.Join(got, "\n"))
+}
+
+func diagString(diag *tfprotov5.Diagnostic) string {
+	return fmt.Sprintf("%s at %q: %s: %s", diag.Severity, pathString(diag.Attribute), diag.Summary, diag.Detail)
+}
+
+// pathString returns a compact string representation of the given attribute
+// path, like variables.foo[0], for comparisons in tests.
+func pathString(path *tftypes.AttributePath) string {
+	if path == nil {
+		return ""
+	}
+	var buf strings.Builder
+	for _, step := range path.Steps {
+		switch step := step.(type) {
+		case tftypes.AttributeName:
+			if buf.Len() != 0 {
+				buf.WriteString(".")
+			}
+			buf.WriteString(string(step))
+		case tftypes.ElementKeyString:
+			fmt.Fprintf(&buf, "[%q]", string(step))
+		case tftypes.ElementKeyInt:
+			fmt.Fprintf(&buf, "[%d]", int64(step))
+		case tftypes.ElementKeyValue:
+			var s string
+			if err := tftypes.Value(step).As(&s); err == nil {
+				fmt.Fprintf(&buf, "[%q]", s)
+			} else {
+				buf.WriteString("[?]")
+			}
+		}
+	}
+	return buf.String()
+}
+
+// testRunBash runs the given script with Bash, passing any given flags
+// before the script, and returns its output, skipping the test if Bash isn't
+// available.
+func testRunBash(t *testing.T, script string, flags ...string) string {
+	t.Helper()
+	bash, err := exec.LookPath("bash")
+	if err != nil {
+		t.Skip("bash is not available")
+	}
+	out, err := exec.Command(bash, append(flags, "-c", script)...).CombinedOutput()
+	if err != nil {
+		t.Fatalf("bash failed: %s\n%s", err, out)
+	}
+	return string(out)
+}
+
+func stringVal(s string) tftypes.Value {
+	return tftypes.NewValue(tftypes.String, s)
+}
+
+func numberVal(s string) tftypes.Value {
+	f, _, err := big.ParseFloat(s, 10, 512, big.ToNearestEven)
+	if err != nil {
+		panic(err)
+	}
+	return tftypes.NewValue(tftypes.Number, f)
+}
+
+func boolVal(b bool) tftypes.Value {
+	return tftypes.NewValue(tftypes.Bool, b)
+}
+
+func stringListVal(elems ...string) tftypes.Value {
+	vals := make([]tftypes.Value, len(elems))
+	for i, s := range elems {
+		vals[i] = stringVal(s)
+	}
+	return tftypes.NewValue(listOfString, vals)
+}
+
+func numberListVal(elems ...string) tftypes.Value {
+	vals := make([]tftypes.Value, len(elems))
+	for i, s := range elems {
+		vals[i] = numberVal(s)
+	}
+	return tftypes.NewValue(listOfNumber, vals)
+}
+
+func stringSetVal(elems ...string) tftypes.Value {
+	vals := make([]tftypes.Value, len(elems))
+	for i, s := range elems {
+		vals[i] = stringVal(s)
+	}
+	return tftypes.NewValue(setOfString, vals)
+}
+
+// stringMapVal returns a map of strings from alternating keys and values.
+func stringMapVal(kv ...string) tftypes.Value {
+	vals := make(map[string]tftypes.Value, len(kv)/2)
+	for i := 0; i < len(kv); i += 2 {
+		vals[kv[i]] = stringVal(kv[i+1])
+	}
+	return tftypes.NewValue(mapOfString, vals)
+}
+
+// numberMapVal returns a map of numbers from alternating keys and values.
+func numberMapVal(kv ...string) tftypes.Value {
+	vals := make(map[string]tftypes.Value, len(kv)/2)
+	for i := 0; i < len(kv); i += 2 {
+		vals[kv[i]] = numberVal(kv[i+1])
+	}
+	return tftypes.NewValue(mapOfNumber, vals)
+}
+
+// objectVal returns an object with the given attributes, whose types are
+// inferred by testTypeOf.
+func objectVal(attrs map[string]tftypes.Value) tftypes.Value {
+	atys := make(map[string]tftypes.Type, len(attrs))
+	for name, v := range attrs {
+		atys[name] = testTypeOf(v)
+	}
+	return tftypes.NewValue(tftypes.Object{AttributeTypes: atys}, attrs)
+}
+
+// tupleVal returns a tuple of the given elements, whose types are inferred
+// by testTypeOf.
+func tupleVal(elems ...tftypes.Value) tftypes.Value {
+	etys := make([]tftypes.Type, len(elems))
+	for i, v := range elems {
+		etys[i] = testTypeOf(v)
+	}
+	return tftypes.NewValue(tftypes.Tuple{ElementTypes: etys}, elems)
+}
+
+func unknownVal(ty tftypes.Type) tftypes.Value {
+	return tftypes.NewValue(ty, tftypes.UnknownValue)
+}
+
+func nullVal(ty tftypes.Type) tftypes.Value {
+	return tftypes.NewValue(ty, nil)
+}
+
+// testTypeOf returns the type of the given value, which tftypes doesn't
+// expose directly. Values of object and tuple types, and collections of
+// them, must be known and not null, because their types are derived from
+// their contents. Empty collections are assumed to have string elements.
+func testTypeOf(v tftypes.Value) tftypes.Type {
+	for _, ty := range []tftypes.Type{
+		tftypes.String, tftypes.Number, tftypes.Bool,
+		listOfString, listOfNumber, mapOfString, mapOfNumber, setOfString,
+		tftypes.List{ElementType: tftypes.Bool},
+		tftypes.List{ElementType: tftypes.DynamicPseudoType},
+		tftypes.Map{AttributeType: tftypes.DynamicPseudoType},
+	} {
+		if v.Is(ty) {
+			return ty
+		}
+	}
+	switch {
+	case v.Is(tftypes.Object{}):
+		var attrs map[string]tftypes.Value
+		if err := v.As(&attrs); err != nil {
+			panic(fmt.Sprintf("can't infer type of object: %s", err))
+		}
+		atys := make(map[string]tftypes.Type, len(attrs))
+		for name, av := range attrs {
+			atys[name] = testTypeOf(av)
+		}
+		return tftypes.Object{AttributeTypes: atys}
+	case v.Is(tftypes.Tuple{}):
+		var elems []tftypes.Value
+		if err := v.As(&elems); err != nil {
+			panic(fmt.Sprintf("can't infer type of tuple: %s", err))
+		}
+		etys := make([]tftypes.Type, len(elems))
+		for i, ev := range elems {
+			etys[i] = testTypeOf(ev)
+		}
+		return tftypes.Tuple{ElementTypes: etys}
+	case v.Is(tftypes.List{}):
+		var elems []tftypes.Value
+		if err := v.As(&elems); err != nil || len(elems) == 0 {
+			panic("can't infer type of list")
+		}
+		return tftypes.List{ElementType: testTypeOf(elems[0])}
+	case v.Is(tftypes.Map{}):
+		var elems map[string]tftypes.Value
+		if err := v.As(&elems); err != nil || len(elems) == 0 {
+			panic("can't infer type of map")
+		}
+		keys := make([]string, 0, len(elems))
+		for k := range elems {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		return tftypes.Map{AttributeType: testTypeOf(elems[keys[0]])}
+	}
+	panic("can't infer type of value")
+}
@@ -0,0 +1,139 @@
+package bash
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/hashicorp/terraform-plugin-go/tfprotov5"
+	"github.com/hashicorp/terraform-plugin-go/tfprotov5/tftypes"
+)
+
+// internalErrorDiag returns an error diagnostic reporting an unexpected
+// internal error, such as a recovered panic, while handling the given
+// bash_script configuration.
+//
+// The diagnostic includes a summary of the types of the variables in the
+// configuration, but not their values, so that users can include it in
+// bug reports without revealing anything sensitive.
+func internalErrorDiag(problem interface{}, raw *tfprotov5.DynamicValue) *tfprotov5.Diagnostic {
+	detail := fmt.Sprintf("The provider encountered an unexpected error: %v.\n\nThis is a bug in the provider, so please report it.", problem)
+	if summary := variablesSummary(raw); summary != "" {
+		detail += " Including the following summary of the variable types, which doesn't include their values, will help with reproducing the problem:\n\n  variables: " + summary
+	}
+	return &tfprotov5.Diagnostic{
+		Severity: tfprotov5.DiagnosticSeverityError,
+		Summary:  "Internal error in the Bash provider",
+		Detail:   detail,
+	}
+}
+
+// variablesSummary returns a description of the names and types of the
+// variables in the given raw bash_script configuration, like
+// "{ region: string, ports: list(number) }", or the empty string if the
+// configuration can't be decoded.
+func variablesSummary(raw *tfprotov5.DynamicValue) string {
+	if raw == nil {
+		return ""
+	}
+	val, err := raw.Unmarshal(bashScriptType)
+	if err != nil {
+		return ""
+	}
+	var obj map[string]tftypes.Value
+	if err := val.As(&obj); err != nil {
+		return ""
+	}
+	vars := obj["variables"]
+	if !vars.Is(tftypes.Object{}) || !vars.IsKnown() || vars.IsNull() {
+		return typeSummary(vars)
+	}
+	return attributesSummary(vars)
+}
+
+// typeSummary returns a description of the type of the given value, in
+// Terraform's type constraint syntax.
+func typeSummary(v tftypes.Value) string {
+	switch {
+	case v.Is(tftypes.String):
+		return "string"
+	case v.Is(tftypes.Number):
+		return "number"
+	case v.Is(tftypes.Bool):
+		return "bool"
+	case v.Is(tftypes.List{}):
+		return "list(" + elementTypeSummary(v, func(ety tftypes.Type) tftypes.Type { return tftypes.List{ElementType: ety} }) + ")"
+	case v.Is(tftypes.Set{}):
+		return "set(" + elementTypeSummary(v, func(ety tftypes.Type) tftypes.Type { return tftypes.Set{ElementType: ety} }) + ")"
+	case v.Is(tftypes.Map{}):
+		return "map(" + elementTypeSummary(v, func(ety tftypes.Type) tftypes.Type { return tftypes.Map{AttributeType: ety} }) + ")"
+	case v.Is(tftypes.Tuple{}):
+		var elems []tftypes.Value
+		if !v.IsKnown() || v.IsNull() || v.As(&elems) != nil {
+			return "tuple"
+		}
+		parts := make([]string, len(elems))
+		for i, ev := range elems {
+			parts[i] = typeSummary(ev)
+		}
+		return "tuple([" + strings.Join(parts, ", ") + "])"
+	case v.Is(tftypes.Object{}):
+		if !v.IsKnown() || v.IsNull() {
+			return "object"
+		}
+		return "object(" + attributesSummary(v) + ")"
+	default:
+		return "any"
+	}
+}
+
+// attributesSummary returns a description of the names and types of the
+// attributes of the given known, non-null object value.
+func attributesSummary(v tftypes.Value) string {
+	var attrs map[string]tftypes.Value
+	if err := v.As(&attrs); err != nil {
+		return "{}"
+	}
+	names := make([]string, 0, len(attrs))
+	for name := range attrs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	parts := make([]string, len(names))
+	for i, name := range names {
+		parts[i] = name + ": " + typeSummary(attrs[name])
+	}
+	if len(parts) == 0 {
+		return "{}"
+	}
+	return "{ " + strings.Join(parts, ", ") + " }"
+}
+
+// elementTypeSummary returns a description of the element type of the given
+// collection value. collType wraps an element type in the collection's type,
+// so that we can check for primitive element types even when there are no
+// elements to inspect.
+func elementTypeSummary(v tftypes.Value, collType func(tftypes.Type) tftypes.Type) string {
+	for name, ety := range map[string]tftypes.Type{
+		"string": tftypes.String,
+		"number": tftypes.Number,
+		"bool":   tftypes.Bool,
+	} {
+		if v.Is(collType(ety)) {
+			return name
+		}
+	}
+	if v.IsKnown() && !v.IsNull() {
+		var elems []tftypes.Value
+		if err := v.As(&elems); err == nil && len(elems) != 0 {
+			return typeSummary(elems[0])
+		}
+		var attrs map[string]tftypes.Value
+		if err := v.As(&attrs); err == nil {
+			for _, ev := range attrs {
+				return typeSummary(ev)
+			}
+		}
+	}
+	return "any"
+}
@@ -0,0 +1,90 @@
+package bash
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-go/tfprotov5/tftypes"
+)
+
+func TestVariablesSummary(t *testing.T) {
+	tests := map[string]struct {
+		variables tftypes.Value
+		want      string
+	}{
+		"primitives": {
+			variables: objectVal(map[string]tftypes.Value{
+				"region":  stringVal("us-west-2"),
+				"port":    numberVal("8080"),
+				"enabled": boolVal(true),
+			}),
+			want: "{ enabled: bool, port: number, region: string }",
+		},
+		"collections": {
+			variables: objectVal(map[string]tftypes.Value{
+				"ports": numberListVal("80", "443"),
+				"names": stringSetVal("a"),
+				"tags":  stringMapVal("k", "v"),
+			}),
+			want: "{ names: set(string), ports: list(number), tags: map(string) }",
+		},
+		"structural": {
+			variables: objectVal(map[string]tftypes.Value{
+				"t": tupleVal(stringVal("a"), numberVal("1")),
+				"o": objectVal(map[string]tftypes.Value{
+					"k": boolVal(false),
+				}),
+			}),
+			want: "{ o: object({ k: bool }), t: tuple([string, number]) }",
+		},
+		"null and unknown": {
+			variables: objectVal(map[string]tftypes.Value{
+				"n": nullVal(listOfString),
+				"u": unknownVal(tftypes.String),
+			}),
+			want: "{ n: list(string), u: string }",
+		},
+		"empty": {
+			variables: objectVal(map[string]tftypes.Value{}),
+			want:      "{}",
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			got := variablesSummary(testBashScriptConfig(t, map[string]tftypes.Value{
+				"source":    stringVal(""),
+				"variables": test.variables,
+			}))
+			if got != test.want {
+				t.Errorf("wrong result\ngot:  %s\nwant: %s", got, test.want)
+			}
+		})
+	}
+}
+
+func TestInternalErrorDiag(t *testing.T) {
+	diag := internalErrorDiag(errors.New("oops"), testBashScriptConfig(t, map[string]tftypes.Value{
+		"source": stringVal(""),
+		"variables": objectVal(map[string]tftypes.Value{
+			"password": stringVal("hunter2"),
+		}),
+	}))
+	if !strings.Contains(diag.Detail, "oops") {
+		t.Errorf("detail doesn't include the error\n%s", diag.Detail)
+	}
+	if !strings.Contains(diag.Detail, "variables: { password: string }") {
+		t.Errorf("detail doesn't include the variables summary\n%s", diag.Detail)
+	}
+	if strings.Contains(diag.Detail, "hunter2") {
+		t.Errorf("detail reveals a variable value\n%s", diag.Detail)
+	}
+
+	// A configuration we can't decode still produces a diagnostic, just
+	// without the summary.
+	diag = internalErrorDiag("oops", nil)
+	if strings.Contains(diag.Detail, "variables:") {
+		t.Errorf("unexpected variables summary\n%s", diag.Detail)
+	}
+}
@@ -0,0 +1,147 @@
+package bash
+
+import (
+	"fmt"
+	"math/big"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/apparentlymart/terraform-provider-bash/bashgen"
+	"github.com/hashicorp/terraform-plugin-go/tfprotov5/tftypes"
+)
+
+// variablesToMakeDecls is like variablesToBashDecls, but produces GNU make
+// variable assignments instead of Bash declarations, for the "make" dialect.
+//
+// Make has no array types, so lists become space-separated make lists and
+// maps are not supported at all.
+func variablesToMakeDecls(vars map[string]tftypes.Value, sortLists bool) (string, error) {
+	if len(vars) == 0 {
+		return "", nil
+	}
+
+	var buf strings.Builder
+	names := make([]string, 0, len(vars))
+	for name := range vars {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		raw, err := makeValue(vars[name], sortLists)
+		if err != nil {
+			return "", fmt.Errorf("invalid value for %q: %w", name, err)
+		}
+		buf.WriteString(name)
+		buf.WriteString(" := ")
+		buf.WriteString(raw)
+		buf.WriteString("\n")
+	}
+	return buf.String(), nil
+}
+
+// makeValue returns the right-hand side of a make variable assignment that
+// would produce the given value.
+func makeValue(val tftypes.Value, sortLists bool) (string, error) {
+	switch {
+	case val.Is(tftypes.String):
+		var s string
+		val.As(&s)
+		return makeQuoteString(s)
+	case val.Is(tftypes.Number):
+		// Make has no numeric types, so we just need a decimal string.
+		var f big.Float
+		val.As(&f)
+		return bashgen.FormatNumber(&f), nil
+	case val.Is(tftypes.Bool):
+		var b bool
+		val.As(&b)
+		return strconv.FormatBool(b), nil
+	case val.Is(listOfString):
+		var l []tftypes.Value
+		val.As(&l)
+		elems := make([]string, len(l))
+		for i, ev := range l {
+			var es string
+			ev.As(&es)
+			if es == "" || strings.ContainsAny(es, " \t\r\n") {
+				return "", fmt.Errorf("make list elements must be non-empty and must not contain whitespace")
+			}
+			quoted, err := makeQuoteString(es)
+			if err != nil {
+				return "", err
+			}
+			elems[i] = quoted
+		}
+		if sortLists {
+			sort.Strings(elems)
+		}
+		return strings.Join(elems, " "), nil
+	case val.Is(listOfNumber):
+		var l []tftypes.Value
+		val.As(&l)
+		fs := make([]*big.Float, len(l))
+		for i, ev := range l {
+			fs[i] = new(big.Float)
+			ev.As(fs[i])
+		}
+		if sortLists {
+			sort.Slice(fs, func(i, j int) bool {
+				return fs[i].Cmp(fs[j]) < 0
+			})
+		}
+		elems := make([]string, len(fs))
+		for i, f := range fs {
+			elems[i] = bashgen.FormatNumber(f)
+		}
+		return strings.Join(elems, " "), nil
+	default:
+		return "", fmt.Errorf("make only supports strings, numbers, booleans, and lists of strings or numbers")
+	}
+}
+
+// makeQuoteString returns a string that GNU make will interpret as the given
+// literal string when used as the value in an immediate variable assignment.
+//
+// Make variable assignments cannot span multiple lines, so this returns an
+// error if the string contains a newline.
+func makeQuoteString(s string) (string, error) {
+	if strings.ContainsAny(s, "\r\n") {
+		return "", fmt.Errorf("make variables cannot contain line breaks")
+	}
+
+	var buf strings.Builder
+	if strings.HasPrefix(s, " ") || strings.HasPrefix(s, "\t") {
+		// Make discards leading whitespace in the value, so we'll begin
+		// with an empty expansion to make the whitespace significant.
+		buf.WriteString("$()")
+	}
+	backslashes := 0
+	for _, c := range s {
+		switch c {
+		case '\\':
+			backslashes++
+			continue
+		case '#':
+			// A backslash before # escapes it, so any literal backslashes
+			// that precede it must themselves be escaped.
+			buf.WriteString(strings.Repeat(`\`, backslashes*2))
+			buf.WriteString(`\#`)
+		case '$':
+			buf.WriteString(strings.Repeat(`\`, backslashes))
+			buf.WriteString("$$")
+		default:
+			buf.WriteString(strings.Repeat(`\`, backslashes))
+			buf.WriteRune(c)
+		}
+		backslashes = 0
+	}
+	if backslashes != 0 {
+		// A trailing backslash would be interpreted as a line continuation,
+		// so we'll follow it with an empty expansion.
+		buf.WriteString(strings.Repeat(`\`, backslashes))
+		buf.WriteString("$()")
+	}
+	return buf.String(), nil
+}
@@ -0,0 +1,105 @@
+package bash
+
+import (
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-go/tfprotov5"
+	"github.com/hashicorp/terraform-plugin-go/tfprotov5/tftypes"
+)
+
+func TestMakeQuoteString(t *testing.T) {
+	tests := []struct {
+		input   string
+		want    string
+		wantErr bool
+	}{
+		{``, ``, false},
+		{`hello`, `hello`, false},
+		{`hello world`, `hello world`, false},
+		{`$HOME`, `$$HOME`, false},
+		{`a#b`, `a\#b`, false},
+		{`a\#b`, `a\\\#b`, false},
+		{`a\b`, `a\b`, false},
+		{`trailing\`, `trailing\$()`, false},
+		{`  indented`, `$()  indented`, false},
+		{"two\nlines", ``, true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.input, func(t *testing.T) {
+			got, err := makeQuoteString(test.input)
+			if test.wantErr {
+				if err == nil {
+					t.Fatalf("unexpected success for %q: got %q", test.input, got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error for %q: %s", test.input, err)
+			}
+			if got != test.want {
+				t.Errorf("wrong result for %q\ngot:  %s\nwant: %s", test.input, got, test.want)
+			}
+		})
+	}
+}
+
+func TestBashScriptMakeDialect(t *testing.T) {
+	got := testResult(t, map[string]tftypes.Value{
+		"source":  stringVal(""),
+		"dialect": stringVal("make"),
+		"variables": objectVal(map[string]tftypes.Value{
+			"cost":    stringVal("$5 # approx"),
+			"enabled": boolVal(true),
+			"hosts":   stringListVal("b.example.com", "a.example.com"),
+			"ports":   numberListVal("443", "80"),
+			"port":    numberVal("8080"),
+		}),
+	})
+	want := `cost := $$5 \# approx
+enabled := true
+hosts := b.example.com a.example.com
+port := 8080
+ports := 443 80
+`
+	if got != want {
+		t.Errorf("wrong result\ngot:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestBashScriptMakeDialectInvalid(t *testing.T) {
+	tests := map[string]struct {
+		value  tftypes.Value
+		detail string
+	}{
+		"map": {
+			value:  stringMapVal("k", "v"),
+			detail: "make only supports strings, numbers, booleans, and lists",
+		},
+		"list element with a space": {
+			value:  stringListVal("a b"),
+			detail: "make list elements must be non-empty and must not contain whitespace",
+		},
+		"empty list element": {
+			value:  stringListVal(""),
+			detail: "make list elements must be non-empty and must not contain whitespace",
+		},
+		"line break": {
+			value:  stringVal("a\nb"),
+			detail: "make variables cannot contain line breaks",
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			diags := testValidate(t, map[string]tftypes.Value{
+				"source":  stringVal(""),
+				"dialect": stringVal("make"),
+				"variables": objectVal(map[string]tftypes.Value{
+					"x": test.value,
+				}),
+			})
+			wantDiag(t, diags, tfprotov5.DiagnosticSeverityError, "Invalid variable value", "variables.x", test.detail)
+		})
+	}
+}
@@ -0,0 +1,185 @@
+package bash
+
+import (
+	"fmt"
+
+	"github.com/hashicorp/terraform-plugin-go/tfprotov5"
+	"github.com/hashicorp/terraform-plugin-go/tfprotov5/tftypes"
+)
+
+// optionConflictDiags checks for combinations of arguments in the given
+// configuration which don't make sense together, returning an error
+// diagnostic for each one it finds.
+//
+// The individual arguments should already have been validated in isolation
+// before calling this function, so that these diagnostics can focus only on
+// the interactions between arguments.
+func optionConflictDiags(c *bashScriptConfig) []*tfprotov5.Diagnostic {
+	var diags []*tfprotov5.Diagnostic
+	conflict := func(attrName, detail string) {
+		diags = append(diags, &tfprotov5.Diagnostic{
+			Severity: tfprotov5.DiagnosticSeverityError,
+			Summary:  "Conflicting options",
+			Detail:   detail,
+			Attribute: &tftypes.AttributePath{
+				Steps: []tftypes.AttributePathStep{
+					tftypes.AttributeName(attrName),
+				},
+			},
+		})
+	}
+
+	// Arguments whose values are not yet known are assumed to be valid here,
+	// because we'll check again once they are known.
+	if c.isSet("comment_prefix") && c.isKnown("markers") && !c.Markers {
+		conflict("comment_prefix", "The \"comment_prefix\" argument only applies when \"markers\" is enabled.")
+	}
+	if c.isSet("single_array_collections") && c.isKnown("single_array") && c.SingleArray == "" {
+		conflict("single_array_collections", "The \"single_array_collections\" argument only applies when \"single_array\" is set.")
+	}
+	if c.isSet("sort_assoc_by") && c.isKnown("emit_assoc_key_order") && !c.EmitAssocKeyOrder {
+		conflict("sort_assoc_by", "The \"sort_assoc_by\" argument only applies when \"emit_assoc_key_order\" is enabled.")
+	}
+	if c.isSet("bool_tokens") && c.isSet("bool_format") {
+		conflict("bool_tokens", "The \"bool_tokens\" argument cannot be used with \"bool_format\", because both select how to represent booleans.")
+	}
+	if c.isSet("null_collection_mode") && c.isKnown("omit_null") && c.OmitNull && c.NullCollectionMode != "omit" {
+		conflict("null_collection_mode", "The \"null_collection_mode\" argument has no effect when \"omit_null\" is enabled, because all null variables are then omitted.")
+	}
+	if c.isSet("shebang") && c.isKnown("dialect") && c.Dialect == "dotenv" {
+		conflict("shebang", "The \"shebang\" argument is not supported for the \"dotenv\" dialect, because dotenv files are not executable.")
+	}
+	if c.isSet("shebang_default") && c.isKnown("dialect") && c.Dialect == "dotenv" {
+		conflict("shebang_default", "The \"shebang_default\" argument is not supported for the \"dotenv\" dialect, because dotenv files are not executable.")
+	}
+	if c.isSet("shebang_default") && c.isKnown("shebang_default") && c.ShebangDefault && c.isSet("shebang") {
+		conflict("shebang_default", "The \"shebang_default\" argument cannot be used with \"shebang\", because both select the interpreter line.")
+	}
+	if c.isSet("replace_source_shebang") && c.isKnown("shebang") && c.isKnown("shebang_default") && c.Shebang == "" {
+		conflict("replace_source_shebang", "The \"replace_source_shebang\" argument only applies when \"shebang\" or \"shebang_default\" is set.")
+	}
+	if c.isSet("env_override_prefix") && c.SingleArray != "" {
+		conflict("env_override_prefix", "The \"env_override_prefix\" argument cannot be used with \"single_array\", because the single array is declared all at once.")
+	}
+	if c.isSet("readonly_names") && c.isKnown("readonly") && c.ReadOnly {
+		conflict("readonly_names", "The \"readonly_names\" argument only applies when \"readonly\" is false, because otherwise all of the variables are already read-only.")
+	}
+	if c.isSet("readonly_names") && c.SingleArray != "" {
+		conflict("readonly_names", "The \"readonly_names\" argument cannot be used with \"single_array\", because the variables are not declared individually.")
+	}
+	if c.isSet("trace_names") && c.SingleArray != "" {
+		conflict("trace_names", "The \"trace_names\" argument cannot be used with \"single_array\", because the variables are not declared individually.")
+	}
+	if c.isSet("expect") && c.SingleArray != "" {
+		conflict("expect", "The \"expect\" argument cannot be used with \"single_array\", because the variables are not declared individually.")
+	}
+	if c.isSet("empty_string_mode") && c.SingleArray != "" {
+		conflict("empty_string_mode", "The \"empty_string_mode\" argument cannot be used with \"single_array\", because the variables are not declared individually.")
+	}
+	if c.isSet("version_fallback") && c.SingleArray != "" {
+		conflict("version_fallback", "The \"version_fallback\" argument cannot be used with \"single_array\", because the single array has no Bash 3 fallback.")
+	}
+	if c.isSet("version_fallback") && c.DeclareScope == "global_explicit" {
+		conflict("version_fallback", "The \"version_fallback\" argument cannot be used with declare_scope = \"global_explicit\", because Bash 3 doesn't support global declarations.")
+	}
+	if c.isSet("name_prefix") && c.SingleArray != "" {
+		conflict("name_prefix", "The \"name_prefix\" argument cannot be used with \"single_array\", because the variables are not declared individually.")
+	}
+	if c.isSet("annotate_types") && c.SingleArray != "" {
+		conflict("annotate_types", "The \"annotate_types\" argument cannot be used with \"single_array\", because the variables are not declared individually.")
+	}
+	if c.isSet("emit_array_counts") && c.SingleArray != "" {
+		conflict("emit_array_counts", "The \"emit_array_counts\" argument cannot be used with \"single_array\", because the variables are not declared individually.")
+	}
+	if c.isSet("maps_as_parallel_arrays") && c.MapsAsParallelArrays {
+		switch {
+		case c.SingleArray != "":
+			conflict("maps_as_parallel_arrays", "The \"maps_as_parallel_arrays\" argument cannot be used with \"single_array\", because the variables are not declared individually.")
+		case c.VersionFallback:
+			conflict("maps_as_parallel_arrays", "The \"maps_as_parallel_arrays\" argument cannot be used with \"version_fallback\", because both select how to declare maps for Bash 3.")
+		case c.EmitAssocKeyOrder:
+			conflict("maps_as_parallel_arrays", "The \"maps_as_parallel_arrays\" argument cannot be used with \"emit_assoc_key_order\", because the parallel arrays already include a \"_keys\" array.")
+		}
+	}
+	if c.isSet("global_names") && c.SingleArray != "" {
+		conflict("global_names", "The \"global_names\" argument cannot be used with \"single_array\", because the variables are not declared individually.")
+	}
+	if c.isSet("global_names") && c.VersionFallback {
+		conflict("global_names", "The \"global_names\" argument cannot be used with \"version_fallback\", because Bash 3 doesn't support global declarations.")
+	}
+	if len(c.ReadOnlyNames) != 0 || len(c.ExportNames) != 0 {
+		switch {
+		case c.SingleArray != "":
+			conflict("variables", "The \"readonly\" and \"export\" settings of individual variables cannot be used with \"single_array\", because the variables are not declared individually.")
+		case c.isKnown("dialect") && c.Dialect != "bash":
+			conflict("variables", fmt.Sprintf("The \"readonly\" and \"export\" settings of individual variables are not supported for the %q dialect.", c.Dialect))
+		}
+	}
+	if c.isKnown("dialect") && c.Dialect != "bash" {
+		bashOnly := []string{
+			"declare_scope",
+			"readonly",
+			"readonly_names",
+			"export",
+			"global_names",
+			"unexport_names",
+			"raw_variables",
+			"name_prefix",
+			"single_array",
+			"exit_trap",
+			"source_ifs",
+			"hermetic_preamble",
+			"strict_mode",
+			"required_commands",
+			"max_line_length",
+			"indent",
+			"version_fallback",
+			"maps_as_parallel_arrays",
+			"split_array_declaration",
+			"list_as_words",
+			"shellcheck_directives",
+			"restricted_safe",
+			"posix_bash_safe",
+			"list_as_null_delimited",
+			"function_name",
+			"emit_assoc_key_order",
+			"emit_array_counts",
+			"annotate_types",
+			"env_override_prefix",
+			"trace_names",
+			"empty_string_mode",
+		}
+		for _, attrName := range bashOnly {
+			if c.isSet(attrName) {
+				conflict(attrName, fmt.Sprintf("The %q argument is not supported for the %q dialect.", attrName, c.Dialect))
+			}
+		}
+	}
+
+	return diags
+}
+
+// isSet returns true if the argument with the given name was set to a
+// non-null value in the configuration.
+func (c *bashScriptConfig) isSet(attrName string) bool {
+	v, ok := c.config[attrName]
+	return ok && !v.IsNull()
+}
+
+// isFullyKnown returns true if all of the arguments in the configuration
+// have known values, including any nested values.
+func (c *bashScriptConfig) isFullyKnown() bool {
+	for _, v := range c.config {
+		if !fullyKnown(v) {
+			return false
+		}
+	}
+	return true
+}
+
+// isKnown returns true if the argument with the given name has a known
+// value in the configuration, which might be null.
+func (c *bashScriptConfig) isKnown(attrName string) bool {
+	v, ok := c.config[attrName]
+	return ok && v.IsKnown()
+}
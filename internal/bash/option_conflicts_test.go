@@ -0,0 +1,106 @@
+package bash
+
+import (
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-go/tfprotov5"
+	"github.com/hashicorp/terraform-plugin-go/tfprotov5/tftypes"
+)
+
+func TestOptionConflicts(t *testing.T) {
+	oneMap := objectVal(map[string]tftypes.Value{
+		"m": stringMapVal("k", "v"),
+	})
+
+	tests := map[string]struct {
+		args map[string]tftypes.Value
+		path string
+	}{
+		"comment_prefix without markers": {
+			args: map[string]tftypes.Value{
+				"comment_prefix": stringVal("example"),
+			},
+			path: "comment_prefix",
+		},
+		"sort_assoc_by without emit_assoc_key_order": {
+			args: map[string]tftypes.Value{
+				"sort_assoc_by": stringVal("value"),
+			},
+			path: "sort_assoc_by",
+		},
+		"bool_tokens with bool_format": {
+			args: map[string]tftypes.Value{
+				"bool_tokens": stringMapVal("true", "yes", "false", "no"),
+				"bool_format": stringVal("integer"),
+			},
+			path: "bool_tokens",
+		},
+		"env_override_prefix with single_array": {
+			args: map[string]tftypes.Value{
+				"single_array":        stringVal("VARS"),
+				"env_override_prefix": stringVal("TF_"),
+			},
+			path: "env_override_prefix",
+		},
+		"trace_names with single_array": {
+			args: map[string]tftypes.Value{
+				"single_array": stringVal("VARS"),
+				"trace_names":  stringSetVal("a"),
+				"variables": objectVal(map[string]tftypes.Value{
+					"a": stringVal("x"),
+				}),
+			},
+			path: "trace_names",
+		},
+		"version_fallback with global_explicit": {
+			args: map[string]tftypes.Value{
+				"version_fallback": boolVal(true),
+				"declare_scope":    stringVal("global_explicit"),
+			},
+			path: "version_fallback",
+		},
+		"maps_as_parallel_arrays with version_fallback": {
+			args: map[string]tftypes.Value{
+				"maps_as_parallel_arrays": boolVal(true),
+				"version_fallback":        boolVal(true),
+				"variables":               oneMap,
+			},
+			path: "maps_as_parallel_arrays",
+		},
+		"maps_as_parallel_arrays with emit_assoc_key_order": {
+			args: map[string]tftypes.Value{
+				"maps_as_parallel_arrays": boolVal(true),
+				"emit_assoc_key_order":    boolVal(true),
+				"variables":               oneMap,
+			},
+			path: "maps_as_parallel_arrays",
+		},
+		"bash-only argument with another dialect": {
+			args: map[string]tftypes.Value{
+				"dialect":     stringVal("make"),
+				"strict_mode": boolVal(true),
+			},
+			path: "strict_mode",
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			args := mergeArgs(map[string]tftypes.Value{
+				"source": stringVal("echo hello\n"),
+			}, test.args)
+			testValidateWant(t, args, tfprotov5.DiagnosticSeverityError, "Conflicting options", test.path, "")
+		})
+	}
+}
+
+func TestOptionConflictsUnknown(t *testing.T) {
+	// An argument whose value isn't known yet can't conflict, because we
+	// don't know whether it'll be set.
+	diags := testValidate(t, map[string]tftypes.Value{
+		"source":         stringVal("echo hello\n"),
+		"markers":        unknownVal(tftypes.Bool),
+		"comment_prefix": stringVal("example"),
+	})
+	wantNoErrors(t, diags)
+}
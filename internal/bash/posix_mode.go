@@ -0,0 +1,63 @@
+package bash
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/hashicorp/terraform-plugin-go/tfprotov5"
+	"github.com/hashicorp/terraform-plugin-go/tfprotov5/tftypes"
+)
+
+// posixSpecialBuiltins are the names of the POSIX special builtins that
+// are also valid Bash variable names. Bash in POSIX mode (set -o posix)
+// doesn't allow declaring functions with these names.
+var posixSpecialBuiltins = map[string]bool{
+	"break":    true,
+	"continue": true,
+	"eval":     true,
+	"exec":     true,
+	"exit":     true,
+	"export":   true,
+	"readonly": true,
+	"return":   true,
+	"set":      true,
+	"shift":    true,
+	"source":   true,
+	"times":    true,
+	"trap":     true,
+	"unset":    true,
+}
+
+// posixModeDiags checks whether the given configuration would generate
+// declarations that fail when Bash is running in POSIX mode, returning a
+// warning diagnostic for each problem it finds.
+//
+// This is only relevant when the posix_bash_safe argument is enabled, so
+// the result is always empty otherwise.
+func posixModeDiags(c *bashScriptConfig) []*tfprotov5.Diagnostic {
+	if !c.PosixBashSafe {
+		return nil
+	}
+
+	var diags []*tfprotov5.Diagnostic
+	names := append([]string(nil), c.ListAsNullDelimited...)
+	sort.Strings(names)
+	for _, name := range names {
+		declName := c.NamePrefix + name
+		if !posixSpecialBuiltins[declName] {
+			continue
+		}
+		diags = append(diags, &tfprotov5.Diagnostic{
+			Severity: tfprotov5.DiagnosticSeverityWarning,
+			Summary:  "Not compatible with Bash POSIX mode",
+			Detail:   fmt.Sprintf("The %q variable is declared as a function because of \"list_as_null_delimited\", but Bash in POSIX mode doesn't allow declaring a function with the same name as the special builtin %q. The script will fail if run with set -o posix.", name, declName),
+			Attribute: &tftypes.AttributePath{
+				Steps: []tftypes.AttributePathStep{
+					tftypes.AttributeName("list_as_null_delimited"),
+					tftypes.ElementKeyValue(tftypes.NewValue(tftypes.String, name)),
+				},
+			},
+		})
+	}
+	return diags
+}
@@ -0,0 +1,88 @@
+package bash
+
+import (
+	"os/exec"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-go/tfprotov5"
+	"github.com/hashicorp/terraform-plugin-go/tfprotov5/tftypes"
+)
+
+func TestPosixModeDiags(t *testing.T) {
+	tests := map[string]struct {
+		args map[string]tftypes.Value
+		path string // if empty, no warnings are expected
+	}{
+		"special builtin": {
+			args: map[string]tftypes.Value{
+				"list_as_null_delimited": stringSetVal("exec"),
+			},
+			path: `list_as_null_delimited["exec"]`,
+		},
+		"special builtin with name_prefix": {
+			args: map[string]tftypes.Value{
+				"name_prefix":            stringVal("ex"),
+				"list_as_null_delimited": stringSetVal("it"),
+			},
+			path: `list_as_null_delimited["it"]`,
+		},
+		"special builtin prefixed": {
+			args: map[string]tftypes.Value{
+				"name_prefix":            stringVal("my_"),
+				"list_as_null_delimited": stringSetVal("exec"),
+			},
+		},
+		"other function": {
+			args: map[string]tftypes.Value{
+				"list_as_null_delimited": stringSetVal("files"),
+			},
+		},
+		"array": {
+			args: map[string]tftypes.Value{},
+		},
+		"disabled": {
+			args: map[string]tftypes.Value{
+				"posix_bash_safe":        boolVal(false),
+				"list_as_null_delimited": stringSetVal("exec"),
+			},
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			args := mergeArgs(map[string]tftypes.Value{
+				"source":          stringVal("echo hello\n"),
+				"posix_bash_safe": boolVal(true),
+				"variables": objectVal(map[string]tftypes.Value{
+					"exec":  stringListVal("a"),
+					"it":    stringListVal("a"),
+					"files": stringListVal("a"),
+				}),
+			}, test.args)
+			testValidateWant(t, args, tfprotov5.DiagnosticSeverityWarning, "Not compatible with Bash POSIX mode", test.path, "")
+		})
+	}
+}
+
+func TestPosixModeRun(t *testing.T) {
+	bash, err := exec.LookPath("bash")
+	if err != nil {
+		t.Skip("bash is not available")
+	}
+
+	// The declarations we warn about work in ordinary Bash, but not in
+	// POSIX mode.
+	script := testResult(t, map[string]tftypes.Value{
+		"source":                 stringVal("echo ok\n"),
+		"list_as_null_delimited": stringSetVal("exec"),
+		"variables": objectVal(map[string]tftypes.Value{
+			"exec": stringListVal("a"),
+		}),
+	})
+	if out, err := exec.Command(bash, "-c", script).CombinedOutput(); err != nil {
+		t.Errorf("failed in ordinary mode: %s\n%s", err, out)
+	}
+	if out, err := exec.Command(bash, "--posix", "-c", script).CombinedOutput(); err == nil {
+		t.Errorf("unexpected success in POSIX mode\n%s", out)
+	}
+}
@@ -3,13 +3,15 @@ package bash
 import (
 	"context"
 	"fmt"
-	"strings"
 
 	"github.com/hashicorp/terraform-plugin-go/tfprotov5"
 	"github.com/hashicorp/terraform-plugin-go/tfprotov5/tftypes"
 )
 
 type Provider struct {
+	// minBashVersion is the default for the min_bash_version argument of
+	// bash_script, from the provider configuration.
+	minBashVersion string
 }
 
 func NewProvider() tfprotov5.ProviderServer {
@@ -19,7 +21,17 @@ func NewProvider() tfprotov5.ProviderServer {
 func (p *Provider) GetProviderSchema(ctx context.Context, req *tfprotov5.GetProviderSchemaRequest) (*tfprotov5.GetProviderSchemaResponse, error) {
 	return &tfprotov5.GetProviderSchemaResponse{
 		Provider: &tfprotov5.Schema{
-			Block: &tfprotov5.SchemaBlock{},
+			Block: &tfprotov5.SchemaBlock{
+				Attributes: []*tfprotov5.SchemaAttribute{
+					{
+						Name:            "min_bash_version",
+						Type:            tftypes.String,
+						Optional:        true,
+						Description:     "The earliest Bash version, like `\"3.2\"`, that all `bash_script` data sources must support unless they set their own `min_bash_version`.",
+						DescriptionKind: tfprotov5.StringKindMarkdown,
+					},
+				},
+			},
 		},
 		DataSourceSchemas: map[string]*tfprotov5.Schema{
 			"bash_script": {
@@ -36,7 +48,406 @@ func (p *Provider) GetProviderSchema(ctx context.Context, req *tfprotov5.GetProv
 							Name:            "variables",
 							Type:            tftypes.DynamicPseudoType,
 							Optional:        true,
-							Description:     "An object describing the variables to present to the script, where each attribute translates to one bash variable.",
+							Description:     "An object or map describing the variables to present to the script, where each attribute or element translates to one bash variable. Can also be a list of such objects or maps, which are merged in order with later objects taking precedence. Each variable can be given as an object with a `value` attribute and optional `readonly` and `export` attributes to override those arguments for just that variable.",
+							DescriptionKind: tfprotov5.StringKindMarkdown,
+						},
+						{
+							Name:            "raw_variables",
+							Type:            tftypes.Map{AttributeType: tftypes.String},
+							Optional:        true,
+							Description:     "A map of additional variables whose values are written verbatim into their declarations, without any quoting, so that they can use Bash syntax such as command substitutions. The names must not also appear in `variables`.",
+							DescriptionKind: tfprotov5.StringKindMarkdown,
+						},
+						{
+							Name:            "name_prefix",
+							Type:            tftypes.String,
+							Optional:        true,
+							Description:     "A prefix to add to the name of each declared variable, like `tf_`, to avoid collisions with variables that the script declares itself. The names in `variables` and the other arguments that refer to variables are given without the prefix.",
+							DescriptionKind: tfprotov5.StringKindMarkdown,
+						},
+						{
+							Name:            "reject_reserved_words",
+							Type:            tftypes.Bool,
+							Optional:        true,
+							Description:     "If set, variable names that are Bash reserved words, like `if` or `function`, are rejected. Bash accepts declarations of those names, but they are confusing to read in a script.",
+							DescriptionKind: tfprotov5.StringKindMarkdown,
+						},
+						{
+							Name:            "declare_scope",
+							Type:            tftypes.String,
+							Optional:        true,
+							Description:     "Selects how the generated declarations are scoped. The default, `\"default\"`, uses plain `declare`, which creates local variables when evaluated inside a function. `\"global_explicit\"` adds the `-g` flag so that the variables are global even when declared inside a function.",
+							DescriptionKind: tfprotov5.StringKindMarkdown,
+						},
+						{
+							Name:            "readonly",
+							Type:            tftypes.Bool,
+							Optional:        true,
+							Description:     "If false, the variables are declared without the read-only attribute, so that the script can assign new values to them. Defaults to true.",
+							DescriptionKind: tfprotov5.StringKindMarkdown,
+						},
+						{
+							Name:            "readonly_names",
+							Type:            tftypes.Set{ElementType: tftypes.String},
+							Optional:        true,
+							Description:     "A set of names of variables to declare with the read-only attribute even though `readonly` is false, for when only some of the variables should be mutable.",
+							DescriptionKind: tfprotov5.StringKindMarkdown,
+						},
+						{
+							Name:            "export",
+							Type:            tftypes.Bool,
+							Optional:        true,
+							Description:     "If true, the variables are declared with the `-x` flag so that they are exported to child processes. Bash can't export arrays, so list and map variables are not exported.",
+							DescriptionKind: tfprotov5.StringKindMarkdown,
+						},
+						{
+							Name:            "sort_lists",
+							Type:            tftypes.Bool,
+							Optional:        true,
+							Description:     "If true, the elements of list variables are sorted lexically before generating the array declarations. Defaults to false, because list order is usually significant.",
+							DescriptionKind: tfprotov5.StringKindMarkdown,
+						},
+						{
+							Name:            "markers",
+							Type:            tftypes.Bool,
+							Optional:        true,
+							Description:     "If true, the generated declarations are wrapped in `# BEGIN` and `# END` comments so that tools and humans can find the generated block within a larger script.",
+							DescriptionKind: tfprotov5.StringKindMarkdown,
+						},
+						{
+							Name:            "comment_prefix",
+							Type:            tftypes.String,
+							Optional:        true,
+							Description:     "The marker text to use in the comments generated when `markers` is enabled. Defaults to `terraform-provider-bash`.",
+							DescriptionKind: tfprotov5.StringKindMarkdown,
+						},
+						{
+							Name:            "unexport_names",
+							Type:            tftypes.Set{ElementType: tftypes.String},
+							Optional:        true,
+							Description:     "A set of variable names to explicitly un-export using `export -n` before the declarations, so that they won't be passed on to child processes even if they were inherited from the environment.",
+							DescriptionKind: tfprotov5.StringKindMarkdown,
+						},
+						{
+							Name:            "single_array",
+							Type:            tftypes.String,
+							Optional:        true,
+							Description:     "If set, all of the variables are declared as elements of a single associative array with this name, keyed by the variable names, instead of as separate variables. Requires Bash 4.",
+							DescriptionKind: tfprotov5.StringKindMarkdown,
+						},
+						{
+							Name:            "single_array_collections",
+							Type:            tftypes.String,
+							Optional:        true,
+							Description:     "Selects how list and map variables are handled when `single_array` is set, since Bash arrays cannot be nested. `\"error\"`, the default, rejects them. `\"json\"` includes them as JSON-encoded strings.",
+							DescriptionKind: tfprotov5.StringKindMarkdown,
+						},
+						{
+							Name:            "convert_windows_paths",
+							Type:            tftypes.Set{ElementType: tftypes.String},
+							Optional:        true,
+							Description:     "A set of names of string variables whose values are Windows-style paths, like `C:\\Users\\example`, to be converted to the MSYS form, like `/c/Users/example`, for use under Git Bash and similar environments.",
+							DescriptionKind: tfprotov5.StringKindMarkdown,
+						},
+						{
+							Name:            "exit_trap",
+							Type:            tftypes.List{ElementType: tftypes.String},
+							Optional:        true,
+							Description:     "A list of commands to run when the script exits, which will be combined into a single `trap` command for the `EXIT` condition at the start of the script.",
+							DescriptionKind: tfprotov5.StringKindMarkdown,
+						},
+						{
+							Name:            "emit_assoc_key_order",
+							Type:            tftypes.Bool,
+							Optional:        true,
+							Description:     "If true, each associative array is accompanied by an indexed array with the suffix `_keys` which lists its keys in a predictable order, since Bash doesn't preserve the order of associative array elements.",
+							DescriptionKind: tfprotov5.StringKindMarkdown,
+						},
+						{
+							Name:            "sort_assoc_by",
+							Type:            tftypes.String,
+							Optional:        true,
+							Description:     "Selects the order of the keys in the arrays generated by `emit_assoc_key_order`: either `\"key\"`, the default, or `\"value\"` to order by the associated values.",
+							DescriptionKind: tfprotov5.StringKindMarkdown,
+						},
+						{
+							Name:            "emit_array_counts",
+							Type:            tftypes.Bool,
+							Optional:        true,
+							Description:     "If true, each array is accompanied by an integer variable with the suffix `_count` giving its number of elements.",
+							DescriptionKind: tfprotov5.StringKindMarkdown,
+						},
+						{
+							Name:            "annotate_types",
+							Type:            tftypes.Bool,
+							Optional:        true,
+							Description:     "If true, each declaration is preceded by a comment giving the Terraform type of the variable, like `# list(string)`.",
+							DescriptionKind: tfprotov5.StringKindMarkdown,
+						},
+						{
+							Name:            "env_override_prefix",
+							Type:            tftypes.String,
+							Optional:        true,
+							Description:     "If set, string variables can be overridden at runtime by setting an environment variable whose name is this prefix followed by the variable name, with the value from `variables` used only as a default.",
+							DescriptionKind: tfprotov5.StringKindMarkdown,
+						},
+						{
+							Name:            "trace_names",
+							Type:            tftypes.Set{ElementType: tftypes.String},
+							Optional:        true,
+							Description:     "A set of variable names to declare with the trace attribute (`declare -t`), which can be useful when debugging the generated script with a `DEBUG` trap.",
+							DescriptionKind: tfprotov5.StringKindMarkdown,
+						},
+						{
+							Name:            "dialect",
+							Type:            tftypes.String,
+							Optional:        true,
+							Description:     "Selects the language of the generated declarations. `\"bash\"`, the default, generates Bash `declare` commands. `\"make\"` instead generates GNU make immediate variable assignments, for use with a makefile given in `source`, `\"sh\"` generates plain POSIX shell variable assignments, for scripts run by `/bin/sh`, and `\"dotenv\"` generates `NAME=value` lines in the format of `env_result`.",
+							DescriptionKind: tfprotov5.StringKindMarkdown,
+						},
+						{
+							Name:            "expect",
+							Type:            tftypes.Map{AttributeType: tftypes.String},
+							Optional:        true,
+							Description:     "A map from variable names to the literal value each variable's declaration is expected to assign, such as `'hello'` for a string or `('a' 'b')` for a list. Terraform will raise an error if the generated declarations don't match, which allows pinning the rendering of important values.",
+							DescriptionKind: tfprotov5.StringKindMarkdown,
+						},
+						{
+							Name:            "generated_header",
+							Type:            tftypes.Bool,
+							Optional:        true,
+							Description:     "If true, the script begins with the comment `# Generated by terraform-provider-bash; do not edit`, after the interpreter line if there is one, to discourage editing the generated script by hand.",
+							DescriptionKind: tfprotov5.StringKindMarkdown,
+						},
+						{
+							Name:            "embed_checksum",
+							Type:            tftypes.Bool,
+							Optional:        true,
+							Description:     "If true, a comment containing the SHA-256 checksum of the rest of the script is appended as its final line, so that a later verification step can detect whether the script was modified.",
+							DescriptionKind: tfprotov5.StringKindMarkdown,
+						},
+						{
+							Name:            "trailing_newline",
+							Type:            tftypes.String,
+							Optional:        true,
+							Description:     "How to handle newlines at the end of the result: `\"preserve\"` (the default) leaves them as they are in `source`, `\"ensure\"` makes the result end with exactly one newline, and `\"strip\"` removes all trailing newlines.",
+							DescriptionKind: tfprotov5.StringKindMarkdown,
+						},
+						{
+							Name:            "line_endings",
+							Type:            tftypes.String,
+							Optional:        true,
+							Description:     "Selects the line endings of the result. `\"lf\"`, the default, converts all line endings to bare newlines, and `\"crlf\"` converts them all to carriage return and newline pairs, for running the script under Git Bash on Windows.",
+							DescriptionKind: tfprotov5.StringKindMarkdown,
+						},
+						{
+							Name:            "empty_string_mode",
+							Type:            tftypes.String,
+							Optional:        true,
+							Description:     "Selects how string variables set to the empty string are declared. `\"declare_empty\"`, the default, assigns the empty string. `\"skip\"` omits the declaration entirely. `\"declare_null\"` declares the variable without assigning a value, so that it is read-only but unset. This affects whether `[[ -v name ]]` is true for the variable.",
+							DescriptionKind: tfprotov5.StringKindMarkdown,
+						},
+						{
+							Name:            "preview",
+							Type:            tftypes.Bool,
+							Optional:        true,
+							Description:     "If true, Terraform will show the generated script as a warning during validation, such as when running `terraform validate`, as long as all of the arguments are known at that point. This is intended only for use while developing a configuration.",
+							DescriptionKind: tfprotov5.StringKindMarkdown,
+						},
+						{
+							Name:            "numeric_names",
+							Type:            tftypes.Set{ElementType: tftypes.String},
+							Optional:        true,
+							Description:     "A set of names of string variables whose values are whole numbers in decimal notation, like `\"8080\"`, to be declared as integer variables instead of strings.",
+							DescriptionKind: tfprotov5.StringKindMarkdown,
+						},
+						{
+							Name:            "source_ifs",
+							Type:            tftypes.String,
+							Optional:        true,
+							Description:     "If set, the script given in `source` runs with `IFS` set to this value, and the previous value of `IFS` is restored afterwards. This is useful for scripts that split strings or expand the generated arrays using a particular separator.",
+							DescriptionKind: tfprotov5.StringKindMarkdown,
+						},
+						{
+							Name:            "shebang",
+							Type:            tftypes.String,
+							Optional:        true,
+							Description:     "An interpreter line, like `#!/bin/bash`, to place at the start of the result. It's an error to set this if `source` already starts with an interpreter line, unless `replace_source_shebang` is also set.",
+							DescriptionKind: tfprotov5.StringKindMarkdown,
+						},
+						{
+							Name:            "shebang_default",
+							Type:            tftypes.Bool,
+							Optional:        true,
+							Description:     "If true, the result starts with the default interpreter line for the selected `dialect`, such as `#!/usr/bin/env bash` for `bash`. Can't be used together with `shebang`.",
+							DescriptionKind: tfprotov5.StringKindMarkdown,
+						},
+						{
+							Name:            "replace_source_shebang",
+							Type:            tftypes.Bool,
+							Optional:        true,
+							Description:     "If true, an interpreter line at the start of `source` is discarded and replaced with the one selected by `shebang` or `shebang_default`, rather than being an error.",
+							DescriptionKind: tfprotov5.StringKindMarkdown,
+						},
+						{
+							Name:            "hermetic_preamble",
+							Type:            tftypes.Bool,
+							Optional:        true,
+							Description:     "If true, the result starts with a short preamble that unsets environment variables and disables shell features which could otherwise make the script behave differently depending on the environment it runs in.",
+							DescriptionKind: tfprotov5.StringKindMarkdown,
+						},
+						{
+							Name:            "max_line_length",
+							Type:            tftypes.Number,
+							Optional:        true,
+							Description:     "If set to a positive number, declarations longer than this many characters are split over multiple lines: arrays are written with one element per line, and long strings are split using line continuations. Defaults to zero, which means no limit.",
+							DescriptionKind: tfprotov5.StringKindMarkdown,
+						},
+						{
+							Name:            "indent",
+							Type:            tftypes.String,
+							Optional:        true,
+							Description:     "A string of spaces and tabs to add to the start of each line of the generated declarations, such as when embedding the result in an indented here-document. Defaults to no indentation.",
+							DescriptionKind: tfprotov5.StringKindMarkdown,
+						},
+						{
+							Name:            "version_fallback",
+							Type:            tftypes.Bool,
+							Optional:        true,
+							Description:     "If true, each associative array declaration is wrapped in a check of the Bash version at runtime, with a fallback for Bash 3 which instead declares an indexed array of alternating keys and values.",
+							DescriptionKind: tfprotov5.StringKindMarkdown,
+						},
+						{
+							Name:            "maps_as_parallel_arrays",
+							Type:            tftypes.Bool,
+							Optional:        true,
+							Description:     "If true, each map variable is declared as two indexed arrays instead of an associative array, for compatibility with Bash 3: one with the suffix `_keys` listing the keys in lexical order, and one with the suffix `_values` listing the corresponding values.",
+							DescriptionKind: tfprotov5.StringKindMarkdown,
+						},
+						{
+							Name:            "required_commands",
+							Type:            tftypes.List{ElementType: tftypes.String},
+							Optional:        true,
+							Description:     "A list of commands that the script depends on. The result starts by checking that each one is available using `command -v`, exiting with an error message if any are missing.",
+							DescriptionKind: tfprotov5.StringKindMarkdown,
+						},
+						{
+							Name:            "split_array_declaration",
+							Type:            tftypes.Bool,
+							Optional:        true,
+							Description:     "If true, each list variable is declared in three separate steps: a `declare -a` command, an assignment of its elements, and then a `readonly -a` command, as required by some style guides.",
+							DescriptionKind: tfprotov5.StringKindMarkdown,
+						},
+						{
+							Name:            "list_as_words",
+							Type:            tftypes.Set{ElementType: tftypes.String},
+							Optional:        true,
+							Description:     "A set of names of list variables to declare as a single string of space-separated words instead of as an array, for scripts that rely on word splitting, like `cmd $args`. Terraform will warn if any element is empty or contains whitespace, because word splitting can't then recover the original elements.",
+							DescriptionKind: tfprotov5.StringKindMarkdown,
+						},
+						{
+							Name:            "shellcheck_directives",
+							Type:            tftypes.Bool,
+							Optional:        true,
+							Description:     "If true, each declaration is preceded by a `# shellcheck disable=...` comment for the ShellCheck warnings that the generated declarations would otherwise trigger, so that running ShellCheck on the result reports only problems in `source`.",
+							DescriptionKind: tfprotov5.StringKindMarkdown,
+						},
+						{
+							Name:            "restricted_safe",
+							Type:            tftypes.Bool,
+							Optional:        true,
+							Description:     "If true, Terraform will return an error for any combination of arguments that would generate a script that can't run in restricted Bash (`bash -r`), such as declaring a variable named `PATH`.",
+							DescriptionKind: tfprotov5.StringKindMarkdown,
+						},
+						{
+							Name:            "list_as_null_delimited",
+							Type:            tftypes.Set{ElementType: tftypes.String},
+							Optional:        true,
+							Description:     "A set of names of list variables to declare as read-only functions that write the list elements to stdout, each terminated by a NUL character, for use with commands like `xargs -0`. The functions have the same names as the variables.",
+							DescriptionKind: tfprotov5.StringKindMarkdown,
+						},
+						{
+							Name:            "function_name",
+							Type:            tftypes.String,
+							Optional:        true,
+							Description:     "If set, the result is a definition of a function with this name, whose body contains the declarations, using `local` instead of `declare`, followed by the source code. The declarations are indented to match.",
+							DescriptionKind: tfprotov5.StringKindMarkdown,
+						},
+						{
+							Name:            "min_bash_version",
+							Type:            tftypes.String,
+							Optional:        true,
+							Description:     "The earliest Bash version, like `\"3.2\"`, that the generated declarations must support. Terraform will return an error if the selected arguments would require a later version. Defaults to the `min_bash_version` from the provider configuration, if any.",
+							DescriptionKind: tfprotov5.StringKindMarkdown,
+						},
+						{
+							Name:            "null_collection_mode",
+							Type:            tftypes.String,
+							Optional:        true,
+							Description:     "How to handle list and map variables whose values are null: `\"omit\"` (the default) declares no variable at all, `\"empty\"` declares an empty array, and `\"error\"` returns an error.",
+							DescriptionKind: tfprotov5.StringKindMarkdown,
+						},
+						{
+							Name:            "omit_null",
+							Type:            tftypes.Bool,
+							Optional:        true,
+							Description:     "If true, variables whose values are null are not declared at all. Otherwise, a null string, number, or bool variable is an error, and null lists and maps are handled as selected by `null_collection_mode`.",
+							DescriptionKind: tfprotov5.StringKindMarkdown,
+						},
+						{
+							Name:            "variables_marker",
+							Type:            tftypes.String,
+							Optional:        true,
+							Description:     "A line in `source` to replace with the variable declarations, for when they must appear somewhere other than the start of the script. Defaults to `# TERRAFORM VARIABLES`. If `source` doesn't contain the default marker then the declarations are inserted at the start of the script as usual, but a marker set explicitly must be present.",
+							DescriptionKind: tfprotov5.StringKindMarkdown,
+						},
+						{
+							Name:            "posix_bash_safe",
+							Type:            tftypes.Bool,
+							Optional:        true,
+							Description:     "If true, Terraform will warn about any combination of arguments that would generate declarations that fail when Bash runs in POSIX mode (`set -o posix`), such as a function from `list_as_null_delimited` named after a POSIX special builtin. This doesn't make the result compatible with shells other than Bash.",
+							DescriptionKind: tfprotov5.StringKindMarkdown,
+						},
+						{
+							Name:            "strict_mode",
+							Type:            tftypes.Bool,
+							Optional:        true,
+							Description:     "If true, the result will start with `set -euo pipefail`, after any interpreter line but before the variable declarations, so that the script exits on the first failing command or reference to an unset variable.",
+							DescriptionKind: tfprotov5.StringKindMarkdown,
+						},
+						{
+							Name:            "bool_format",
+							Type:            tftypes.String,
+							Optional:        true,
+							Description:     "How to represent boolean variables: `\"word\"` (the default) declares them as the strings `true` and `false`, `\"integer\"` declares them as the integers `1` and `0`, and `\"presence\"` declares only the true ones, as the integer `1`, leaving the false ones undeclared.",
+							DescriptionKind: tfprotov5.StringKindMarkdown,
+						},
+						{
+							Name:            "bool_tokens",
+							Type:            tftypes.Map{AttributeType: tftypes.String},
+							Optional:        true,
+							Description:     "Custom strings to represent boolean variables, as a map with exactly the keys `\"true\"` and `\"false\"`, like `{ \"true\" = \"yes\", \"false\" = \"no\" }`. Cannot be used with `bool_format`.",
+							DescriptionKind: tfprotov5.StringKindMarkdown,
+						},
+						{
+							Name:            "global_names",
+							Type:            tftypes.Set{ElementType: tftypes.String},
+							Optional:        true,
+							Description:     "A set of names of variables to declare as global, using `declare -g`, regardless of `declare_scope`. When the result is used as the body of a function, the other variables will be local to the function. Requires Bash 4.2 or later.",
+							DescriptionKind: tfprotov5.StringKindMarkdown,
+						},
+						{
+							Name:            "split_to_array",
+							Type:            tftypes.Map{AttributeType: tftypes.String},
+							Optional:        true,
+							Description:     "A map from names of string variables to delimiters, causing each of those variables to be declared as an indexed array of the parts of its value between occurrences of the delimiter, such as `{ search_path = \":\" }`. An empty string becomes an empty array.",
+							DescriptionKind: tfprotov5.StringKindMarkdown,
+						},
+						{
+							Name:            "result_sensitive",
+							Type:            tftypes.Bool,
+							Optional:        true,
+							Description:     "If true, the generated script is returned in `sensitive_result` instead of `result`, so that Terraform will hide it in its output. Use this when any of the variables are sensitive.",
 							DescriptionKind: tfprotov5.StringKindMarkdown,
 						},
 						{
@@ -46,6 +457,83 @@ func (p *Provider) GetProviderSchema(ctx context.Context, req *tfprotov5.GetProv
 							Description:     "The resulting script, which combines the script body given in `source` with the variables given in `variables`.",
 							DescriptionKind: tfprotov5.StringKindMarkdown,
 						},
+						{
+							Name:            "result_base64",
+							Type:            tftypes.String,
+							Computed:        true,
+							Description:     "The same script as `result`, encoded using standard base64, for use with arguments that expect base64 content, such as the `write_files` block in cloud-init.",
+							DescriptionKind: tfprotov5.StringKindMarkdown,
+						},
+						{
+							Name:            "result_sha256",
+							Type:            tftypes.String,
+							Computed:        true,
+							Description:     "The hex-encoded SHA-256 digest of `result`, which changes whenever the generated script changes and so is useful as a trigger for other resources. Null when `result_sensitive` is true.",
+							DescriptionKind: tfprotov5.StringKindMarkdown,
+						},
+						{
+							Name:            "result_lines",
+							Type:            tftypes.List{ElementType: tftypes.String},
+							Computed:        true,
+							Description:     "The same script as `result`, split into a list of lines without their line terminators. A newline at the end of the script doesn't produce an extra empty element.",
+							DescriptionKind: tfprotov5.StringKindMarkdown,
+						},
+						{
+							Name:            "sensitive_result",
+							Type:            tftypes.String,
+							Computed:        true,
+							Sensitive:       true,
+							Description:     "The resulting script when `result_sensitive` is true, in which case `result`, `result_base64`, `result_sha256`, `result_lines`, `env_result`, and `variable_bash_types` are all null. Null otherwise.",
+							DescriptionKind: tfprotov5.StringKindMarkdown,
+						},
+						{
+							Name:            "version_constraint",
+							Type:            tftypes.String,
+							Computed:        true,
+							Description:     "A version constraint string, like `>= 4.0`, describing the Bash versions that can run the declarations generated for this configuration. This doesn't consider the requirements of the script given in `source`.",
+							DescriptionKind: tfprotov5.StringKindMarkdown,
+						},
+						{
+							Name:            "env_result",
+							Type:            tftypes.String,
+							Computed:        true,
+							Description:     "The string and number variables from `variables` in the \"dotenv\" format, with one `NAME=value` line per variable, for use with tools that read environment variables from a file. List and map variables are not included.",
+							DescriptionKind: tfprotov5.StringKindMarkdown,
+						},
+						{
+							Name:            "variable_bash_types",
+							Type:            tftypes.Map{AttributeType: tftypes.String},
+							Computed:        true,
+							Description:     "A map from each variable name to the kind of Bash variable declared for it: `\"scalar\"`, `\"integer\"`, `\"indexed_array\"`, `\"associative_array\"`, `\"function\"` for lists in `list_as_null_delimited`, or `\"parallel_arrays\"` for maps when `maps_as_parallel_arrays` is set. This is null when `single_array` is set or when `dialect` isn't `\"bash\"`, because the variables are then not declared individually, and when `result_sensitive` is true.",
+							DescriptionKind: tfprotov5.StringKindMarkdown,
+						},
+					},
+				},
+			},
+			"bash_bundle": {
+				Block: &tfprotov5.SchemaBlock{
+					Attributes: []*tfprotov5.SchemaAttribute{
+						{
+							Name:            "files",
+							Type:            tftypes.Map{AttributeType: tftypes.String},
+							Optional:        true,
+							Description:     "A map from file paths to the text content to write to each one. The paths are relative to the working directory where the script runs, and must not be absolute, contain `.` or `..` segments, or be inside another path given as a file.",
+							DescriptionKind: tfprotov5.StringKindMarkdown,
+						},
+						{
+							Name:            "files_base64",
+							Type:            tftypes.Map{AttributeType: tftypes.String},
+							Optional:        true,
+							Description:     "Like `files`, but with base64-encoded content, such as from Terraform's `filebase64` function, for files that aren't UTF-8 text.",
+							DescriptionKind: tfprotov5.StringKindMarkdown,
+						},
+						{
+							Name:            "result",
+							Type:            tftypes.String,
+							Computed:        true,
+							Description:     "A Bash script which, when run, writes each of the files given in `files` and `files_base64`, creating parent directories as needed.",
+							DescriptionKind: tfprotov5.StringKindMarkdown,
+						},
 					},
 				},
 			},
@@ -54,17 +542,35 @@ func (p *Provider) GetProviderSchema(ctx context.Context, req *tfprotov5.GetProv
 }
 
 func (p *Provider) PrepareProviderConfig(ctx context.Context, req *tfprotov5.PrepareProviderConfigRequest) (*tfprotov5.PrepareProviderConfigResponse, error) {
-	// This provider has an empty provider configuration schema, so we have
-	// nothing to do here except echo back the empty object we were given.
+	config, diags := decodeProviderConfig(req.Config)
+	if config != nil && config.MinBashVersion != "" {
+		if _, err := parseBashVersion(config.MinBashVersion); err != nil {
+			diags = append(diags, &tfprotov5.Diagnostic{
+				Severity: tfprotov5.DiagnosticSeverityError,
+				Summary:  "Invalid minimum Bash version",
+				Detail:   fmt.Sprintf("Can't use %q as a Bash version: %s. Specify a version like \"4.2\".", config.MinBashVersion, err),
+				Attribute: &tftypes.AttributePath{
+					Steps: []tftypes.AttributePathStep{
+						tftypes.AttributeName("min_bash_version"),
+					},
+				},
+			})
+		}
+	}
 	return &tfprotov5.PrepareProviderConfigResponse{
 		PreparedConfig: req.Config,
+		Diagnostics:    diags,
 	}, nil
 }
 
 func (p *Provider) ConfigureProvider(ctx context.Context, req *tfprotov5.ConfigureProviderRequest) (*tfprotov5.ConfigureProviderResponse, error) {
-	// This provider has an empty provider configuration schema, so there's
-	// nothing to do here.
-	return &tfprotov5.ConfigureProviderResponse{}, nil
+	config, diags := decodeProviderConfig(req.Config)
+	if config != nil {
+		p.minBashVersion = config.MinBashVersion
+	}
+	return &tfprotov5.ConfigureProviderResponse{
+		Diagnostics: diags,
+	}, nil
 }
 
 func (p *Provider) StopProvider(ctx context.Context, req *tfprotov5.StopProviderRequest) (*tfprotov5.StopProviderResponse, error) {
@@ -73,13 +579,42 @@ func (p *Provider) StopProvider(ctx context.Context, req *tfprotov5.StopProvider
 }
 
 func (p *Provider) ValidateDataSourceConfig(ctx context.Context, req *tfprotov5.ValidateDataSourceConfigRequest) (*tfprotov5.ValidateDataSourceConfigResponse, error) {
-	if req.TypeName != "bash_script" {
+	switch req.TypeName {
+	case "bash_script":
+		return p.validateBashScriptConfig(ctx, req)
+	case "bash_bundle":
+		_, diags := newBashBundleConfig(req.Config)
+		return &tfprotov5.ValidateDataSourceConfigResponse{
+			Diagnostics: diags,
+		}, nil
+	default:
 		// Should never get here because we have no other data resource types
 		// declared in the schema.
 		return nil, fmt.Errorf("unsupported data resource type %s", req.TypeName)
 	}
+}
 
-	_, diags := newBashScriptConfig(req.Config)
+func (p *Provider) validateBashScriptConfig(ctx context.Context, req *tfprotov5.ValidateDataSourceConfigRequest) (*tfprotov5.ValidateDataSourceConfigResponse, error) {
+	config, diags := newBashScriptConfig(req.Config, p.minBashVersion)
+	if !hasErrors(diags) && config.Preview && config.isFullyKnown() {
+		// We can only render the result if everything is known, so the
+		// preview will be silently skipped if it's not.
+		if config.ResultSensitive {
+			// Diagnostics are shown in clear text, so we mustn't include
+			// the script here if it's meant to be sensitive.
+			diags = append(diags, &tfprotov5.Diagnostic{
+				Severity: tfprotov5.DiagnosticSeverityWarning,
+				Summary:  "Script preview",
+				Detail:   "The \"preview\" argument is enabled, but the script is not shown here because \"result_sensitive\" is also enabled.",
+			})
+		} else if varDecls, err := config.Declarations(); err == nil {
+			diags = append(diags, &tfprotov5.Diagnostic{
+				Severity: tfprotov5.DiagnosticSeverityWarning,
+				Summary:  "Script preview",
+				Detail:   "The \"preview\" argument is enabled, so here is the script that will be generated:\n\n" + config.Script(varDecls),
+			})
+		}
+	}
 
 	return &tfprotov5.ValidateDataSourceConfigResponse{
 		Diagnostics: diags,
@@ -87,39 +622,70 @@ func (p *Provider) ValidateDataSourceConfig(ctx context.Context, req *tfprotov5.
 }
 
 func (p *Provider) ReadDataSource(ctx context.Context, req *tfprotov5.ReadDataSourceRequest) (*tfprotov5.ReadDataSourceResponse, error) {
-	if req.TypeName != "bash_script" {
+	switch req.TypeName {
+	case "bash_script":
+		return p.readBashScript(ctx, req)
+	case "bash_bundle":
+		config, diags := newBashBundleConfig(req.Config)
+		if hasErrors(diags) {
+			return &tfprotov5.ReadDataSourceResponse{
+				Diagnostics: diags,
+			}, nil
+		}
+		return &tfprotov5.ReadDataSourceResponse{
+			State:       config.ResultDynamicValue(config.Script()),
+			Diagnostics: diags,
+		}, nil
+	default:
 		// Should never get here because we have no other data resource types
 		// declared in the schema.
 		return nil, fmt.Errorf("unsupported data resource type %s", req.TypeName)
 	}
+}
+
+func (p *Provider) readBashScript(ctx context.Context, req *tfprotov5.ReadDataSourceRequest) (resp *tfprotov5.ReadDataSourceResponse, err error) {
+	defer func() {
+		// A panic here would otherwise crash the whole provider, and
+		// Terraform would report only the stack trace without any
+		// indication of what configuration caused it.
+		if r := recover(); r != nil {
+			resp = &tfprotov5.ReadDataSourceResponse{
+				Diagnostics: []*tfprotov5.Diagnostic{
+					internalErrorDiag(r, req.Config),
+				},
+			}
+			err = nil
+		}
+	}()
 
 	var diags []*tfprotov5.Diagnostic
 
-	config, diags := newBashScriptConfig(req.Config)
-	if len(diags) != 0 {
-		// NOTE: This assumes that diags doesn't contain any warnings, which
-		// is always true at the time of writing this.
+	config, diags := newBashScriptConfig(req.Config, p.minBashVersion)
+	if hasErrors(diags) {
 		return &tfprotov5.ReadDataSourceResponse{
 			Diagnostics: diags,
 		}, nil
 	}
 
-	varDecls := variablesToBashDecls(config.Variables)
-	source := config.Source
-	var result string
-	if strings.HasPrefix(source, "#!") {
-		// If the source seems to start with an interpreter line then we'll
-		// keep it at the start and insert the variables after it.
-		newline := strings.Index(source, "\n")
-		if newline < 0 {
-			result = source + "\n" + varDecls
-		} else {
-			before, after := source[:newline+1], source[newline+1:]
-			result = before + varDecls + after
-		}
-	} else {
-		result = varDecls + source
+	if !config.isFullyKnown() {
+		// We can't generate the script until we know all of its inputs,
+		// so the result is unknown for now.
+		return &tfprotov5.ReadDataSourceResponse{
+			State:       config.UnknownResultDynamicValue(),
+			Diagnostics: diags,
+		}, nil
+	}
+
+	varDecls, err := config.Declarations()
+	if err != nil {
+		// Should never get here because newBashScriptConfig should've
+		// already rejected anything we can't render.
+		diags = append(diags, internalErrorDiag(fmt.Sprintf("failed to generate variable declarations: %s", err), req.Config))
+		return &tfprotov5.ReadDataSourceResponse{
+			Diagnostics: diags,
+		}, nil
 	}
+	result := config.Script(varDecls)
 
 	ret := config.ResultDynamicValue(result)
 
@@ -0,0 +1,54 @@
+package bash
+
+import (
+	"fmt"
+
+	"github.com/hashicorp/terraform-plugin-go/tfprotov5"
+	"github.com/hashicorp/terraform-plugin-go/tfprotov5/tftypes"
+)
+
+// providerConfig is the decoded form of the provider configuration block.
+type providerConfig struct {
+	MinBashVersion string
+}
+
+var providerConfigType = tftypes.Object{
+	AttributeTypes: map[string]tftypes.Type{
+		"min_bash_version": tftypes.String,
+	},
+}
+
+// decodeProviderConfig decodes the given raw provider configuration.
+//
+// Returns a nil config if the configuration can't be decoded, in which case
+// the diagnostics describe why. Arguments whose values are not yet known
+// are treated as unset.
+func decodeProviderConfig(raw *tfprotov5.DynamicValue) (*providerConfig, []*tfprotov5.Diagnostic) {
+	var diags []*tfprotov5.Diagnostic
+
+	val, err := raw.Unmarshal(providerConfigType)
+	if err != nil {
+		// Terraform should have verified that the configuration matches
+		// our schema, so this should never happen.
+		diags = append(diags, &tfprotov5.Diagnostic{
+			Severity: tfprotov5.DiagnosticSeverityError,
+			Summary:  "Invalid provider configuration",
+			Detail:   fmt.Sprintf("The given configuration doesn't match the expected schema: %s.", err),
+		})
+		return nil, diags
+	}
+
+	var obj map[string]tftypes.Value
+	if err := val.As(&obj); err != nil {
+		diags = append(diags, &tfprotov5.Diagnostic{
+			Severity: tfprotov5.DiagnosticSeverityError,
+			Summary:  "Invalid provider configuration",
+			Detail:   fmt.Sprintf("The given configuration doesn't match the expected schema: %s.", err),
+		})
+		return nil, diags
+	}
+
+	return &providerConfig{
+		MinBashVersion: stringAttr(obj, "min_bash_version", ""),
+	}, diags
+}
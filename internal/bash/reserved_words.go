@@ -0,0 +1,60 @@
+package bash
+
+import (
+	"fmt"
+
+	"github.com/hashicorp/terraform-plugin-go/tfprotov5"
+	"github.com/hashicorp/terraform-plugin-go/tfprotov5/tftypes"
+)
+
+// reservedWords are the Bash reserved words that are also valid variable
+// names. The others, like "{" and "[[", are already rejected as invalid
+// names.
+var reservedWords = map[string]bool{
+	"case":     true,
+	"coproc":   true,
+	"do":       true,
+	"done":     true,
+	"elif":     true,
+	"else":     true,
+	"esac":     true,
+	"fi":       true,
+	"for":      true,
+	"function": true,
+	"if":       true,
+	"in":       true,
+	"select":   true,
+	"then":     true,
+	"time":     true,
+	"until":    true,
+	"while":    true,
+}
+
+// reservedWordDiags checks for variables whose names are in reservedWords
+// when reject_reserved_words is set, returning an error diagnostic for each
+// one it finds.
+//
+// Bash only treats these words as reserved at the start of a command, so
+// declaring them works, but a script that uses them as variable names is
+// hard to read.
+func reservedWordDiags(c *bashScriptConfig) []*tfprotov5.Diagnostic {
+	if !c.RejectReservedWords {
+		return nil
+	}
+
+	var diags []*tfprotov5.Diagnostic
+	check := func(name string, path *tftypes.AttributePath) {
+		name = c.NamePrefix + name
+		if !reservedWords[name] {
+			return
+		}
+		diags = append(diags, &tfprotov5.Diagnostic{
+			Severity:  tfprotov5.DiagnosticSeverityError,
+			Summary:   "Variable name is a reserved word",
+			Detail:    fmt.Sprintf("Cannot use %q as a variable name, because it's a Bash reserved word and reject_reserved_words is set.", name),
+			Attribute: path,
+		})
+	}
+	c.forEachVariableName(check)
+	return diags
+}
@@ -0,0 +1,74 @@
+package bash
+
+import (
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-go/tfprotov5"
+	"github.com/hashicorp/terraform-plugin-go/tfprotov5/tftypes"
+)
+
+func TestBashScriptReservedWords(t *testing.T) {
+	tests := map[string]struct {
+		args map[string]tftypes.Value
+		path string // path of the expected error, if any
+	}{
+		"if with the flag": {
+			args: map[string]tftypes.Value{
+				"reject_reserved_words": boolVal(true),
+				"variables": objectVal(map[string]tftypes.Value{
+					"if": stringVal("x"),
+				}),
+			},
+			path: "variables.if",
+		},
+		"if without the flag": {
+			args: map[string]tftypes.Value{
+				"variables": objectVal(map[string]tftypes.Value{
+					"if": stringVal("x"),
+				}),
+			},
+		},
+		"if with the flag unset": {
+			args: map[string]tftypes.Value{
+				"reject_reserved_words": boolVal(false),
+				"variables": objectVal(map[string]tftypes.Value{
+					"if": stringVal("x"),
+				}),
+			},
+		},
+		"ordinary name with the flag": {
+			args: map[string]tftypes.Value{
+				"reject_reserved_words": boolVal(true),
+				"variables": objectVal(map[string]tftypes.Value{
+					"iff": stringVal("x"),
+				}),
+			},
+		},
+		"raw variable": {
+			args: map[string]tftypes.Value{
+				"reject_reserved_words": boolVal(true),
+				"raw_variables":         stringMapVal("then", "$(date)"),
+			},
+			path: `raw_variables["then"]`,
+		},
+		"with name_prefix": {
+			// The prefix makes the declared name an ordinary one.
+			args: map[string]tftypes.Value{
+				"reject_reserved_words": boolVal(true),
+				"name_prefix":           stringVal("tf_"),
+				"variables": objectVal(map[string]tftypes.Value{
+					"if": stringVal("x"),
+				}),
+			},
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			args := mergeArgs(map[string]tftypes.Value{
+				"source": stringVal("echo hello\n"),
+			}, test.args)
+			testValidateWant(t, args, tfprotov5.DiagnosticSeverityError, "Variable name is a reserved word", test.path, "")
+		})
+	}
+}
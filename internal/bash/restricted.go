@@ -0,0 +1,64 @@
+package bash
+
+import (
+	"fmt"
+
+	"github.com/hashicorp/terraform-plugin-go/tfprotov5"
+	"github.com/hashicorp/terraform-plugin-go/tfprotov5/tftypes"
+)
+
+// restrictedVariables are the variables that restricted Bash (bash -r)
+// doesn't allow a script to assign, which therefore can't be declared.
+var restrictedVariables = map[string]bool{
+	"BASH_ENV": true,
+	"ENV":      true,
+	"HISTFILE": true,
+	"PATH":     true,
+	"SHELL":    true,
+}
+
+// restrictedDiags checks whether the given configuration would generate a
+// script that fails in restricted Bash, returning an error diagnostic for
+// each problem it finds.
+//
+// This is only relevant when the restricted_safe argument is enabled, so
+// the result is always empty otherwise.
+func restrictedDiags(c *bashScriptConfig) []*tfprotov5.Diagnostic {
+	if !c.RestrictedSafe {
+		return nil
+	}
+
+	var diags []*tfprotov5.Diagnostic
+	problem := func(attrName, detail string) {
+		diags = append(diags, &tfprotov5.Diagnostic{
+			Severity: tfprotov5.DiagnosticSeverityError,
+			Summary:  "Not compatible with restricted Bash",
+			Detail:   detail,
+			Attribute: &tftypes.AttributePath{
+				Steps: []tftypes.AttributePathStep{
+					tftypes.AttributeName(attrName),
+				},
+			},
+		})
+	}
+
+	if c.HermeticPreamble {
+		problem("hermetic_preamble", "The preamble generated by \"hermetic_preamble\" unsets BASH_ENV and ENV, which restricted Bash doesn't allow.")
+	}
+	if len(c.RequiredCommands) != 0 {
+		problem("required_commands", "The checks generated by \"required_commands\" redirect output to /dev/null, which restricted Bash doesn't allow.")
+	}
+	c.forEachVariableName(func(name string, path *tftypes.AttributePath) {
+		declName := c.NamePrefix + name
+		if !restrictedVariables[declName] {
+			return
+		}
+		diags = append(diags, &tfprotov5.Diagnostic{
+			Severity:  tfprotov5.DiagnosticSeverityError,
+			Summary:   "Not compatible with restricted Bash",
+			Detail:    fmt.Sprintf("Restricted Bash doesn't allow assigning to %s, so it can't be declared as a variable.", declName),
+			Attribute: path,
+		})
+	})
+	return diags
+}
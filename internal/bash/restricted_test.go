@@ -0,0 +1,121 @@
+package bash
+
+import (
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-go/tfprotov5"
+	"github.com/hashicorp/terraform-plugin-go/tfprotov5/tftypes"
+)
+
+func TestRestrictedDiags(t *testing.T) {
+	tests := map[string]struct {
+		args map[string]tftypes.Value
+		path string // if empty, no errors are expected
+	}{
+		"ordinary variables": {
+			args: map[string]tftypes.Value{
+				"variables": objectVal(map[string]tftypes.Value{
+					"a": stringVal("x"),
+				}),
+			},
+		},
+		"PATH": {
+			args: map[string]tftypes.Value{
+				"variables": objectVal(map[string]tftypes.Value{
+					"PATH": stringVal("/bin"),
+				}),
+			},
+			path: "variables.PATH",
+		},
+		"SHELL": {
+			args: map[string]tftypes.Value{
+				"variables": objectVal(map[string]tftypes.Value{
+					"SHELL": stringVal("/bin/sh"),
+				}),
+			},
+			path: "variables.SHELL",
+		},
+		"PATH with name_prefix": {
+			args: map[string]tftypes.Value{
+				"name_prefix": stringVal("PA"),
+				"variables": objectVal(map[string]tftypes.Value{
+					"TH": stringVal("/bin"),
+				}),
+			},
+			path: "variables.TH",
+		},
+		"PATH prefixed": {
+			args: map[string]tftypes.Value{
+				"name_prefix": stringVal("APP_"),
+				"variables": objectVal(map[string]tftypes.Value{
+					"PATH": stringVal("/bin"),
+				}),
+			},
+		},
+		"raw PATH": {
+			args: map[string]tftypes.Value{
+				"raw_variables": stringMapVal("PATH", "/bin"),
+			},
+			path: `raw_variables["PATH"]`,
+		},
+		"raw HISTFILE": {
+			args: map[string]tftypes.Value{
+				"raw_variables": stringMapVal("HISTFILE", "/dev/null"),
+			},
+			path: `raw_variables["HISTFILE"]`,
+		},
+		"raw SHELL with name_prefix": {
+			args: map[string]tftypes.Value{
+				"name_prefix":   stringVal("SH"),
+				"raw_variables": stringMapVal("ELL", "/bin/sh"),
+			},
+			path: `raw_variables["ELL"]`,
+		},
+		"hermetic_preamble": {
+			args: map[string]tftypes.Value{
+				"hermetic_preamble": boolVal(true),
+			},
+			path: "hermetic_preamble",
+		},
+		"required_commands": {
+			args: map[string]tftypes.Value{
+				"required_commands": stringListVal("jq"),
+			},
+			path: "required_commands",
+		},
+		"disabled": {
+			args: map[string]tftypes.Value{
+				"restricted_safe":   boolVal(false),
+				"hermetic_preamble": boolVal(true),
+				"variables": objectVal(map[string]tftypes.Value{
+					"PATH": stringVal("/bin"),
+				}),
+			},
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			args := mergeArgs(map[string]tftypes.Value{
+				"source":          stringVal("echo hello\n"),
+				"restricted_safe": boolVal(true),
+			}, test.args)
+			testValidateWant(t, args, tfprotov5.DiagnosticSeverityError, "Not compatible with restricted Bash", test.path, "")
+		})
+	}
+}
+
+func TestRestrictedRun(t *testing.T) {
+	script := testResult(t, map[string]tftypes.Value{
+		"source":          stringVal("echo \"$a ${b[1]}\"\n"),
+		"restricted_safe": boolVal(true),
+		"variables": objectVal(map[string]tftypes.Value{
+			"a": stringVal("x"),
+			"b": stringListVal("y", "z"),
+		}),
+	})
+	got := testRunBash(t, script, "-r")
+	if want := "x z\n"; got != want {
+		t.Errorf("wrong output\ngot:  %q\nwant: %q", got, want)
+	}
+}
@@ -0,0 +1,170 @@
+package bash
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/hashicorp/terraform-plugin-go/tfprotov5"
+	"github.com/hashicorp/terraform-plugin-go/tfprotov5/tftypes"
+)
+
+// schemaMismatchDetail returns a description of how the top-level attributes
+// of the given raw configuration differ from the attributes of ty, for use
+// in an error message when the configuration doesn't match our schema.
+//
+// Returns the empty string if the attribute names match or if the raw value
+// can't be decoded well enough to find its attribute names, in which case
+// the mismatch must be somewhere deeper in the value.
+func schemaMismatchDetail(raw *tfprotov5.DynamicValue, ty tftypes.Object) string {
+	if raw.JSON != nil {
+		got, err := jsonAttrNames(raw.JSON)
+		if err != nil {
+			return ""
+		}
+		return attrNamesMismatchDetail(got, ty)
+	}
+	return msgpackMismatchDetail(raw, ty)
+}
+
+// attrNamesMismatchDetail compares the given attribute names with the
+// attributes of ty, as described for schemaMismatchDetail.
+func attrNamesMismatchDetail(got []string, ty tftypes.Object) string {
+	var unexpected, missing []string
+	gotSet := make(map[string]bool, len(got))
+	for _, name := range got {
+		gotSet[name] = true
+		if _, ok := ty.AttributeTypes[name]; !ok {
+			unexpected = append(unexpected, name)
+		}
+	}
+	for name := range ty.AttributeTypes {
+		if !gotSet[name] {
+			missing = append(missing, name)
+		}
+	}
+	sort.Strings(unexpected)
+	sort.Strings(missing)
+
+	var parts []string
+	if len(unexpected) != 0 {
+		parts = append(parts, fmt.Sprintf("unexpected attributes %s", strings.Join(unexpected, ", ")))
+	}
+	if len(missing) != 0 {
+		parts = append(parts, fmt.Sprintf("missing attributes %s", strings.Join(missing, ", ")))
+	}
+	if len(parts) == 0 {
+		return ""
+	}
+	return strings.Join(parts, "; ")
+}
+
+// jsonAttrNames returns the names of the top-level attributes in the given
+// JSON object, without needing to know the object's type.
+func jsonAttrNames(src []byte) ([]string, error) {
+	var attrs map[string]json.RawMessage
+	if err := json.Unmarshal(src, &attrs); err != nil {
+		return nil, err
+	}
+	names := make([]string, 0, len(attrs))
+	for name := range attrs {
+		names = append(names, name)
+	}
+	return names, nil
+}
+
+// msgpackMismatchDetail is the part of schemaMismatchDetail for values in
+// the msgpack encoding, which Terraform normally uses.
+//
+// tftypes can only decode an object whose type we already know, so we
+// learn about the mismatch from the errors it returns: it reports the first
+// attribute name that's not in the type, but only once the number of
+// attributes matches. We therefore make a few further attempts with
+// adjusted types, which is fine because this is only for error messages.
+func msgpackMismatchDetail(raw *tfprotov5.DynamicValue, ty tftypes.Object) string {
+	_, err := raw.Unmarshal(ty)
+	if err == nil {
+		return ""
+	}
+	if name, ok := unknownAttrName(err); ok {
+		return fmt.Sprintf("unexpected attributes %s", name)
+	}
+	want, got, ok := attrCountMismatch(err)
+	if !ok {
+		return ""
+	}
+
+	switch {
+	case got > want:
+		// Placeholder attributes, whose names can't appear in a real
+		// configuration, make the counts match so that decoding will
+		// fail at the first attribute that we don't expect.
+		atys := make(map[string]tftypes.Type, got)
+		for name, aty := range ty.AttributeTypes {
+			atys[name] = aty
+		}
+		for i := 0; i < got-want; i++ {
+			atys[fmt.Sprintf("\x00%d", i)] = tftypes.String
+		}
+		_, err := raw.Unmarshal(tftypes.Object{AttributeTypes: atys})
+		if name, ok := unknownAttrName(err); ok {
+			if got-want == 1 {
+				return fmt.Sprintf("unexpected attributes %s", name)
+			}
+			return fmt.Sprintf("%d unexpected attributes, including %s", got-want, name)
+		}
+	case got == want-1:
+		// Exactly one attribute is missing, so decoding will succeed
+		// once we remove that one from the type.
+		for name := range ty.AttributeTypes {
+			atys := make(map[string]tftypes.Type, want-1)
+			for other, aty := range ty.AttributeTypes {
+				if other != name {
+					atys[other] = aty
+				}
+			}
+			if _, err := raw.Unmarshal(tftypes.Object{AttributeTypes: atys}); err == nil {
+				return fmt.Sprintf("missing attributes %s", name)
+			}
+		}
+	}
+	return fmt.Sprintf("expected %d attributes, but got %d", want, got)
+}
+
+var (
+	unknownAttrErrPattern    = regexp.MustCompile(`^unknown attribute ("(?:[^"\\]|\\.)*")$`)
+	attrCountMismatchPattern = regexp.MustCompile(`^error decoding object; expected (\d+) attributes, got (\d+)$`)
+)
+
+// unknownAttrName returns the attribute name from a tftypes decoding error
+// reporting an unexpected attribute, or false if err isn't such an error.
+func unknownAttrName(err error) (string, bool) {
+	if err == nil {
+		return "", false
+	}
+	m := unknownAttrErrPattern.FindStringSubmatch(err.Error())
+	if m == nil {
+		return "", false
+	}
+	name, err := strconv.Unquote(m[1])
+	if err != nil {
+		return "", false
+	}
+	return name, true
+}
+
+// attrCountMismatch returns the expected and actual number of attributes
+// from a tftypes decoding error reporting that they differ, or false if err
+// isn't such an error.
+func attrCountMismatch(err error) (want, got int, ok bool) {
+	m := attrCountMismatchPattern.FindStringSubmatch(err.Error())
+	if m == nil {
+		return 0, 0, false
+	}
+	want, _ = strconv.Atoi(m[1])
+	got, _ = strconv.Atoi(m[2])
+	return want, got, true
+}
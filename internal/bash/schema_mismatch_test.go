@@ -0,0 +1,114 @@
+package bash
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-go/tfprotov5"
+	"github.com/hashicorp/terraform-plugin-go/tfprotov5/tftypes"
+)
+
+func TestSchemaMismatchDetail(t *testing.T) {
+	n := len(bashScriptType.AttributeTypes)
+
+	tests := map[string]struct {
+		add    []string
+		remove []string
+		want   string
+	}{
+		"matching": {
+			want: "",
+		},
+		"one unexpected": {
+			add:  []string{"new_thing"},
+			want: "unexpected attributes new_thing",
+		},
+		"two unexpected": {
+			add:  []string{"new_thing", "other_thing"},
+			want: "2 unexpected attributes, including new_thing",
+		},
+		"one missing": {
+			remove: []string{"source"},
+			want:   "missing attributes source",
+		},
+		"two missing": {
+			remove: []string{"source", "dialect"},
+			want:   fmt.Sprintf("expected %d attributes, but got %d", n, n-2),
+		},
+		"one renamed": {
+			add:    []string{"new_thing"},
+			remove: []string{"source"},
+			want:   "unexpected attributes new_thing",
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			atys := make(map[string]tftypes.Type, len(bashScriptType.AttributeTypes))
+			for name, aty := range bashScriptType.AttributeTypes {
+				atys[name] = aty
+			}
+			for _, name := range test.add {
+				atys[name] = tftypes.String
+			}
+			for _, name := range test.remove {
+				delete(atys, name)
+			}
+			attrs := make(map[string]tftypes.Value, len(atys))
+			for name, aty := range atys {
+				attrs[name] = tftypes.NewValue(aty, nil)
+			}
+			ty := tftypes.Object{AttributeTypes: atys}
+			raw, err := tfprotov5.NewDynamicValue(ty, tftypes.NewValue(ty, attrs))
+			if err != nil {
+				t.Fatalf("failed to encode configuration: %s", err)
+			}
+
+			got := schemaMismatchDetail(&raw, bashScriptType)
+			if got != test.want {
+				t.Errorf("wrong result\ngot:  %s\nwant: %s", got, test.want)
+			}
+		})
+	}
+}
+
+func TestSchemaMismatchDetailJSON(t *testing.T) {
+	raw := &tfprotov5.DynamicValue{
+		JSON: []byte(`{"source":"echo hello","new_thing":true}`),
+	}
+	ty := tftypes.Object{
+		AttributeTypes: map[string]tftypes.Type{
+			"source":    tftypes.String,
+			"variables": tftypes.DynamicPseudoType,
+		},
+	}
+	got := schemaMismatchDetail(raw, ty)
+	want := "unexpected attributes new_thing; missing attributes variables"
+	if got != want {
+		t.Errorf("wrong result\ngot:  %s\nwant: %s", got, want)
+	}
+}
+
+func TestBashScriptSchemaMismatch(t *testing.T) {
+	atys := map[string]tftypes.Type{"new_thing": tftypes.String}
+	attrs := map[string]tftypes.Value{"new_thing": stringVal("x")}
+	for name, aty := range bashScriptType.AttributeTypes {
+		atys[name] = aty
+		attrs[name] = tftypes.NewValue(aty, nil)
+	}
+	ty := tftypes.Object{AttributeTypes: atys}
+	raw, err := tfprotov5.NewDynamicValue(ty, tftypes.NewValue(ty, attrs))
+	if err != nil {
+		t.Fatalf("failed to encode configuration: %s", err)
+	}
+
+	resp, err := NewProvider().ValidateDataSourceConfig(context.Background(), &tfprotov5.ValidateDataSourceConfigRequest{
+		TypeName: "bash_script",
+		Config:   &raw,
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	wantDiag(t, resp.Diagnostics, tfprotov5.DiagnosticSeverityError, "Invalid configuration", "", "unexpected attributes new_thing")
+}
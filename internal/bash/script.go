@@ -0,0 +1,321 @@
+package bash
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+
+	"github.com/hashicorp/terraform-plugin-go/tfprotov5/tftypes"
+
+	"github.com/apparentlymart/terraform-provider-bash/bashgen"
+)
+
+// Declarations returns the variable declarations for the configuration, in
+// the syntax of the selected dialect.
+func (c *bashScriptConfig) Declarations() (string, error) {
+	switch c.Dialect {
+	case "make":
+		return variablesToMakeDecls(c.RenderVariables(), c.SortLists)
+	case "sh":
+		return variablesToShDecls(c.RenderVariables())
+	case "dotenv":
+		return variablesToDotenv(c.RenderVariables()), nil
+	default:
+		return variablesToBashDecls(c.RenderVariables(), c.RawVariables, c.RenderOptions())
+	}
+}
+
+// Literal returns the literal value assigned to the variable with the given
+// name in the declarations, in the syntax of the selected dialect.
+func (c *bashScriptConfig) Literal(name string) (string, error) {
+	val := c.RenderVariables()[name]
+	switch c.Dialect {
+	case "make":
+		return makeValue(val, c.SortLists)
+	case "sh":
+		return shValue(val)
+	case "dotenv":
+		return dotenvValue(val)
+	default:
+		goVal := variablesToGo(map[string]tftypes.Value{name: val})[name]
+		return bashgen.Literal(name, goVal, c.RenderOptions())
+	}
+}
+
+// Script combines the given variable declarations with the source code from
+// the configuration to produce the final script, taking into account any
+// settings in the configuration that affect how they are combined.
+func (c *bashScriptConfig) Script(varDecls string) string {
+	if c.Markers {
+		varDecls = "# BEGIN " + c.CommentPrefix + "\n" + varDecls + "# END " + c.CommentPrefix + "\n"
+	}
+	if len(c.ExitTrap) != 0 {
+		varDecls = "trap " + bashgen.Quote(strings.Join(c.ExitTrap, "; ")) + " EXIT\n" + varDecls
+	}
+	if len(c.RequiredCommands) != 0 {
+		var checks strings.Builder
+		for _, cmd := range c.RequiredCommands {
+			checks.WriteString("command -v " + bashgen.Quote(cmd) + " >/dev/null 2>&1 || { printf 'missing required command: %s\\n' " + bashgen.Quote(cmd) + " >&2; exit 1; }\n")
+		}
+		varDecls = checks.String() + varDecls
+	}
+	if c.HermeticPreamble {
+		varDecls = hermeticPreamble + varDecls
+	}
+	if c.StrictMode {
+		varDecls = strictModePreamble + varDecls
+	}
+
+	if c.FunctionName != "" {
+		// We indent only the declarations, and not the source, because
+		// indenting the source would change the content of any
+		// here-documents or multi-line strings it contains.
+		varDecls = bashgen.Indent(varDecls, "  ")
+	}
+
+	script := c.Source
+	if c.Shebang != "" {
+		if strings.HasPrefix(script, "#!") {
+			// newBashScriptConfig only allows this if
+			// ReplaceSourceShebang is set.
+			script = stripFirstLine(script)
+		}
+		script = c.Shebang + "\n" + script
+	}
+	if c.WrapIFS {
+		script = c.wrapIFS(script)
+	}
+	if lines := markerLines(script, c.VariablesMarker); len(lines) == 1 {
+		// newBashScriptConfig makes sure there's at most one marker.
+		start, end := lines[0][0], lines[0][1]
+		script = script[:start] + varDecls + script[end:]
+	} else if strings.HasPrefix(script, "#!") {
+		// If the source seems to start with an interpreter line then we'll
+		// keep it at the start and insert the variables after it.
+		newline := strings.Index(script, "\n")
+		if newline < 0 {
+			script = script + "\n" + varDecls
+		} else {
+			before, after := script[:newline+1], script[newline+1:]
+			script = before + varDecls + after
+		}
+	} else {
+		script = varDecls + script
+	}
+
+	if c.FunctionName != "" {
+		script = wrapFunction(c.FunctionName, script)
+	}
+	if c.GeneratedHeader {
+		script = withGeneratedHeader(script)
+	}
+
+	newline := "\n"
+	switch c.LineEndings {
+	case "lf":
+		script = strings.ReplaceAll(script, "\r\n", "\n")
+	case "crlf":
+		newline = "\r\n"
+		script = strings.ReplaceAll(strings.ReplaceAll(script, "\r\n", "\n"), "\n", newline)
+	}
+
+	if c.EmbedChecksum {
+		script = withChecksum(script, newline)
+	}
+	switch c.TrailingNewline {
+	case "ensure":
+		script = strings.TrimRight(script, newline) + newline
+	case "strip":
+		script = strings.TrimRight(script, newline)
+	}
+	return script
+}
+
+// hermeticPreamble is the code included at the start of the script when
+// the hermetic_preamble argument is enabled. It's intended to reduce the
+// ways in which the environment of whoever runs the script can influence
+// its behavior.
+//
+// The contents of this preamble are documented, so any changes here
+// should be reflected in the documentation too.
+const hermeticPreamble = `unset BASH_ENV ENV CDPATH GLOBIGNORE
+set +H
+`
+
+// defaultVariablesMarker is the default value of the variables_marker
+// argument, which marks where in the source to insert the declarations.
+const defaultVariablesMarker = "# TERRAFORM VARIABLES"
+
+// markerLines returns the start and end offsets of each line in the given
+// source whose content is the given marker, ignoring leading and trailing
+// whitespace. The end offset includes the line's terminating newline, if
+// any.
+func markerLines(source, marker string) [][2]int {
+	var ret [][2]int
+	for start := 0; start < len(source); {
+		end := len(source)
+		if newline := strings.IndexByte(source[start:], '\n'); newline >= 0 {
+			end = start + newline + 1
+		}
+		if strings.TrimSpace(source[start:end]) == marker {
+			ret = append(ret, [2]int{start, end})
+		}
+		start = end
+	}
+	return ret
+}
+
+// strictModePreamble is the code included at the start of the script when
+// the strict_mode argument is enabled.
+const strictModePreamble = "set -euo pipefail\n"
+
+// dialectShebangs are the default interpreter lines for each dialect, used
+// when the shebang_default argument is set.
+var dialectShebangs = map[string]string{
+	"bash": "#!/usr/bin/env bash",
+	"make": "#!/usr/bin/make -f",
+	"sh":   "#!/bin/sh",
+}
+
+// wrapFunction returns the given script wrapped in the definition of a
+// function with the given name. An interpreter line at the start of the
+// script is kept outside of the function, so that it remains effective.
+func wrapFunction(name, script string) string {
+	shebang, script := splitShebang(script)
+	if strings.TrimSpace(script) == "" {
+		// Bash doesn't allow a function body with no commands in it.
+		script = "  :\n"
+	}
+	if !strings.HasSuffix(script, "\n") {
+		script += "\n"
+	}
+	return shebang + name + "() {\n" + script + "}\n"
+}
+
+// generatedHeader is the comment included at the start of the script when
+// the generated_header argument is enabled. It must not include anything
+// that varies between runs, like a timestamp, because that would cause the
+// result to change every time.
+const generatedHeader = "# Generated by terraform-provider-bash; do not edit\n"
+
+// withGeneratedHeader returns the given script with generatedHeader added
+// at the start, or immediately after the interpreter line if there is one.
+func withGeneratedHeader(script string) string {
+	shebang, rest := splitShebang(script)
+	return shebang + generatedHeader + rest
+}
+
+// splitShebang splits the given script into its interpreter line, with a
+// terminating newline, and the rest of the script. The interpreter line is
+// empty if the script doesn't start with one.
+func splitShebang(script string) (shebang, rest string) {
+	if !strings.HasPrefix(script, "#!") {
+		return "", script
+	}
+	rest = stripFirstLine(script)
+	shebang = script[:len(script)-len(rest)]
+	if !strings.HasSuffix(shebang, "\n") {
+		shebang += "\n"
+	}
+	return shebang, rest
+}
+
+// stripFirstLine returns the given string with its first line, including
+// the terminating newline, removed.
+func stripFirstLine(s string) string {
+	newline := strings.Index(s, "\n")
+	if newline < 0 {
+		return ""
+	}
+	return s[newline+1:]
+}
+
+// Names of the temporary variables used by the wrapper that wrapIFS
+// generates.
+const (
+	savedIFSVar = "_bash_script_saved_ifs"
+	hadIFSVar   = "_bash_script_had_ifs"
+)
+
+// wrapIFS wraps the given script source so that it runs with IFS set to
+// c.SourceIFS, restoring the previous value (or lack of value) of IFS
+// afterwards.
+//
+// If the source starts with an interpreter line then that line is kept
+// at the start, outside of the wrapper.
+func (c *bashScriptConfig) wrapIFS(source string) string {
+	var interp string
+	if strings.HasPrefix(source, "#!") {
+		newline := strings.Index(source, "\n")
+		if newline < 0 {
+			return source + "\n" + c.wrapIFS("")
+		}
+		interp, source = source[:newline+1], source[newline+1:]
+	}
+	if source != "" && !strings.HasSuffix(source, "\n") {
+		source += "\n"
+	}
+
+	var buf strings.Builder
+	buf.WriteString(interp)
+	buf.WriteString(savedIFSVar + `="${IFS-}" ` + hadIFSVar + `="${IFS+set}"` + "\n")
+	buf.WriteString("IFS=" + bashgen.Quote(c.SourceIFS) + "\n")
+	buf.WriteString(source)
+	buf.WriteString(`if [ -n "$` + hadIFSVar + `" ]; then IFS="$` + savedIFSVar + `"; else unset IFS; fi` + "\n")
+	buf.WriteString("unset " + savedIFSVar + " " + hadIFSVar + "\n")
+	return buf.String()
+}
+
+// withChecksum appends a comment line to the given script recording the
+// SHA-256 checksum of everything before it, including the newline that
+// terminates the last line of the script.
+//
+// The checksum line is always the last line, so the checksum can be verified
+// by hashing all but the last line of the result. newline is the line
+// terminator to use for any lines that withChecksum adds.
+func withChecksum(script, newline string) string {
+	if script != "" && !strings.HasSuffix(script, "\n") {
+		script += newline
+	}
+	sum := sha256.Sum256([]byte(script))
+	return script + "# sha256: " + hex.EncodeToString(sum[:]) + newline
+}
+
+// balancedQuotes returns true if the given Bash command has no unterminated
+// single-quoted or double-quoted strings and doesn't end with an unpaired
+// backslash.
+//
+// This is not a full Bash parser, so it's only a heuristic for catching
+// obvious mistakes in commands that we're going to embed into a larger
+// command.
+func balancedQuotes(cmd string) bool {
+	var quote byte
+	for i := 0; i < len(cmd); i++ {
+		c := cmd[i]
+		switch quote {
+		case '\'':
+			if c == '\'' {
+				quote = 0
+			}
+		case '"':
+			switch c {
+			case '\\':
+				i++ // skip the escaped character
+			case '"':
+				quote = 0
+			}
+		default:
+			switch c {
+			case '\\':
+				i++ // skip the escaped character
+			case '\'', '"':
+				quote = c
+			}
+		}
+		if i >= len(cmd) {
+			// A backslash was the last character.
+			return false
+		}
+	}
+	return quote == 0
+}
@@ -0,0 +1,867 @@
+package bash
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"os/exec"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-go/tfprotov5"
+	"github.com/hashicorp/terraform-plugin-go/tfprotov5/tftypes"
+)
+
+func TestScript(t *testing.T) {
+	oneVar := objectVal(map[string]tftypes.Value{
+		"a": stringVal("x"),
+	})
+
+	tests := map[string]struct {
+		args map[string]tftypes.Value
+		want string
+	}{
+		"markers": {
+			args: map[string]tftypes.Value{
+				"source":    stringVal("echo \"$a\"\n"),
+				"variables": oneVar,
+				"markers":   boolVal(true),
+			},
+			want: `# BEGIN terraform-provider-bash
+declare -r a='x'
+# END terraform-provider-bash
+echo "$a"
+`,
+		},
+		"markers with custom comment_prefix": {
+			args: map[string]tftypes.Value{
+				"source":         stringVal("echo \"$a\"\n"),
+				"variables":      oneVar,
+				"markers":        boolVal(true),
+				"comment_prefix": stringVal("managed by example"),
+			},
+			want: `# BEGIN managed by example
+declare -r a='x'
+# END managed by example
+echo "$a"
+`,
+		},
+		"markers with no variables": {
+			args: map[string]tftypes.Value{
+				"source":  stringVal("echo hello\n"),
+				"markers": boolVal(true),
+			},
+			want: `# BEGIN terraform-provider-bash
+# END terraform-provider-bash
+echo hello
+`,
+		},
+		"exit_trap": {
+			args: map[string]tftypes.Value{
+				"source":    stringVal("echo \"$a\"\n"),
+				"variables": oneVar,
+				"exit_trap": stringListVal(`rm -rf "$work_dir"`, "echo 'Finished'"),
+			},
+			want: `trap 'rm -rf "$work_dir"; echo '\''Finished'\''' EXIT
+declare -r a='x'
+echo "$a"
+`,
+		},
+		"embed_checksum": {
+			args: map[string]tftypes.Value{
+				"source":         stringVal("echo hello"),
+				"embed_checksum": boolVal(true),
+			},
+			want: "echo hello\n# sha256: 5dbad7dd0b9b122dcd9956884390f4aac4738caba8ff53498a7ab6718b176c30\n",
+		},
+		"source_ifs": {
+			args: map[string]tftypes.Value{
+				"source":     stringVal("echo \"$a\"\n"),
+				"variables":  oneVar,
+				"source_ifs": stringVal(","),
+			},
+			want: `declare -r a='x'
+_bash_script_saved_ifs="${IFS-}" _bash_script_had_ifs="${IFS+set}"
+IFS=','
+echo "$a"
+if [ -n "$_bash_script_had_ifs" ]; then IFS="$_bash_script_saved_ifs"; else unset IFS; fi
+unset _bash_script_saved_ifs _bash_script_had_ifs
+`,
+		},
+		"hermetic_preamble": {
+			args: map[string]tftypes.Value{
+				"source":            stringVal("#!/bin/bash\necho \"$a\"\n"),
+				"variables":         oneVar,
+				"hermetic_preamble": boolVal(true),
+				"strict_mode":       boolVal(true),
+			},
+			want: `#!/bin/bash
+set -euo pipefail
+unset BASH_ENV ENV CDPATH GLOBIGNORE
+set +H
+declare -r a='x'
+echo "$a"
+`,
+		},
+		"strict_mode": {
+			args: map[string]tftypes.Value{
+				"source":      stringVal("echo \"$a\"\n"),
+				"variables":   oneVar,
+				"strict_mode": boolVal(true),
+			},
+			want: `set -euo pipefail
+declare -r a='x'
+echo "$a"
+`,
+		},
+		"strict_mode with interpreter line": {
+			args: map[string]tftypes.Value{
+				"source":      stringVal("#!/bin/bash -x\necho \"$a\"\n"),
+				"variables":   oneVar,
+				"strict_mode": boolVal(true),
+			},
+			want: `#!/bin/bash -x
+set -euo pipefail
+declare -r a='x'
+echo "$a"
+`,
+		},
+		"strict_mode with shebang_default": {
+			args: map[string]tftypes.Value{
+				"source":          stringVal("echo \"$a\"\n"),
+				"variables":       oneVar,
+				"strict_mode":     boolVal(true),
+				"shebang_default": boolVal(true),
+			},
+			want: `#!/usr/bin/env bash
+set -euo pipefail
+declare -r a='x'
+echo "$a"
+`,
+		},
+		"strict_mode with markers": {
+			args: map[string]tftypes.Value{
+				"source":      stringVal("echo \"$a\"\n"),
+				"variables":   oneVar,
+				"strict_mode": boolVal(true),
+				"markers":     boolVal(true),
+			},
+			want: `set -euo pipefail
+# BEGIN terraform-provider-bash
+declare -r a='x'
+# END terraform-provider-bash
+echo "$a"
+`,
+		},
+		"strict_mode disabled": {
+			args: map[string]tftypes.Value{
+				"source":      stringVal("echo \"$a\"\n"),
+				"variables":   oneVar,
+				"strict_mode": boolVal(false),
+			},
+			want: `declare -r a='x'
+echo "$a"
+`,
+		},
+		"indent": {
+			args: map[string]tftypes.Value{
+				"source": stringVal("echo \"$a\"\n"),
+				"indent": stringVal("  "),
+				"variables": objectVal(map[string]tftypes.Value{
+					"m": stringMapVal("k", "v", "j", "w"),
+				}),
+			},
+			want: `  declare -rA m=(['j']='w' ['k']='v')
+echo "$a"
+`,
+		},
+		"annotate_types": {
+			args: map[string]tftypes.Value{
+				"source":         stringVal("echo \"$s\"\n"),
+				"annotate_types": boolVal(true),
+				"variables": objectVal(map[string]tftypes.Value{
+					"s": stringVal("x"),
+					"n": numberVal("1"),
+					"l": stringListVal("a"),
+					"m": stringMapVal("k", "v"),
+				}),
+			},
+			want: `# list(string)
+declare -ra l=('a')
+# map(string)
+declare -rA m=(['k']='v')
+# number
+declare -ri n=1
+# string
+declare -r s='x'
+echo "$s"
+`,
+		},
+		"generated_header": {
+			args: map[string]tftypes.Value{
+				"source":           stringVal("echo \"$a\"\n"),
+				"variables":        oneVar,
+				"generated_header": boolVal(true),
+			},
+			want: `# Generated by terraform-provider-bash; do not edit
+declare -r a='x'
+echo "$a"
+`,
+		},
+		"generated_header after the source shebang": {
+			args: map[string]tftypes.Value{
+				"source":           stringVal("#!/bin/bash\necho \"$a\"\n"),
+				"variables":        oneVar,
+				"generated_header": boolVal(true),
+			},
+			want: `#!/bin/bash
+# Generated by terraform-provider-bash; do not edit
+declare -r a='x'
+echo "$a"
+`,
+		},
+		"generated_header with shebang and strict_mode": {
+			args: map[string]tftypes.Value{
+				"source":           stringVal("echo \"$a\"\n"),
+				"variables":        oneVar,
+				"generated_header": boolVal(true),
+				"shebang":          stringVal("#!/usr/bin/env bash"),
+				"strict_mode":      boolVal(true),
+			},
+			want: `#!/usr/bin/env bash
+# Generated by terraform-provider-bash; do not edit
+set -euo pipefail
+declare -r a='x'
+echo "$a"
+`,
+		},
+		"required_commands": {
+			args: map[string]tftypes.Value{
+				"source":            stringVal("echo \"$a\"\n"),
+				"variables":         oneVar,
+				"required_commands": stringListVal("curl", "jq"),
+			},
+			want: `command -v 'curl' >/dev/null 2>&1 || { printf 'missing required command: %s\n' 'curl' >&2; exit 1; }
+command -v 'jq' >/dev/null 2>&1 || { printf 'missing required command: %s\n' 'jq' >&2; exit 1; }
+declare -r a='x'
+echo "$a"
+`,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			got := testResult(t, test.args)
+			if got != test.want {
+				t.Errorf("wrong result\ngot:\n%s\nwant:\n%s", got, test.want)
+			}
+		})
+	}
+}
+
+func TestBalancedQuotes(t *testing.T) {
+	tests := []struct {
+		input string
+		want  bool
+	}{
+		{``, true},
+		{`echo hello`, true},
+		{`echo 'hello'`, true},
+		{`echo "hello \"world\""`, true},
+		{`echo "it's"`, true},
+		{`echo \'`, true},
+		{`echo 'hello`, false},
+		{`echo "hello`, false},
+		{`echo "hello\"`, false},
+		{`echo hello\`, false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.input, func(t *testing.T) {
+			got := balancedQuotes(test.input)
+			if got != test.want {
+				t.Errorf("wrong result for %q: got %t, want %t", test.input, got, test.want)
+			}
+		})
+	}
+}
+
+func TestWithChecksum(t *testing.T) {
+	tests := map[string]struct {
+		script  string
+		newline string
+		body    string
+	}{
+		"empty": {
+			script:  "",
+			newline: "\n",
+			body:    "",
+		},
+		"trailing newline": {
+			script:  "echo hello\n",
+			newline: "\n",
+			body:    "echo hello\n",
+		},
+		"no trailing newline": {
+			script:  "echo hello",
+			newline: "\n",
+			body:    "echo hello\n",
+		},
+		"crlf": {
+			script:  "echo hello",
+			newline: "\r\n",
+			body:    "echo hello\r\n",
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			got := withChecksum(test.script, test.newline)
+			sum := sha256.Sum256([]byte(test.body))
+			want := test.body + "# sha256: " + hex.EncodeToString(sum[:]) + test.newline
+			if got != want {
+				t.Errorf("wrong result\ngot:  %q\nwant: %q", got, want)
+			}
+		})
+	}
+}
+
+func TestScriptSourceIFS(t *testing.T) {
+	script := testResult(t, map[string]tftypes.Value{
+		"source":     stringVal("words=($csv)\necho \"${#words[@]}\"\n"),
+		"source_ifs": stringVal(","),
+		"variables": objectVal(map[string]tftypes.Value{
+			"csv": stringVal("a,b c,d"),
+		}),
+	})
+
+	tests := map[string]struct {
+		before string
+		want   string
+	}{
+		"IFS unset": {
+			before: "unset IFS\n",
+			want:   "3\nunset\n",
+		},
+		"IFS set": {
+			before: "IFS=:\n",
+			want:   "3\n:\n",
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			got := testRunBash(t, test.before+script+`echo "${IFS-unset}"`+"\n")
+			if got != test.want {
+				t.Errorf("wrong output\ngot:\n%s\nwant:\n%s", got, test.want)
+			}
+		})
+	}
+}
+
+func TestScriptShebang(t *testing.T) {
+	tests := map[string]struct {
+		args    map[string]tftypes.Value
+		want    string
+		summary string // if set, an error with this summary is expected instead
+		path    string // path of the expected error, if not "shebang"
+	}{
+		"neither": {
+			args: map[string]tftypes.Value{
+				"source": stringVal("echo \"$a\"\n"),
+			},
+			want: `declare -r a='x'
+echo "$a"
+`,
+		},
+		"source only": {
+			args: map[string]tftypes.Value{
+				"source": stringVal("#!/bin/bash\necho \"$a\"\n"),
+			},
+			want: `#!/bin/bash
+declare -r a='x'
+echo "$a"
+`,
+		},
+		"source with arguments": {
+			args: map[string]tftypes.Value{
+				"source": stringVal("#!/bin/bash -e\necho \"$a\"\n"),
+			},
+			want: `#!/bin/bash -e
+declare -r a='x'
+echo "$a"
+`,
+		},
+		"source interpreter line only": {
+			args: map[string]tftypes.Value{
+				"source": stringVal("#!/bin/bash"),
+			},
+			want: `#!/bin/bash
+declare -r a='x'
+`,
+		},
+		"source with blank line first": {
+			args: map[string]tftypes.Value{
+				"source": stringVal("\n#!/bin/bash\necho \"$a\"\n"),
+			},
+			want: `declare -r a='x'
+
+#!/bin/bash
+echo "$a"
+`,
+		},
+		"source with comment first": {
+			args: map[string]tftypes.Value{
+				"source": stringVal("# example\n#!/bin/bash\necho \"$a\"\n"),
+			},
+			want: `declare -r a='x'
+# example
+#!/bin/bash
+echo "$a"
+`,
+		},
+		"argument only": {
+			args: map[string]tftypes.Value{
+				"source":  stringVal("echo \"$a\"\n"),
+				"shebang": stringVal("#!/usr/bin/env bash"),
+			},
+			want: `#!/usr/bin/env bash
+declare -r a='x'
+echo "$a"
+`,
+		},
+		"both": {
+			args: map[string]tftypes.Value{
+				"source":  stringVal("#!/bin/bash\necho \"$a\"\n"),
+				"shebang": stringVal("#!/usr/bin/env bash"),
+			},
+			summary: "Conflicting interpreter lines",
+		},
+		"both with replace_source_shebang": {
+			args: map[string]tftypes.Value{
+				"source":                 stringVal("#!/bin/bash\necho \"$a\"\n"),
+				"shebang":                stringVal("#!/usr/bin/env bash"),
+				"replace_source_shebang": boolVal(true),
+			},
+			want: `#!/usr/bin/env bash
+declare -r a='x'
+echo "$a"
+`,
+		},
+		"default": {
+			args: map[string]tftypes.Value{
+				"source":          stringVal("echo \"$a\"\n"),
+				"shebang_default": boolVal(true),
+			},
+			want: `#!/usr/bin/env bash
+declare -r a='x'
+echo "$a"
+`,
+		},
+		"default sh": {
+			args: map[string]tftypes.Value{
+				"source":          stringVal("echo \"$a\"\n"),
+				"dialect":         stringVal("sh"),
+				"shebang_default": boolVal(true),
+			},
+			want: `#!/bin/sh
+a='x'
+echo "$a"
+`,
+		},
+		"default make": {
+			args: map[string]tftypes.Value{
+				"source":          stringVal("all:\n\techo $(a)\n"),
+				"dialect":         stringVal("make"),
+				"shebang_default": boolVal(true),
+			},
+			want: `#!/usr/bin/make -f
+a := x
+all:
+	echo $(a)
+`,
+		},
+		"default disabled": {
+			args: map[string]tftypes.Value{
+				"source":          stringVal("echo \"$a\"\n"),
+				"shebang_default": boolVal(false),
+			},
+			want: `declare -r a='x'
+echo "$a"
+`,
+		},
+		"default and source": {
+			args: map[string]tftypes.Value{
+				"source":          stringVal("#!/bin/bash\necho \"$a\"\n"),
+				"shebang_default": boolVal(true),
+			},
+			summary: "Conflicting interpreter lines",
+			path:    "shebang_default",
+		},
+		"default and source with replace_source_shebang": {
+			args: map[string]tftypes.Value{
+				"source":                 stringVal("#!/bin/bash\necho \"$a\"\n"),
+				"shebang_default":        boolVal(true),
+				"replace_source_shebang": boolVal(true),
+			},
+			want: `#!/usr/bin/env bash
+declare -r a='x'
+echo "$a"
+`,
+		},
+		"default and argument": {
+			args: map[string]tftypes.Value{
+				"source":          stringVal("echo \"$a\"\n"),
+				"shebang":         stringVal("#!/bin/bash"),
+				"shebang_default": boolVal(true),
+			},
+			summary: "Conflicting options",
+			path:    "shebang_default",
+		},
+		"default dotenv": {
+			args: map[string]tftypes.Value{
+				"source":          stringVal(""),
+				"dialect":         stringVal("dotenv"),
+				"shebang_default": boolVal(true),
+			},
+			summary: "Conflicting options",
+			path:    "shebang_default",
+		},
+		"not an interpreter line": {
+			args: map[string]tftypes.Value{
+				"source":  stringVal("echo \"$a\"\n"),
+				"shebang": stringVal("true"),
+			},
+			summary: "Invalid interpreter line",
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			args := mergeArgs(map[string]tftypes.Value{
+				"variables": objectVal(map[string]tftypes.Value{
+					"a": stringVal("x"),
+				}),
+			}, test.args)
+			if test.summary != "" {
+				diags := testValidate(t, args)
+				path := test.path
+				if path == "" {
+					path = "shebang"
+				}
+				wantDiag(t, diags, tfprotov5.DiagnosticSeverityError, test.summary, path, "")
+				return
+			}
+			got := testResult(t, args)
+			if got != test.want {
+				t.Errorf("wrong result\ngot:\n%s\nwant:\n%s", got, test.want)
+			}
+		})
+	}
+}
+
+func TestScriptRequiredCommandsRun(t *testing.T) {
+	script := testResult(t, map[string]tftypes.Value{
+		"source":            stringVal("echo ok\n"),
+		"required_commands": stringListVal("bash", "terraform-provider-bash-no-such-command"),
+	})
+	bash, err := exec.LookPath("bash")
+	if err != nil {
+		t.Skip("bash is not available")
+	}
+	out, err := exec.Command(bash, "-c", script).CombinedOutput()
+	if err == nil {
+		t.Fatalf("unexpected success\n%s", out)
+	}
+	want := "missing required command: terraform-provider-bash-no-such-command\n"
+	if got := string(out); got != want {
+		t.Errorf("wrong output\ngot:  %q\nwant: %q", got, want)
+	}
+}
+
+func TestScriptSourceVerbatim(t *testing.T) {
+	tests := map[string]string{
+		"single line":           "echo \"$a\"\n",
+		"no trailing newline":   "echo \"$a\"",
+		"here-document":         "cat <<EOF\n  indented $a\n\tand tabbed\nEOF\n",
+		"blank lines":           "\n\necho \"$a\"\n\n",
+		"comment first":         "# #!/bin/bash\necho \"$a\"\n",
+		"non-ASCII":             "echo 'héllo, 世界'\n",
+		"declaration-like text": "declare -r a='y'\n",
+	}
+
+	for name, source := range tests {
+		t.Run(name, func(t *testing.T) {
+			got := testResult(t, map[string]tftypes.Value{
+				"source": stringVal(source),
+				"variables": objectVal(map[string]tftypes.Value{
+					"a": stringVal("x"),
+				}),
+			})
+			want := "declare -r a='x'\n" + source
+			if got != want {
+				t.Errorf("wrong result\ngot:\n%s\nwant:\n%s", got, want)
+			}
+		})
+	}
+}
+
+func TestScriptTrailingNewline(t *testing.T) {
+	sources := map[string]string{
+		"none":     "echo a",
+		"one":      "echo a\n",
+		"multiple": "echo a\n\n\n",
+	}
+	tests := map[string]map[string]string{ // mode, then source name, to result
+		"": { // the default
+			"none":     "echo a",
+			"one":      "echo a\n",
+			"multiple": "echo a\n\n\n",
+		},
+		"preserve": {
+			"none":     "echo a",
+			"one":      "echo a\n",
+			"multiple": "echo a\n\n\n",
+		},
+		"ensure": {
+			"none":     "echo a\n",
+			"one":      "echo a\n",
+			"multiple": "echo a\n",
+		},
+		"strip": {
+			"none":     "echo a",
+			"one":      "echo a",
+			"multiple": "echo a",
+		},
+	}
+
+	for mode, wants := range tests {
+		for sourceName, want := range wants {
+			t.Run(mode+" "+sourceName, func(t *testing.T) {
+				args := map[string]tftypes.Value{
+					"source": stringVal(sources[sourceName]),
+				}
+				if mode != "" {
+					args["trailing_newline"] = stringVal(mode)
+				}
+				if got := testResult(t, args); got != want {
+					t.Errorf("wrong result\ngot:  %q\nwant: %q", got, want)
+				}
+			})
+		}
+	}
+
+	t.Run("with declarations", func(t *testing.T) {
+		got := testResult(t, map[string]tftypes.Value{
+			"source":           stringVal(""),
+			"trailing_newline": stringVal("strip"),
+			"variables": objectVal(map[string]tftypes.Value{
+				"a": stringVal("x"),
+			}),
+		})
+		if want := "declare -r a='x'"; got != want {
+			t.Errorf("wrong result\ngot:  %q\nwant: %q", got, want)
+		}
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		diags := testValidate(t, map[string]tftypes.Value{
+			"source":           stringVal("echo a\n"),
+			"trailing_newline": stringVal("remove"),
+		})
+		wantDiag(t, diags, tfprotov5.DiagnosticSeverityError, "Invalid trailing newline mode", "trailing_newline", "")
+	})
+}
+
+func TestScriptLineEndings(t *testing.T) {
+	args := map[string]tftypes.Value{
+		"source":          stringVal("#!/bin/bash\r\necho \"${long[@]}\"\necho done\r\n"),
+		"max_line_length": numberVal("20"),
+		"variables": objectVal(map[string]tftypes.Value{
+			"long": stringListVal("alpha", "bravo", "charlie"),
+		}),
+	}
+	body := `#!/bin/bash
+declare -ra long=(
+  'alpha'
+  'bravo'
+  'charlie'
+)
+echo "${long[@]}"
+echo done
+`
+
+	tests := map[string]struct {
+		lineEndings string // empty means the default
+		want        string
+	}{
+		"default": {
+			want: body,
+		},
+		"lf": {
+			lineEndings: "lf",
+			want:        body,
+		},
+		"crlf": {
+			lineEndings: "crlf",
+			want:        strings.ReplaceAll(body, "\n", "\r\n"),
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			testArgs := map[string]tftypes.Value{}
+			for k, v := range args {
+				testArgs[k] = v
+			}
+			if test.lineEndings != "" {
+				testArgs["line_endings"] = stringVal(test.lineEndings)
+			}
+			if got := testResult(t, testArgs); got != test.want {
+				t.Errorf("wrong result\ngot:  %q\nwant: %q", got, test.want)
+			}
+		})
+	}
+
+	t.Run("preserve", func(t *testing.T) {
+		diags := testValidate(t, map[string]tftypes.Value{
+			"source":       stringVal("echo hello\n"),
+			"line_endings": stringVal("preserve"),
+		})
+		wantDiag(t, diags, tfprotov5.DiagnosticSeverityError, "Invalid line endings", "line_endings", "")
+	})
+}
+
+func TestScriptVariablesMarker(t *testing.T) {
+	tests := map[string]struct {
+		args    map[string]tftypes.Value
+		want    string
+		summary string // if set, an error with this summary is expected instead
+		path    string // path of the expected error
+	}{
+		"default marker": {
+			args: map[string]tftypes.Value{
+				"source": stringVal("umask 077\n# TERRAFORM VARIABLES\necho \"$a\"\n"),
+			},
+			want: `umask 077
+declare -r a='x'
+echo "$a"
+`,
+		},
+		"default marker absent": {
+			args: map[string]tftypes.Value{
+				"source": stringVal("umask 077\necho \"$a\"\n"),
+			},
+			want: `declare -r a='x'
+umask 077
+echo "$a"
+`,
+		},
+		"custom marker": {
+			args: map[string]tftypes.Value{
+				"source":           stringVal("umask 077\n  #@vars  \necho \"$a\"\n"),
+				"variables_marker": stringVal("#@vars"),
+			},
+			want: `umask 077
+declare -r a='x'
+echo "$a"
+`,
+		},
+		"marker on last line": {
+			args: map[string]tftypes.Value{
+				"source": stringVal("umask 077\n# TERRAFORM VARIABLES"),
+			},
+			want: `umask 077
+declare -r a='x'
+`,
+		},
+		"marker after interpreter line": {
+			args: map[string]tftypes.Value{
+				"source": stringVal("#!/bin/bash\numask 077\n# TERRAFORM VARIABLES\necho \"$a\"\n"),
+			},
+			want: `#!/bin/bash
+umask 077
+declare -r a='x'
+echo "$a"
+`,
+		},
+		"marker within a line": {
+			args: map[string]tftypes.Value{
+				"source": stringVal("echo '# TERRAFORM VARIABLES'\n"),
+			},
+			want: `declare -r a='x'
+echo '# TERRAFORM VARIABLES'
+`,
+		},
+		"marker twice": {
+			args: map[string]tftypes.Value{
+				"source": stringVal("# TERRAFORM VARIABLES\n# TERRAFORM VARIABLES\n"),
+			},
+			summary: "Ambiguous variables marker",
+			path:    "source",
+		},
+		"custom marker absent": {
+			args: map[string]tftypes.Value{
+				"source":           stringVal("echo \"$a\"\n"),
+				"variables_marker": stringVal("#@vars"),
+			},
+			summary: "Missing variables marker",
+			path:    "variables_marker",
+		},
+		"empty marker": {
+			args: map[string]tftypes.Value{
+				"source":           stringVal("echo \"$a\"\n"),
+				"variables_marker": stringVal("  "),
+			},
+			summary: "Invalid variables marker",
+			path:    "variables_marker",
+		},
+		"multi-line marker": {
+			args: map[string]tftypes.Value{
+				"source":           stringVal("echo \"$a\"\n"),
+				"variables_marker": stringVal("#a\n#b"),
+			},
+			summary: "Invalid variables marker",
+			path:    "variables_marker",
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			args := mergeArgs(map[string]tftypes.Value{
+				"variables": objectVal(map[string]tftypes.Value{
+					"a": stringVal("x"),
+				}),
+			}, test.args)
+			if test.summary != "" {
+				diags := testValidate(t, args)
+				wantDiag(t, diags, tfprotov5.DiagnosticSeverityError, test.summary, test.path, "")
+				return
+			}
+			got := testResult(t, args)
+			if got != test.want {
+				t.Errorf("wrong result\ngot:\n%s\nwant:\n%s", got, test.want)
+			}
+		})
+	}
+}
+
+func TestScriptStrictModeRun(t *testing.T) {
+	script := testResult(t, map[string]tftypes.Value{
+		"source":      stringVal("#!/bin/bash\necho \"$a\"\necho \"$undeclared\"\necho unreachable\n"),
+		"strict_mode": boolVal(true),
+		"variables": objectVal(map[string]tftypes.Value{
+			"a": stringVal("x"),
+		}),
+	})
+	bash, err := exec.LookPath("bash")
+	if err != nil {
+		t.Skip("bash is not available")
+	}
+	out, err := exec.Command(bash, "-c", script).Output()
+	if err == nil {
+		t.Fatalf("unexpected success\n%s", out)
+	}
+	if got, want := string(out), "x\n"; got != want {
+		t.Errorf("wrong output\ngot:  %q\nwant: %q", got, want)
+	}
+}
@@ -0,0 +1,76 @@
+package bash
+
+import (
+	"fmt"
+	"math/big"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/apparentlymart/terraform-provider-bash/bashgen"
+	"github.com/hashicorp/terraform-plugin-go/tfprotov5/tftypes"
+)
+
+// variablesToShDecls is like variablesToBashDecls, but produces plain
+// POSIX shell variable assignments instead of Bash declarations, for the
+// "sh" dialect.
+//
+// POSIX sh has no declare command and no array types, so each variable is
+// just assigned and lists and maps are not supported at all.
+func variablesToShDecls(vars map[string]tftypes.Value) (string, error) {
+	if len(vars) == 0 {
+		return "", nil
+	}
+
+	var buf strings.Builder
+	names := make([]string, 0, len(vars))
+	for name := range vars {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		raw, err := shValue(vars[name])
+		if err != nil {
+			return "", fmt.Errorf("invalid value for %q: %w", name, err)
+		}
+		buf.WriteString(name)
+		buf.WriteString("=")
+		buf.WriteString(raw)
+		buf.WriteString("\n")
+	}
+	return buf.String(), nil
+}
+
+// shValue returns the right-hand side of a POSIX shell variable assignment
+// that would produce the given value.
+func shValue(val tftypes.Value) (string, error) {
+	switch {
+	case val.Is(tftypes.String):
+		var s string
+		val.As(&s)
+		return shQuote(s), nil
+	case val.Is(tftypes.Number):
+		// The shell has no numeric types, but a decimal number needs no
+		// quoting.
+		var f big.Float
+		val.As(&f)
+		return bashgen.FormatNumber(&f), nil
+	case val.Is(tftypes.Bool):
+		var b bool
+		val.As(&b)
+		return strconv.FormatBool(b), nil
+	default:
+		return "", fmt.Errorf("POSIX sh has no arrays, so only strings, numbers, and booleans are supported")
+	}
+}
+
+// shQuote returns a POSIX shell string literal which will produce exactly
+// the given string.
+//
+// Unlike bashgen.Quote, this never uses ANSI-C quoting, which POSIX sh
+// doesn't support, so any line breaks in s appear literally inside the
+// quotes.
+func shQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
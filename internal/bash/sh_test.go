@@ -0,0 +1,89 @@
+package bash
+
+import (
+	"os/exec"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-go/tfprotov5"
+	"github.com/hashicorp/terraform-plugin-go/tfprotov5/tftypes"
+)
+
+func TestBashScriptShDialect(t *testing.T) {
+	tests := map[string]struct {
+		variables tftypes.Value
+		want      string
+		path      string // path of the expected error, if any
+	}{
+		"scalars": {
+			variables: objectVal(map[string]tftypes.Value{
+				"greeting": stringVal("it's me"),
+				"port":     numberVal("8080"),
+				"enabled":  boolVal(true),
+			}),
+			want: `enabled='true'
+greeting='it'\''s me'
+port=8080
+`,
+		},
+		"multi-line string": {
+			variables: objectVal(map[string]tftypes.Value{
+				"s": stringVal("a\nb"),
+			}),
+			want: `s='a
+b'
+`,
+		},
+		"list": {
+			variables: objectVal(map[string]tftypes.Value{
+				"zones": stringListVal("a", "b"),
+			}),
+			path: "variables.zones",
+		},
+		"map": {
+			variables: objectVal(map[string]tftypes.Value{
+				"tags": stringMapVal("k", "v"),
+			}),
+			path: "variables.tags",
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			args := map[string]tftypes.Value{
+				"source":    stringVal(""),
+				"dialect":   stringVal("sh"),
+				"variables": test.variables,
+			}
+			if test.path != "" {
+				diags := testValidate(t, args)
+				wantDiag(t, diags, tfprotov5.DiagnosticSeverityError, "Invalid variable value", test.path, "POSIX sh has no arrays")
+				return
+			}
+			if got := testResult(t, args); got != test.want {
+				t.Errorf("wrong result\ngot:\n%s\nwant:\n%s", got, test.want)
+			}
+		})
+	}
+}
+
+func TestBashScriptShDialectRun(t *testing.T) {
+	sh, err := exec.LookPath("sh")
+	if err != nil {
+		t.Skip("sh is not available")
+	}
+	script := testResult(t, map[string]tftypes.Value{
+		"source":  stringVal("printf '%s|%s\\n' \"$greeting\" \"$port\"\n"),
+		"dialect": stringVal("sh"),
+		"variables": objectVal(map[string]tftypes.Value{
+			"greeting": stringVal("it's $me\nhere"),
+			"port":     numberVal("8080"),
+		}),
+	})
+	out, err := exec.Command(sh, "-c", script).CombinedOutput()
+	if err != nil {
+		t.Fatalf("sh failed: %s\n%s", err, out)
+	}
+	if got, want := string(out), "it's $me\nhere|8080\n"; got != want {
+		t.Errorf("wrong output\ngot:  %q\nwant: %q", got, want)
+	}
+}
@@ -0,0 +1,77 @@
+package bash
+
+import (
+	"fmt"
+
+	"github.com/hashicorp/terraform-plugin-go/tfprotov5"
+	"github.com/hashicorp/terraform-plugin-go/tfprotov5/tftypes"
+)
+
+// specialVariables are the names of variables that Bash itself, or the
+// programs a script typically runs, give a special meaning, and so which
+// are almost certainly a mistake to declare from Terraform.
+var specialVariables = map[string]bool{
+	"BASH":          true,
+	"BASHOPTS":      true,
+	"BASHPID":       true,
+	"BASH_ENV":      true,
+	"BASH_SOURCE":   true,
+	"BASH_VERSINFO": true,
+	"BASH_VERSION":  true,
+	"CDPATH":        true,
+	"ENV":           true,
+	"EUID":          true,
+	"FUNCNAME":      true,
+	"GLOBIGNORE":    true,
+	"GROUPS":        true,
+	"HISTFILE":      true,
+	"HOME":          true,
+	"HOSTNAME":      true,
+	"IFS":           true,
+	"LANG":          true,
+	"LC_ALL":        true,
+	"LINENO":        true,
+	"OLDPWD":        true,
+	"OPTARG":        true,
+	"OPTIND":        true,
+	"PATH":          true,
+	"PIPESTATUS":    true,
+	"PPID":          true,
+	"PS1":           true,
+	"PS2":           true,
+	"PS4":           true,
+	"PWD":           true,
+	"RANDOM":        true,
+	"REPLY":         true,
+	"SECONDS":       true,
+	"SHELL":         true,
+	"SHELLOPTS":     true,
+	"SHLVL":         true,
+	"TMPDIR":        true,
+	"UID":           true,
+	"USER":          true,
+}
+
+// specialNameDiags checks for variables whose names are in
+// specialVariables, returning a warning diagnostic for each one it finds.
+//
+// These are only warnings because there are legitimate reasons to set some
+// of these variables, such as PATH, but doing so by accident can break the
+// script in confusing ways.
+func specialNameDiags(c *bashScriptConfig) []*tfprotov5.Diagnostic {
+	var diags []*tfprotov5.Diagnostic
+	check := func(name string, path *tftypes.AttributePath) {
+		name = c.NamePrefix + name
+		if !specialVariables[name] {
+			return
+		}
+		diags = append(diags, &tfprotov5.Diagnostic{
+			Severity:  tfprotov5.DiagnosticSeverityWarning,
+			Summary:   "Variable name has a special meaning",
+			Detail:    fmt.Sprintf("Bash or the commands it runs give %s a special meaning, so declaring it will change how the script behaves, and declaring it as read-only may cause errors when Bash tries to update it. Choose a different name unless you intend to override %s.", name, name),
+			Attribute: path,
+		})
+	}
+	c.forEachVariableName(check)
+	return diags
+}
@@ -6,136 +6,365 @@ import (
 	"sort"
 	"strings"
 
+	"github.com/hashicorp/terraform-plugin-go/tfprotov5"
 	"github.com/hashicorp/terraform-plugin-go/tfprotov5/tftypes"
+
+	"github.com/apparentlymart/terraform-provider-bash/bashgen"
 )
 
+// mergeVariables merges the given ordered sequence of variables objects,
+// as given in a list or tuple value for the "variables" argument, into a
+// single set of variables. Where more than one object defines the same
+// variable, the later object wins, but it must give a value of the same
+// type as the earlier one. Null elements are ignored, so that an override
+// object can be conditionally omitted, as are unknown elements, because
+// the result will be unknown anyway.
+func mergeVariables(layers []tftypes.Value) (map[string]tftypes.Value, []*tfprotov5.Diagnostic) {
+	var diags []*tfprotov5.Diagnostic
+	ret := make(map[string]tftypes.Value)
+	from := make(map[string]int)
+	for i, layer := range layers {
+		if layer.IsNull() || !layer.IsKnown() {
+			continue
+		}
+		var vars map[string]tftypes.Value
+		if !(layer.Is(tftypes.Object{}) || layer.Is(tftypes.Map{})) || layer.As(&vars) != nil {
+			diags = append(diags, &tfprotov5.Diagnostic{
+				Severity: tfprotov5.DiagnosticSeverityError,
+				Summary:  "Invalid variables",
+				Detail:   fmt.Sprintf("Element %d of \"variables\" must be an object or map with one element per variable you wish to declare for the Bash script.", i),
+				Attribute: &tftypes.AttributePath{
+					Steps: []tftypes.AttributePathStep{
+						tftypes.AttributeName("variables"),
+						tftypes.ElementKeyInt(int64(i)),
+					},
+				},
+			})
+			continue
+		}
+		for name, val := range vars {
+			if prev, exists := ret[name]; exists {
+				prevType, newType := typeSummary(prev), typeSummary(val)
+				if prevType != newType {
+					diags = append(diags, &tfprotov5.Diagnostic{
+						Severity: tfprotov5.DiagnosticSeverityError,
+						Summary:  "Conflicting variable types",
+						Detail:   fmt.Sprintf("Element %d of \"variables\" sets %q to a value of type %s, but element %d already set it to a value of type %s. An override must have the same type as the value it replaces.", i, name, newType, from[name], prevType),
+						Attribute: &tftypes.AttributePath{
+							Steps: []tftypes.AttributePathStep{
+								tftypes.AttributeName("variables"),
+								tftypes.ElementKeyInt(int64(i)),
+								tftypes.AttributeName(name),
+							},
+						},
+					})
+					continue
+				}
+			}
+			ret[name] = val
+			from[name] = i
+		}
+	}
+	return ret, diags
+}
+
+// structuredVariableAttrs are the attributes allowed in the structured form
+// of a variable, like { value = "...", export = true }, with whether each
+// one is required.
+var structuredVariableAttrs = map[string]bool{
+	"value":    true,
+	"readonly": false,
+	"export":   false,
+}
+
+// unwrapStructuredVariables replaces any variables given in the structured
+// form, which is an object with a "value" attribute and optional "readonly"
+// and "export" attributes, with just their values, returning the
+// per-variable settings separately. Other variables are returned as-is.
+//
+// The given map is not modified, because it may share its storage with the
+// configuration value that we echo back in the result.
+func unwrapStructuredVariables(vars map[string]tftypes.Value) (map[string]tftypes.Value, map[string]bool, map[string]bool, []*tfprotov5.Diagnostic) {
+	var diags []*tfprotov5.Diagnostic
+	ret := make(map[string]tftypes.Value, len(vars))
+	readOnly := make(map[string]bool)
+	export := make(map[string]bool)
+	for name, val := range vars {
+		ret[name] = val
+		var attrs map[string]tftypes.Value
+		if !val.Is(tftypes.Object{}) || !val.IsKnown() || val.IsNull() || val.As(&attrs) != nil {
+			continue
+		}
+		structured := true
+		for attrName := range attrs {
+			if _, ok := structuredVariableAttrs[attrName]; !ok {
+				structured = false
+			}
+		}
+		for attrName, required := range structuredVariableAttrs {
+			if _, ok := attrs[attrName]; required && !ok {
+				structured = false
+			}
+		}
+		if !structured {
+			// This is an ordinary object, which objectsAsMaps will later
+			// declare as an associative array if its attributes are all
+			// strings. The structured form takes precedence, so an object
+			// like { value = "v" } is declared as the scalar "v" rather
+			// than as an associative array with a single "value" key. A
+			// map isn't an object, so tomap() avoids that ambiguity.
+			continue
+		}
+
+		ret[name] = attrs["value"]
+		for attrName, settings := range map[string]map[string]bool{"readonly": readOnly, "export": export} {
+			v, ok := attrs[attrName]
+			if !ok || v.IsNull() || !v.IsKnown() {
+				continue
+			}
+			var b bool
+			if !v.Is(tftypes.Bool) || v.As(&b) != nil {
+				diags = append(diags, &tfprotov5.Diagnostic{
+					Severity: tfprotov5.DiagnosticSeverityError,
+					Summary:  "Invalid variable settings",
+					Detail:   fmt.Sprintf("The %q attribute of %q must be a bool.", attrName, name),
+					Attribute: &tftypes.AttributePath{
+						Steps: []tftypes.AttributePathStep{
+							tftypes.AttributeName("variables"),
+							tftypes.AttributeName(name),
+							tftypes.AttributeName(attrName),
+						},
+					},
+				})
+				continue
+			}
+			settings[name] = b
+		}
+	}
+	return ret, readOnly, export, diags
+}
+
+// setsAsLists replaces any sets of strings in the given variables with lists
+// of the same elements in lexical order, so that the rest of the provider
+// can treat them just like lists, and the result is stable even though
+// sets have no inherent order.
+//
+// This modifies the given map, so it must not share its storage with the
+// configuration value that we echo back in the result.
+func setsAsLists(vars map[string]tftypes.Value) {
+	for name, val := range vars {
+		if !val.Is(setOfString) {
+			continue
+		}
+		if !val.IsKnown() {
+			vars[name] = tftypes.NewValue(listOfString, tftypes.UnknownValue)
+			continue
+		}
+		if val.IsNull() {
+			vars[name] = tftypes.NewValue(listOfString, nil)
+			continue
+		}
+		var elems []tftypes.Value
+		val.As(&elems)
+		sort.SliceStable(elems, func(i, j int) bool {
+			var a, b string
+			elems[i].As(&a)
+			elems[j].As(&b)
+			return a < b
+		})
+		vars[name] = tftypes.NewValue(listOfString, elems)
+	}
+}
+
+// emptyCollectionsAsStrings replaces any empty collections whose element
+// type Terraform couldn't determine, such as the empty tuple that results
+// from writing [] in the configuration, or the result of tolist([]), with
+// an empty list or map of strings. Any element type would produce the same
+// declaration for an empty collection, so this just allows the rest of the
+// provider to accept them.
+//
+// This modifies the given map, so it must not share its storage with the
+// configuration value that we echo back in the result.
+func emptyCollectionsAsStrings(vars map[string]tftypes.Value) {
+	for name, val := range vars {
+		if !val.IsKnown() || val.IsNull() {
+			continue
+		}
+		switch {
+		case val.Is(tftypes.Tuple{ElementTypes: []tftypes.Type{}}),
+			val.Is(tftypes.List{ElementType: tftypes.DynamicPseudoType}),
+			val.Is(tftypes.Set{ElementType: tftypes.DynamicPseudoType}):
+			var elems []tftypes.Value
+			if val.As(&elems) == nil && len(elems) == 0 {
+				vars[name] = tftypes.NewValue(listOfString, []tftypes.Value{})
+			}
+		case val.Is(tftypes.Map{AttributeType: tftypes.DynamicPseudoType}):
+			var elems map[string]tftypes.Value
+			if val.As(&elems) == nil && len(elems) == 0 {
+				vars[name] = tftypes.NewValue(mapOfString, map[string]tftypes.Value{})
+			}
+		}
+	}
+}
+
+// objectsAsMaps replaces any objects in the given variables whose attributes
+// are all strings with maps of strings, so that they are declared as
+// associative arrays keyed by attribute name. It returns an error diagnostic
+// for each object that has an attribute of any other type.
+//
+// This modifies the given map, so it must not share its storage with the
+// configuration value that we echo back in the result.
+func objectsAsMaps(vars map[string]tftypes.Value) []*tfprotov5.Diagnostic {
+	var diags []*tfprotov5.Diagnostic
+	for name, val := range vars {
+		if !val.Is(tftypes.Object{}) {
+			continue
+		}
+		if !val.IsKnown() {
+			// We'll check the attribute types once it's known.
+			vars[name] = tftypes.NewValue(mapOfString, tftypes.UnknownValue)
+			continue
+		}
+		if val.IsNull() {
+			vars[name] = tftypes.NewValue(mapOfString, nil)
+			continue
+		}
+		var attrs map[string]tftypes.Value
+		val.As(&attrs)
+		attrNames := make([]string, 0, len(attrs))
+		for attrName := range attrs {
+			attrNames = append(attrNames, attrName)
+		}
+		sort.Strings(attrNames)
+		valid := true
+		for _, attrName := range attrNames {
+			if attrs[attrName].Is(tftypes.String) {
+				continue
+			}
+			valid = false
+			diags = append(diags, &tfprotov5.Diagnostic{
+				Severity: tfprotov5.DiagnosticSeverityError,
+				Summary:  "Invalid variable value",
+				Detail:   fmt.Sprintf("Invalid value for Bash variable %q: an object can only be declared as an associative array if all of its attributes are strings, but %q is of type %s.", name, attrName, typeSummary(attrs[attrName])),
+				Attribute: &tftypes.AttributePath{
+					Steps: []tftypes.AttributePathStep{
+						tftypes.AttributeName("variables"),
+						tftypes.AttributeName(name),
+						tftypes.AttributeName(attrName),
+					},
+				},
+			})
+		}
+		if valid {
+			vars[name] = tftypes.NewValue(mapOfString, attrs)
+		} else {
+			// We've already reported the problem, so we'll just remove it
+			// to avoid reporting it again as an unsupported type.
+			delete(vars, name)
+		}
+	}
+	return diags
+}
+
 // variablesToBashDecls tries to produce a bash script fragment containing
 // declarations for each of the variables described in vars.
 //
 // Only a subset of possible Terraform values can be translated to bash
 // variables because of differences in type system, but this function assumes
 // that the variable names and values were already checked during configuration
-// decoding and so will just return something invalid if given an unsupported
-// value to deal with.
-func variablesToBashDecls(vars map[string]tftypes.Value) string {
-	if len(vars) == 0 {
-		return ""
-	}
-
-	var buf strings.Builder
-	names := make([]string, 0, len(vars))
-	for name := range vars {
-		names = append(names, name)
+// decoding and so will just return an error if given an unsupported value to
+// deal with.
+//
+// raw gives additional variables whose values are written verbatim, as
+// described for bashgen.Raw.
+func variablesToBashDecls(vars map[string]tftypes.Value, raw map[string]string, opts bashgen.Options) (string, error) {
+	goVars := variablesToGo(vars)
+	for name, val := range raw {
+		goVars[name] = bashgen.Raw(val)
 	}
-	sort.Strings(names)
+	return bashgen.RenderDeclarations(goVars, opts)
+}
 
-	for _, name := range names {
-		val := vars[name]
+// variablesToGo translates the given Terraform values into the Go types
+// that bashgen.RenderDeclarations expects.
+//
+// Values of unsupported types are passed through as-is, so that
+// bashgen.RenderDeclarations can report them as errors.
+func variablesToGo(vars map[string]tftypes.Value) map[string]interface{} {
+	ret := make(map[string]interface{}, len(vars))
+	for name, val := range vars {
 		switch {
 		case val.Is(tftypes.String):
 			var s string
 			val.As(&s)
-			buf.WriteString("declare -r ")
-			buf.WriteString(name)
-			buf.WriteString("=")
-			buf.WriteString(bashQuoteString(s))
-			buf.WriteString("\n")
+			ret[name] = s
 		case val.Is(tftypes.Number):
-			var f big.Float
-			val.As(&f)
-			// NOTE: Bash only actually supports integers, so here we're
-			// assuming that the configuration decoder already rejected
-			// fractional values.
-			buf.WriteString("declare -ri ")
-			buf.WriteString(name)
-			buf.WriteString("=")
-			buf.WriteString(f.Text('f', -1))
-			buf.WriteString("\n")
+			f := new(big.Float)
+			val.As(f)
+			ret[name] = f
 		case val.Is(listOfString):
 			var l []tftypes.Value
 			val.As(&l)
-			buf.WriteString("declare -ra ")
-			buf.WriteString(name)
-			buf.WriteString("=(")
+			ss := make([]string, len(l))
 			for i, ev := range l {
-				var es string
-				ev.As(&es)
-				if i != 0 {
-					buf.WriteString(" ")
-				}
-				buf.WriteString(bashQuoteString(es))
+				ev.As(&ss[i])
 			}
-			buf.WriteString(")\n")
+			ret[name] = ss
+		case val.Is(listOfNumber):
+			var l []tftypes.Value
+			val.As(&l)
+			fs := make([]*big.Float, len(l))
+			for i, ev := range l {
+				fs[i] = new(big.Float)
+				ev.As(fs[i])
+			}
+			ret[name] = fs
 		case val.Is(mapOfString):
 			var m map[string]tftypes.Value
 			val.As(&m)
-			buf.WriteString("declare -rA ")
-			buf.WriteString(name)
-			buf.WriteString("=(")
-			i := 0
+			ms := make(map[string]string, len(m))
 			for ek, ev := range m {
 				var es string
 				ev.As(&es)
-				if i != 0 {
-					buf.WriteString(" ")
-				}
-				buf.WriteString("[")
-				buf.WriteString(bashQuoteString(ek))
-				buf.WriteString("]=")
-				buf.WriteString(bashQuoteString(es))
-				i++
+				ms[ek] = es
 			}
-			buf.WriteString(")\n")
+			ret[name] = ms
+		case val.Is(mapOfNumber):
+			var m map[string]tftypes.Value
+			val.As(&m)
+			mf := make(map[string]*big.Float, len(m))
+			for ek, ev := range m {
+				mf[ek] = new(big.Float)
+				ev.As(mf[ek])
+			}
+			ret[name] = mf
 		default:
-			// Shouldn't get here if config decoding validation is working
-			fmt.Fprintf(&buf, "# ERROR: Don't know how to serialize %q for bash\n", name)
+			ret[name] = val
 		}
 	}
-	return buf.String()
-}
-
-func bashQuoteString(s string) string {
-	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+	return ret
 }
 
-func validVariableName(s string) bool {
-	if len(s) == 0 {
-		return false
-	}
-	for i, c := range s {
-		if i == 0 {
-			if !validVariableNameInitialCharacter(c) {
-				return false
-			}
-		} else {
-			if !validVariableNameSubsequentCharacter(c) {
-				return false
-			}
+// msysPath converts a Windows-style path, like C:\Users\example, into the
+// form that MSYS-based environments such as Git Bash expect, like
+// /c/Users/example.
+//
+// Strings that don't start with a drive letter just have their backslashes
+// replaced with forward slashes, so that relative paths and UNC paths are
+// also usable.
+func msysPath(s string) string {
+	s = strings.ReplaceAll(s, `\`, "/")
+	if len(s) >= 2 && s[1] == ':' && isASCIILetter(s[0]) {
+		drive := "/" + strings.ToLower(s[:1])
+		rest := s[2:]
+		if rest != "" && !strings.HasPrefix(rest, "/") {
+			rest = "/" + rest
 		}
+		return drive + rest
 	}
-	return true
+	return s
 }
 
-func validVariableNameInitialCharacter(c rune) bool {
-	switch {
-	case c == '_':
-		return true
-	case c >= 'A' && c <= 'Z':
-		return true
-	case c >= 'a' && c <= 'z':
-		return true
-	default:
-		return false
-	}
-}
-
-func validVariableNameSubsequentCharacter(c rune) bool {
-	switch {
-	case validVariableNameInitialCharacter(c):
-		return true
-	case c >= '0' && c <= '9':
-		return true
-	default:
-		return false
-	}
+func isASCIILetter(c byte) bool {
+	return (c >= 'A' && c <= 'Z') || (c >= 'a' && c <= 'z')
 }
@@ -0,0 +1,31 @@
+package bash
+
+import (
+	"testing"
+)
+
+func TestMsysPath(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{``, ``},
+		{`C:\Users\example`, `/c/Users/example`},
+		{`c:\Users\example`, `/c/Users/example`},
+		{`D:/already/forward`, `/d/already/forward`},
+		{`C:`, `/c`},
+		{`C:foo`, `/c/foo`},
+		{`relative\path`, `relative/path`},
+		{`\\server\share`, `//server/share`},
+		{`/c/unchanged`, `/c/unchanged`},
+	}
+
+	for _, test := range tests {
+		t.Run(test.input, func(t *testing.T) {
+			got := msysPath(test.input)
+			if got != test.want {
+				t.Errorf("wrong result for %q: got %q, want %q", test.input, got, test.want)
+			}
+		})
+	}
+}